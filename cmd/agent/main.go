@@ -1,57 +1,167 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/mpkelevra23/arithmetic-web-service/config"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/agent"
-	"log"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/health"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"net"
+	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"go.uber.org/zap"
 )
 
 func main() {
-	// Загружаем переменные окружения
-	err := godotenv.Load()
+	// Загружаем конфигурацию из файла, окружения и флагов командной строки
+	cfg, err := config.LoadAgentConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Println("Файл .env не найден, используем переменные окружения системы")
+		zapLogger, _ := zap.NewProduction()
+		zapLogger.Fatal("Configuration error", zap.Error(err))
 	}
+	cfg.PrintAndExit()
 
-	// Получаем URL оркестратора
-	orchestratorURL := getEnv("ORCHESTRATOR_URL", "http://localhost:8080")
+	// Инициализируем логгер с заданными уровнем и форматом
+	logger, err := logging.New(logging.Options{Level: cfg.LogLevel, Format: cfg.LogFormat})
+	if err != nil {
+		zapLogger, _ := zap.NewProduction()
+		zapLogger.Fatal("Logger initialization error", zap.Error(err))
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
 
-	// Получаем вычислительную мощность
-	computingPower := getEnvInt("COMPUTING_POWER", 3)
+	if cfg.EnvFileLoaded {
+		logger.Info(".env file successfully loaded")
+	} else {
+		logger.Info(".env file not found")
+	}
 
 	// Создаем агента
-	a := agent.NewAgent(orchestratorURL, computingPower)
-
-	// Запускаем агента
-	log.Printf("Агент запущен. URL оркестратора: %s, вычислительная мощность: %d\n",
-		orchestratorURL, computingPower)
-	a.Start()
-}
+	a := agent.NewAgent(cfg.OrchestratorURL, cfg.ComputingPower)
+	a.SetAuthToken(cfg.AgentSecret)
+	a.SetID(cfg.AgentID)
+	a.SetLogger(logger)
+	minWorkers, maxWorkers := cfg.WorkerBounds()
+	a.SetConcurrency(agent.Concurrency{Min: minWorkers, Max: maxWorkers, CPUBudget: cfg.CPUBudget})
 
-// getEnv возвращает значение переменной окружения или значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
+	// Настраиваем повторную отправку результатов и приостановку запросов к недоступному оркестратору
+	outbox, err := agent.NewOutbox(cfg.OutboxFile)
+	if err != nil {
+		logger.Fatal("Outbox initialization error", zap.Error(err))
 	}
-	return value
-}
+	a.SetOutbox(outbox)
+	a.SetRetryPolicy(agent.Backoff{Base: cfg.RetryBaseDelay, Max: cfg.RetryMaxDelay}, cfg.BreakerThreshold, cfg.BreakerCooldown)
 
-// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию
-func getEnvInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+	// Настраиваем исполнителей операций: встроенные с заданной стоимостью и внешние программы
+	timing, err := agent.TimingForMode(cfg.WorkMode)
+	if err != nil {
+		logger.Fatal("Executor setup error", zap.Error(err))
+	}
+	executors := agent.NewBuiltinExecutors(timing)
+	external, err := agent.ParseProcessExecutors(cfg.Executors)
+	if err != nil {
+		logger.Fatal("Executor setup error", zap.Error(err))
+	}
+	for _, executor := range external {
+		executors.Register(executor.Operation, executor, agent.NoDelay)
+		logger.Info("External executor registered",
+			zap.String("operation", string(executor.Operation)),
+			zap.Strings("command", executor.Command),
+		)
 	}
+	a.SetExecutors(executors)
 
-	value, err := strconv.Atoi(valueStr)
+	// Настраиваем трассировку: OTLP/HTTP-коллектор или локальный файл
+	exporter, err := tracing.NewExporter(cfg.Tracing.OTLPEndpoint, cfg.Tracing.File)
 	if err != nil {
-		log.Printf("Ошибка преобразования %s=%s в число, используем значение по умолчанию %d\n",
-			key, valueStr, defaultValue)
-		return defaultValue
+		logger.Fatal("Tracing setup error", zap.Error(err))
+	}
+	if exporter != nil {
+		tracer := tracing.NewTracer(cfg.Tracing.ServiceName, exporter)
+		defer tracer.Shutdown()
+		a.SetTracer(tracer)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Запускаем локальный сервер состояния: метрики, состояние воркеров, проверки состояния и сведения о сборке
+	if cfg.MetricsPort != "" {
+		registry := metrics.NewRegistry()
+		a.SetMetrics(registry)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", registry.Handler())
+		mux.Handle("/status", a.StatusHandler())
+		health.Register(mux, "agent",
+			health.Check{Name: "orchestrator", Check: a.Ping},
+			health.Check{Name: "running", Check: func(context.Context) error {
+				if ctx.Err() != nil {
+					return errors.New("agent is shutting down")
+				}
+				return nil
+			}},
+		)
+		listener, err := listenStatus(cfg, logger)
+		if err != nil {
+			logger.Fatal("Status server error", zap.Error(err))
+		}
+		logger.Info("Agent status server started", zap.String("address", listener.Addr().String()))
+		go func() {
+			if err := http.Serve(listener, mux); err != nil {
+				logger.Error("Status server error", zap.Error(err))
+			}
+		}()
+	}
+
+	// Запускаем агента до получения сигнала остановки
+	logger.Info("Agent configured",
+		zap.String("orchestrator_url", cfg.OrchestratorURL),
+		zap.Int("computing_power", cfg.ComputingPower),
+		zap.Int("min_workers", minWorkers),
+		zap.Int("max_workers", maxWorkers),
+		zap.Float64("cpu_budget", cfg.CPUBudget),
+		zap.String("work_mode", cfg.WorkMode),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		a.Start(ctx)
+		close(done)
+	}()
+	<-ctx.Done()
+	stop() // Повторный сигнал завершает процесс немедленно
+
+	// Ждем, пока воркеры завершат текущие задачи и отправят результаты
+	logger.Info("Shutting down, finishing current tasks", zap.Duration("timeout", cfg.ShutdownTimeout))
+	select {
+	case <-done:
+	case <-time.After(cfg.ShutdownTimeout):
+		logger.Warn("Shutdown deadline exceeded, unfinished tasks abandoned")
+	}
+}
+
+// listenStatus открывает порт сервера состояния. Если порт по умолчанию занят, например другим
+// агентом на том же хосте, сервер запускается на свободном порту. Занятый явно заданный порт — ошибка
+func listenStatus(cfg *config.AgentConfig, logger *zap.Logger) (net.Listener, error) {
+	listener, err := net.Listen("tcp", ":"+cfg.MetricsPort)
+	if err == nil || cfg.MetricsPortSet {
+		return listener, err
 	}
-	return value
+	logger.Warn("Default status server port is busy, using a free port; set METRICS_PORT for a fixed one",
+		zap.String("port", cfg.MetricsPort),
+		zap.Error(err),
+	)
+	return net.Listen("tcp", ":0")
 }
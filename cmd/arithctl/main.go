@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/cli"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := cli.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
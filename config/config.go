@@ -1,35 +1,352 @@
 package config
 
 import (
-	"github.com/joho/godotenv"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
 )
 
-// Config содержит конфигурационные параметры приложения.
-type Config struct {
-	Port     string
-	LogLevel string
+// Common содержит параметры, общие для всех бинарников.
+type Common struct {
+	LogLevel        string        // Уровень логирования: debug, info, warn или error
+	LogFormat       string        // Формат логов: json или console
+	ShutdownTimeout time.Duration // Время на корректную остановку после SIGINT или SIGTERM
+	EnvFileLoaded   bool          // Был ли загружен файл .env
+	PrintConfig     bool          // Задан флаг --print-config
+
+	settings *settings
+}
+
+// Print выводит итоговую конфигурацию в формате файла конфигурации с указанием источника значений.
+func (c *Common) Print(w io.Writer) error {
+	return c.settings.print(w)
+}
+
+// TracingConfig содержит параметры экспорта спанов.
+type TracingConfig struct {
+	OTLPEndpoint string // Адрес OTLP/HTTP-коллектора
+	File         string // Файл для спанов в формате OTLP/JSON
+	ServiceName  string // Имя сервиса в спанах
+}
+
+// OrchestratorConfig содержит конфигурационные параметры API-сервера (cmd/orchestrator и cmd/server).
+type OrchestratorConfig struct {
+	Common
+	Port string
+
+	TimeAdditionMS       int // Время выполнения сложения (мс)
+	TimeSubtractionMS    int // Время выполнения вычитания (мс)
+	TimeMultiplicationMS int // Время выполнения умножения (мс)
+	TimeDivisionMS       int // Время выполнения деления (мс)
+
+	WebhookSecret       string // Ключ подписи webhook-уведомлений
+	WebhookAllowedHosts string // Хосты и подсети закрытой сети, разрешенные для уведомлений (через запятую)
+	JWTSecret           string // Ключ подписи JWT пользователей
+	JWTTTL              time.Duration
+	AgentSecret         string // Общий секрет агентов
+	AdminSecret         string // Секрет административного API
+
+	APIKeyExpressionsPerMinute int // Квота выражений в минуту для новых API-ключей
+	APIKeyTasksPerDay          int // Квота задач в сутки для новых API-ключей
+	APIKeyMaxExpressionLength  int // Максимальная длина выражения для новых API-ключей
+
+	RateLimitRPS   int // Запросов в секунду с одного IP или API-ключа (0 — без ограничения)
+	RateLimitBurst int
+
+	RetentionMaxAge        time.Duration // Время хранения завершенных выражений (0 — бессрочно)
+	RetentionErrorMaxAge   time.Duration // Время хранения выражений с ошибкой
+	RetentionMaxCount      int           // Максимальное количество выражений (0 — без ограничения)
+	RetentionSweepInterval time.Duration // Интервал фоновой очистки
+
+	IdempotencyTTL      time.Duration
+	ResultCacheEnabled  bool          // Повторное использование результатов одинаковых задач
+	AuditMode           bool          // Выполнение каждой задачи двумя агентами со сверкой результатов
+	AuditTimeout        time.Duration // Ожидание второго результата в режиме аудита (0 — бессрочно)
+	LeaseTimeout        time.Duration // Срок, после которого задача без результата выдается снова (0 — бессрочно)
+	EmbeddedAgents      int           // Агенты, работающие в процессе оркестратора (0 — без встроенных агентов)
+	EmbeddedWorkers     int           // Количество воркеров каждого встроенного агента
+	ReadyMinAgents      int           // Минимум активных агентов для готовности (/readyz)
+	AgentActivityWindow time.Duration // Время, в течение которого агент считается активным
+	Tracing             TracingConfig
+}
+
+// AgentConfig содержит конфигурационные параметры агента (cmd/agent).
+type AgentConfig struct {
+	Common
+	OrchestratorURL  string
+	ComputingPower   int           // Начальное количество воркеров
+	MinWorkers       int           // Минимальное количество воркеров (0 — ComputingPower)
+	MaxWorkers       int           // Максимальное количество воркеров (0 — ComputingPower)
+	CPUBudget        float64       // Доступные агенту ядра процессора (0 — без ограничения)
+	AgentSecret      string        // Общий секрет агентов
+	AgentID          string        // Идентификатор агента (пусто — <hostname>-<pid>)
+	MetricsPort      string        // Порт сервера состояния с /metrics, /healthz, /readyz и /version (пусто — отключен, 0 — любой свободный)
+	MetricsPortSet   bool          // Порт сервера состояния задан явно, а не взят по умолчанию
+	OutboxFile       string        // Файл недоставленных результатов (пусто — только в памяти)
+	RetryBaseDelay   time.Duration // Начальная задержка повторных попыток
+	RetryMaxDelay    time.Duration // Максимальная задержка повторных попыток
+	BreakerThreshold int           // Ошибок подряд до приостановки запросов к оркестратору
+	BreakerCooldown  time.Duration // Пауза перед пробным запросом
+	WorkMode         string        // Стоимость встроенных операций: sleep (имитация) или cpu (нагрузка)
+	Executors        string        // Внешние исполнители операций вида "OP=команда;OP2=команда2"
+	Tracing          TracingConfig
+}
+
+// CLIConfig содержит параметры клиента командной строки (cmd/arithctl).
+type CLIConfig struct {
+	Common
+	Endpoint   string        // Адрес API-сервера
+	Token      string        // JWT пользователя
+	APIKey     string        // API-ключ сервисной учетной записи
+	AdminToken string        // Секрет администратора для команды agents
+	Output     string        // Формат вывода: table или json
+	Timeout    time.Duration // Время ожидания ответа на один запрос
+	Args       []string      // Команда и ее аргументы после флагов
+}
+
+// LoadOrchestratorConfig загружает конфигурацию оркестратора из файла конфигурации,
+// файла .env, переменных окружения и флагов командной строки (args без имени программы).
+func LoadOrchestratorConfig(args []string) (*OrchestratorConfig, error) {
+	return loadAPIServerConfig("orchestrator", args)
+}
+
+// LoadConfig загружает конфигурацию cmd/server. Это тот же API-сервер, что и оркестратор,
+// но параметры из файла конфигурации берутся из таблицы [server], а по умолчанию запускается
+// один встроенный агент, чтобы выражения /api/v1/calculate вычислялись без отдельных агентов.
+func LoadConfig(args []string) (*OrchestratorConfig, error) {
+	return loadAPIServerConfig("server", args)
+}
+
+// loadAPIServerConfig загружает конфигурацию API-сервера; section задает таблицу
+// файла конфигурации и имя сервиса в спанах.
+func loadAPIServerConfig(section string, args []string) (*OrchestratorConfig, error) {
+	cfg := &OrchestratorConfig{}
+	s := &settings{section: section}
+
+	s.String(&cfg.Port, "port", "PORT", "8080", "HTTP port", checkPort)
+	registerCommon(s, &cfg.Common)
+	s.Int(&cfg.TimeAdditionMS, "time_addition_ms", "TIME_ADDITION_MS", 100, "addition time, ms", nonNegative[int])
+	s.Int(&cfg.TimeSubtractionMS, "time_subtraction_ms", "TIME_SUBTRACTION_MS", 100, "subtraction time, ms", nonNegative[int])
+	s.Int(&cfg.TimeMultiplicationMS, "time_multiplications_ms", "TIME_MULTIPLICATIONS_MS", 200, "multiplication time, ms", nonNegative[int])
+	s.Int(&cfg.TimeDivisionMS, "time_divisions_ms", "TIME_DIVISIONS_MS", 200, "division time, ms", nonNegative[int])
+	s.Secret(&cfg.WebhookSecret, "webhook_secret", "WEBHOOK_SECRET", "webhook signing key")
+	s.String(&cfg.WebhookAllowedHosts, "webhook_allowed_hosts", "WEBHOOK_ALLOWED_HOSTS", "", "comma-separated hosts, IPs and CIDR ranges of private networks allowed as webhook targets", checkHostList)
+	s.Secret(&cfg.JWTSecret, "jwt_secret", "JWT_SECRET", "JWT signing key (empty disables user authentication)")
+	s.Duration(&cfg.JWTTTL, "jwt_ttl", "JWT_TTL", 24*time.Hour, "JWT lifetime", positive[time.Duration])
+	s.Secret(&cfg.AgentSecret, "agent_secret", "AGENT_SECRET", "shared agent secret for /internal/task")
+	s.Secret(&cfg.AdminSecret, "admin_secret", "ADMIN_SECRET", "admin API secret")
+	s.Int(&cfg.APIKeyExpressionsPerMinute, "api_key_expressions_per_minute", "API_KEY_EXPRESSIONS_PER_MINUTE", 60, "expressions per minute for new API keys", nonNegative[int])
+	s.Int(&cfg.APIKeyTasksPerDay, "api_key_tasks_per_day", "API_KEY_TASKS_PER_DAY", 10000, "tasks per day for new API keys", nonNegative[int])
+	s.Int(&cfg.APIKeyMaxExpressionLength, "api_key_max_expression_length", "API_KEY_MAX_EXPRESSION_LENGTH", 1000, "max expression length for new API keys", nonNegative[int])
+	s.Int(&cfg.RateLimitRPS, "rate_limit_rps", "RATE_LIMIT_RPS", 0, "requests per second per IP or API key (0 disables)", nonNegative[int])
+	s.Int(&cfg.RateLimitBurst, "rate_limit_burst", "RATE_LIMIT_BURST", 20, "request burst", positive[int])
+	s.Duration(&cfg.RetentionMaxAge, "retention_max_age", "RETENTION_MAX_AGE", 0, "lifetime of finished expressions (0 keeps forever)", nonNegative[time.Duration])
+	s.Duration(&cfg.RetentionErrorMaxAge, "retention_error_max_age", "RETENTION_ERROR_MAX_AGE", 0, "lifetime of failed expressions (0 uses retention_max_age)", nonNegative[time.Duration])
+	s.Int(&cfg.RetentionMaxCount, "retention_max_count", "RETENTION_MAX_COUNT", 0, "max stored expressions (0 is unlimited)", nonNegative[int])
+	s.Duration(&cfg.RetentionSweepInterval, "retention_sweep_interval", "RETENTION_SWEEP_INTERVAL", time.Minute, "background purge interval (0 disables)", nonNegative[time.Duration])
+	s.Duration(&cfg.IdempotencyTTL, "idempotency_ttl", "IDEMPOTENCY_TTL", 24*time.Hour, "idempotency key lifetime", positive[time.Duration])
+	s.Bool(&cfg.ResultCacheEnabled, "result_cache_enabled", "RESULT_CACHE_ENABLED", false, "reuse results of identical tasks")
+	s.Bool(&cfg.AuditMode, "audit_mode", "AUDIT_MODE", false, "run every task on two agents and compare results")
+	s.Duration(&cfg.AuditTimeout, "audit_timeout", "AUDIT_TIMEOUT", 30*time.Second, "time to wait for the second result in audit mode before accepting a single one (0 waits forever)", nonNegative[time.Duration])
+	s.Duration(&cfg.LeaseTimeout, "lease_timeout", "LEASE_TIMEOUT", 5*time.Minute, "time after which an issued task without a result is re-queued (0 disables)", nonNegative[time.Duration])
+	embeddedAgents := 0
+	if section == "server" {
+		embeddedAgents = 1
+	}
+	s.Int(&cfg.EmbeddedAgents, "embedded_agents", "EMBEDDED_AGENTS", embeddedAgents, "agents running inside the orchestrator process (0 disables)", nonNegative[int])
+	s.Int(&cfg.EmbeddedWorkers, "embedded_workers", "EMBEDDED_WORKERS", 3, "workers of each embedded agent", positive[int])
+	s.Int(&cfg.ReadyMinAgents, "ready_min_agents", "READY_MIN_AGENTS", 0, "active agents required by /readyz", nonNegative[int])
+	s.Duration(&cfg.AgentActivityWindow, "agent_activity_window", "AGENT_ACTIVITY_WINDOW", 30*time.Second, "time an agent counts as active after its last request", positive[time.Duration])
+	registerTracing(s, &cfg.Tracing, section)
+
+	if err := s.load(&cfg.Common, args); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadAgentConfig загружает конфигурацию агента.
+func LoadAgentConfig(args []string) (*AgentConfig, error) {
+	cfg := &AgentConfig{}
+	s := &settings{section: "agent"}
+
+	s.String(&cfg.OrchestratorURL, "orchestrator_url", "ORCHESTRATOR_URL", "http://localhost:8080", "orchestrator base URL", checkURL)
+	s.Int(&cfg.ComputingPower, "computing_power", "COMPUTING_POWER", 3, "initial number of workers", positive[int])
+	s.Int(&cfg.MinWorkers, "min_workers", "MIN_WORKERS", 0, "minimum number of workers (0 uses computing_power)", nonNegative[int])
+	s.Int(&cfg.MaxWorkers, "max_workers", "MAX_WORKERS", 0, "maximum number of workers (0 uses computing_power)", nonNegative[int])
+	s.Float(&cfg.CPUBudget, "cpu_budget", "CPU_BUDGET", 0, "CPU cores the agent may use (0 is unlimited)", nonNegative[float64])
+	registerCommon(s, &cfg.Common)
+	s.Secret(&cfg.AgentSecret, "agent_secret", "AGENT_SECRET", "shared agent secret for /internal/task")
+	s.String(&cfg.AgentID, "agent_id", "AGENT_ID", "", "agent ID in logs (empty is <hostname>-<pid>)", nil)
+	s.String(&cfg.MetricsPort, "metrics_port", "METRICS_PORT", "9090", "port of the status server with /metrics, /healthz, /readyz and /version (empty disables, 0 picks a free port)", func(port string) error {
+		if port == "" || port == "0" {
+			return nil
+		}
+		return checkPort(port)
+	})
+	s.String(&cfg.OutboxFile, "outbox_file", "OUTBOX_FILE", "", "file persisting undelivered results (empty keeps them in memory)", nil)
+	s.Duration(&cfg.RetryBaseDelay, "retry_base_delay", "RETRY_BASE_DELAY", 500*time.Millisecond, "initial retry delay", positive[time.Duration])
+	s.Duration(&cfg.RetryMaxDelay, "retry_max_delay", "RETRY_MAX_DELAY", 30*time.Second, "maximum retry delay", positive[time.Duration])
+	s.Int(&cfg.BreakerThreshold, "breaker_threshold", "BREAKER_THRESHOLD", 5, "consecutive failures that pause requests to the orchestrator", positive[int])
+	s.Duration(&cfg.BreakerCooldown, "breaker_cooldown", "BREAKER_COOLDOWN", 10*time.Second, "pause before a probe request", positive[time.Duration])
+	s.String(&cfg.WorkMode, "work_mode", "WORK_MODE", "sleep", "cost of built-in operations: sleep (simulated delay) or cpu (real CPU load)", func(mode string) error {
+		if mode != "sleep" && mode != "cpu" {
+			return fmt.Errorf("неизвестный режим работы: %q", mode)
+		}
+		return nil
+	})
+	s.String(&cfg.Executors, "executors", "EXECUTORS", "", "external operation executors: OP=command args;OP2=command", nil)
+	registerTracing(s, &cfg.Tracing, "agent")
+
+	if err := s.load(&cfg.Common, args); err != nil {
+		return nil, err
+	}
+	cfg.MetricsPortSet = s.isSet("metrics_port")
+	if minWorkers, maxWorkers := cfg.WorkerBounds(); minWorkers > maxWorkers {
+		return nil, fmt.Errorf("некорректные границы воркеров: min_workers %d больше max_workers %d", minWorkers, maxWorkers)
+	}
+	// Результаты из outbox отправляются после перезапуска, и оркестратор примет их,
+	// только если агент предъявит тот же идентификатор
+	if cfg.OutboxFile != "" && cfg.AgentID == "" {
+		return nil, fmt.Errorf("outbox_file требует agent_id: результаты, сохраненные до перезапуска, не будут приняты под новым идентификатором")
+	}
+	return cfg, nil
+}
+
+// LoadCLIConfig загружает конфигурацию arithctl. Флаги задаются до команды, а команда
+// и ее аргументы возвращаются в Args. Без --config и CONFIG_FILE читается файл
+// arithctl/config.toml в каталоге настроек пользователя, если он существует.
+func LoadCLIConfig(args []string) (*CLIConfig, error) {
+	cfg := &CLIConfig{}
+	s := &settings{section: "arithctl", allowArgs: true}
+	if dir, err := os.UserConfigDir(); err == nil {
+		s.defaultFile = filepath.Join(dir, "arithctl", "config.toml")
+	}
+
+	s.String(&cfg.Endpoint, "endpoint", "ARITHCTL_ENDPOINT", "http://localhost:8080", "API server base URL", checkURL)
+	s.Secret(&cfg.Token, "token", "ARITHCTL_TOKEN", "user JWT")
+	s.Secret(&cfg.APIKey, "api_key", "ARITHCTL_API_KEY", "API key")
+	s.Secret(&cfg.AdminToken, "admin_token", "ARITHCTL_ADMIN_TOKEN", "admin API secret for the agents command")
+	s.String(&cfg.Output, "output", "ARITHCTL_OUTPUT", "table", "output format: table or json", func(format string) error {
+		if format != "table" && format != "json" {
+			return fmt.Errorf("неизвестный формат вывода: %q", format)
+		}
+		return nil
+	})
+	s.Duration(&cfg.Timeout, "timeout", "ARITHCTL_TIMEOUT", 30*time.Second, "timeout of a single API request", positive[time.Duration])
+
+	if err := s.load(&cfg.Common, args); err != nil {
+		return nil, err
+	}
+	cfg.Args = s.args
+	return cfg, nil
+}
+
+// WorkerBounds возвращает границы количества воркеров; незаданные границы равны ComputingPower.
+func (c *AgentConfig) WorkerBounds() (minWorkers, maxWorkers int) {
+	minWorkers, maxWorkers = c.MinWorkers, c.MaxWorkers
+	if minWorkers == 0 {
+		minWorkers = c.ComputingPower
+		if maxWorkers > 0 {
+			minWorkers = min(minWorkers, maxWorkers)
+		}
+	}
+	if maxWorkers == 0 {
+		maxWorkers = max(c.ComputingPower, minWorkers)
+	}
+	return minWorkers, maxWorkers
 }
 
-// LoadConfig загружает конфигурацию из файла .env и переменных окружения.
-// Возвращает конфигурацию, флаг наличия .env файла и ошибку при необходимости.
-func LoadConfig() (*Config, bool, error) {
-	// Попытка загрузить переменные из .env файла
-	err := godotenv.Load()
-	envLoaded := err == nil
+// registerCommon регистрирует параметры логирования.
+func registerCommon(s *settings, c *Common) {
+	s.String(&c.LogLevel, "log_level", "LOG_LEVEL", "info", "log level: debug, info, warn or error", func(level string) error {
+		_, err := logging.ParseLevel(level)
+		return err
+	})
+	s.String(&c.LogFormat, "log_format", "LOG_FORMAT", logging.FormatJSON, "log format: json or console", func(format string) error {
+		if format != logging.FormatJSON && format != logging.FormatConsole {
+			return fmt.Errorf("неизвестный формат логов: %q", format)
+		}
+		return nil
+	})
+	s.Duration(&c.ShutdownTimeout, "shutdown_timeout", "SHUTDOWN_TIMEOUT", 30*time.Second, "graceful shutdown deadline", positive[time.Duration])
+}
+
+// registerTracing регистрирует параметры трассировки.
+func registerTracing(s *settings, t *TracingConfig, service string) {
+	s.String(&t.OTLPEndpoint, "otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "", "OTLP/HTTP collector URL", func(endpoint string) error {
+		if endpoint == "" {
+			return nil
+		}
+		return checkURL(endpoint)
+	})
+	s.String(&t.File, "trace_file", "TRACE_FILE", "", "file for spans in OTLP/JSON", nil)
+	s.String(&t.ServiceName, "service_name", "OTEL_SERVICE_NAME", service, "service name in spans", nil)
+}
+
+// checkPort проверяет номер TCP-порта.
+func checkPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("некорректный TCP-порт: %q", port)
+	}
+	return nil
+}
 
-	config := &Config{
-		Port:     getEnv("PORT", "8080"),
-		LogLevel: getEnv("LOG_LEVEL", "info"),
+// checkURL проверяет абсолютный HTTP(S) URL.
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("некорректный http(s) URL: %q", raw)
 	}
+	return nil
+}
 
-	return config, envLoaded, nil
+// checkHostList проверяет подсети в списке хостов через запятую.
+func checkHostList(list string) error {
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("некорректная подсеть: %q", entry)
+		}
+	}
+	return nil
 }
 
-// getEnv возвращает значение переменной окружения или значение по умолчанию, если переменная не установлена.
-func getEnv(key, defaultVal string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// positive проверяет, что значение больше нуля.
+func positive[T int | time.Duration](v T) error {
+	if v <= 0 {
+		return fmt.Errorf("должно быть больше нуля, получено %v", v)
+	}
+	return nil
+}
+
+// nonNegative проверяет, что значение не меньше нуля.
+func nonNegative[T int | float64 | time.Duration](v T) error {
+	if v < 0 {
+		return fmt.Errorf("не может быть отрицательным, получено %v", v)
+	}
+	return nil
+}
+
+// PrintAndExit выводит конфигурацию в stdout и завершает процесс, если задан флаг --print-config.
+func (c *Common) PrintAndExit() {
+	if !c.PrintConfig {
+		return
+	}
+	if err := c.Print(os.Stdout); err != nil {
+		os.Exit(1)
 	}
-	return defaultVal
+	os.Exit(0)
 }
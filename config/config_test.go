@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile создает файл конфигурации во временном каталоге
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// unsetEnv удаляет переменные окружения на время теста
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoadOrchestratorConfig_Precedence(t *testing.T) {
+	unsetEnv(t, "CONFIG_FILE", "PORT", "TIME_ADDITION_MS", "TIME_SUBTRACTION_MS", "TIME_DIVISIONS_MS", "RETENTION_MAX_AGE", "LOG_LEVEL", "RATE_LIMIT_RPS", "EMBEDDED_AGENTS")
+	path := writeFile(t, `
+log_level = "debug" # общий для всех бинарников
+
+[orchestrator]
+port = "9000"
+time_addition_ms = 1_000
+time_subtraction_ms = 500
+retention_max_age = "2h"
+
+[agent]
+computing_power = 8
+`)
+	t.Setenv("TIME_ADDITION_MS", "300")
+	t.Setenv("TIME_SUBTRACTION_MS", "400")
+
+	cfg, err := LoadOrchestratorConfig([]string{"--config", path, "--time-subtraction-ms", "50"})
+	if err != nil {
+		t.Fatalf("LoadOrchestratorConfig: %v", err)
+	}
+
+	if cfg.Port != "9000" || cfg.LogLevel != "debug" || cfg.RetentionMaxAge != 2*time.Hour {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.TimeAdditionMS != 300 {
+		t.Errorf("env should override file: got %d", cfg.TimeAdditionMS)
+	}
+	if cfg.TimeSubtractionMS != 50 {
+		t.Errorf("flag should override env: got %d", cfg.TimeSubtractionMS)
+	}
+	if cfg.TimeDivisionMS != 200 || cfg.Tracing.ServiceName != "orchestrator" || cfg.RateLimitRPS != 0 || cfg.EmbeddedAgents != 0 {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+
+	// cmd/server по умолчанию запускает встроенного агента
+	serverCfg, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if serverCfg.EmbeddedAgents != 1 {
+		t.Errorf("server embedded agents = %d, want 1", serverCfg.EmbeddedAgents)
+	}
+}
+
+func TestLoadCLIConfig(t *testing.T) {
+	unsetEnv(t, "CONFIG_FILE", "ARITHCTL_ENDPOINT", "ARITHCTL_TOKEN", "ARITHCTL_OUTPUT")
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "arithctl"), 0o700)
+	content := "[arithctl]\nendpoint = \"http://calc:8080\"\ntoken = \"file-token\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "arithctl", "config.toml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARITHCTL_TOKEN", "env-token")
+
+	cfg, err := LoadCLIConfig([]string{"--output", "json", "ls", "--status", "ERROR"})
+	if err != nil {
+		t.Fatalf("LoadCLIConfig: %v", err)
+	}
+	if cfg.Endpoint != "http://calc:8080" || cfg.Token != "env-token" || cfg.Output != "json" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if strings.Join(cfg.Args, " ") != "ls --status ERROR" {
+		t.Errorf("args = %q, want command with its flags", cfg.Args)
+	}
+
+	if _, err := LoadCLIConfig([]string{"--output", "yaml"}); err == nil {
+		t.Error("unknown output format should be rejected")
+	}
+}
+
+func TestLoadAgentConfig_MetricsPort(t *testing.T) {
+	unsetEnv(t, "CONFIG_FILE", "METRICS_PORT", "OUTBOX_FILE", "AGENT_ID")
+
+	cfg, err := LoadAgentConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadAgentConfig: %v", err)
+	}
+	if cfg.MetricsPort != "9090" || cfg.MetricsPortSet {
+		t.Errorf("default metrics port = %q, set = %v; want 9090 not set", cfg.MetricsPort, cfg.MetricsPortSet)
+	}
+
+	// Нулевой порт означает любой свободный и считается заданным явно
+	t.Setenv("METRICS_PORT", "0")
+	cfg, err = LoadAgentConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadAgentConfig: %v", err)
+	}
+	if cfg.MetricsPort != "0" || !cfg.MetricsPortSet {
+		t.Errorf("metrics port = %q, set = %v; want 0 set explicitly", cfg.MetricsPort, cfg.MetricsPortSet)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	unsetEnv(t, "CONFIG_FILE", "TIME_ADDITION_MS", "COMPUTING_POWER", "ORCHESTRATOR_URL", "OUTBOX_FILE", "AGENT_ID")
+
+	tests := []struct {
+		name string
+		load func() error
+		want string
+	}{
+		{
+			name: "negative operation time",
+			load: func() error {
+				_, err := LoadOrchestratorConfig([]string{"--time-addition-ms=-1"})
+				return err
+			},
+			want: "некорректный time_addition_ms (TIME_ADDITION_MS, источник flag)",
+		},
+		{
+			name: "outbox without agent id",
+			load: func() error {
+				_, err := LoadAgentConfig([]string{"--outbox-file", "outbox.json"})
+				return err
+			},
+			want: "outbox_file требует agent_id",
+		},
+		{
+			name: "zero computing power",
+			load: func() error {
+				t.Setenv("COMPUTING_POWER", "0")
+				_, err := LoadAgentConfig(nil)
+				return err
+			},
+			want: "некорректный computing_power (COMPUTING_POWER, источник env)",
+		},
+		{
+			name: "not a number",
+			load: func() error {
+				_, err := LoadAgentConfig([]string{"--computing-power", "many"})
+				return err
+			},
+			want: "не целое число",
+		},
+		{
+			name: "min workers above max",
+			load: func() error {
+				_, err := LoadAgentConfig([]string{"--computing-power=3", "--min-workers=5", "--max-workers=2"})
+				return err
+			},
+			want: "min_workers 5 больше max_workers 2",
+		},
+		{
+			name: "unknown key",
+			load: func() error {
+				_, err := LoadAgentConfig([]string{"--config", writeFile(t, "[agent]\nworkers = 4\n")})
+				return err
+			},
+			want: `неизвестный ключ "workers"`,
+		},
+		{
+			name: "unquoted string",
+			load: func() error {
+				_, err := LoadAgentConfig([]string{"--config", writeFile(t, "orchestrator_url = http://localhost\n")})
+				return err
+			},
+			want: "строки указываются в кавычках",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	unsetEnv(t, "CONFIG_FILE", "AGENT_SECRET", "COMPUTING_POWER", "LOG_FORMAT")
+	t.Setenv("AGENT_SECRET", "top-secret")
+
+	cfg, err := LoadAgentConfig([]string{"--print-config", "--computing-power", "5"})
+	if err != nil {
+		t.Fatalf("LoadAgentConfig: %v", err)
+	}
+	if !cfg.PrintConfig {
+		t.Error("PrintConfig should be set")
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if strings.Contains(out, "top-secret") {
+		t.Errorf("secret leaked:\n%s", out)
+	}
+	for _, want := range []string{
+		"[agent]\n",
+		`agent_secret = "***" # env, env AGENT_SECRET`,
+		"computing_power = 5 # flag, env COMPUTING_POWER",
+		`log_format = "json" # default, env LOG_FORMAT`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
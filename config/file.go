@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseFile читает файл конфигурации в подмножестве TOML: таблицы [section], пары
+// key = value со строками в кавычках, целыми и дробными числами или логическими значениями
+// и комментарии #. Ключи вне таблиц относятся к секции "".
+func parseFile(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл конфигурации: %w", err)
+	}
+	defer file.Close()
+
+	sections := map[string]map[string]string{"": {}}
+	section := ""
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%s:%d: некорректный заголовок таблицы", path, lineNo)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if !validKey(section) {
+				return nil, fmt.Errorf("%s:%d: некорректное имя таблицы %q", path, lineNo, section)
+			}
+			if _, exists := sections[section]; !exists {
+				sections[section] = map[string]string{}
+			}
+			continue
+		}
+
+		key, raw, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: ожидается key = value", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if !validKey(key) {
+			return nil, fmt.Errorf("%s:%d: некорректный ключ %q", path, lineNo, key)
+		}
+		if _, exists := sections[section][key]; exists {
+			return nil, fmt.Errorf("%s:%d: повторяющийся ключ %q", path, lineNo, key)
+		}
+
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %v", path, lineNo, key, err)
+		}
+		sections[section][key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+
+	return sections, nil
+}
+
+// stripComment удаляет комментарий # в конце строки, если он не внутри строки в кавычках.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseValue преобразует скалярное значение TOML в строку.
+func parseValue(raw string) (string, error) {
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("не задано значение")
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") || strings.Contains(raw[1:len(raw)-1], "'") {
+			return "", fmt.Errorf("некорректная строка %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	}
+
+	number := strings.ReplaceAll(raw, "_", "")
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return "", fmt.Errorf("неподдерживаемое значение %s (строки указываются в кавычках)", raw)
+	}
+	return number, nil
+}
+
+// validKey сообщает, является ли s простым ключом TOML.
+func validKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
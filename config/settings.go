@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Источники значения параметра в порядке возрастания приоритета.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnvFile = ".env"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// knownSections перечисляет таблицы файла конфигурации. Каждый бинарник читает свою таблицу
+// и ключи верхнего уровня, а таблицы других бинарников пропускает.
+var knownSections = map[string]bool{"server": true, "orchestrator": true, "agent": true, "arithctl": true}
+
+// setting описывает один параметр конфигурации и источники его значения.
+type setting struct {
+	key    string // Ключ в файле конфигурации; имя флага — ключ с дефисами
+	env    string // Переменная окружения
+	usage  string
+	quoted bool // Выводится как строка
+	secret bool // Скрывается в выводе --print-config
+	source string
+	set    func(string) error
+	get    func() string
+	check  func() error
+}
+
+// settings — набор параметров одного бинарника.
+type settings struct {
+	section     string
+	list        []*setting
+	defaultFile string   // Файл конфигурации, который читается без --config и CONFIG_FILE, если он существует
+	allowArgs   bool     // После флагов разрешены позиционные аргументы
+	args        []string // Позиционные аргументы
+}
+
+// add регистрирует параметр, значение по умолчанию которого уже записано в поле.
+func (s *settings) add(st *setting) {
+	st.source = sourceDefault
+	s.list = append(s.list, st)
+}
+
+// String регистрирует строковый параметр.
+func (s *settings) String(p *string, key, env, def, usage string, check func(string) error) {
+	*p = def
+	s.add(&setting{
+		key: key, env: env, usage: usage, quoted: true,
+		set: func(v string) error { *p = v; return nil },
+		get: func() string { return *p },
+		check: func() error {
+			if check == nil {
+				return nil
+			}
+			return check(*p)
+		},
+	})
+}
+
+// Secret регистрирует строковый параметр, который скрывается при выводе.
+func (s *settings) Secret(p *string, key, env, usage string) {
+	s.String(p, key, env, "", usage, nil)
+	s.list[len(s.list)-1].secret = true
+}
+
+// Int регистрирует целочисленный параметр.
+func (s *settings) Int(p *int, key, env string, def int, usage string, check func(int) error) {
+	*p = def
+	s.add(&setting{
+		key: key, env: env, usage: usage,
+		set: func(v string) error {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("не целое число: %q", v)
+			}
+			*p = n
+			return nil
+		},
+		get: func() string { return strconv.Itoa(*p) },
+		check: func() error {
+			if check == nil {
+				return nil
+			}
+			return check(*p)
+		},
+	})
+}
+
+// Duration регистрирует параметр-длительность, например "30s" или "24h".
+func (s *settings) Duration(p *time.Duration, key, env string, def time.Duration, usage string, check func(time.Duration) error) {
+	*p = def
+	s.add(&setting{
+		key: key, env: env, usage: usage, quoted: true,
+		set: func(v string) error {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("некорректная длительность: %q", v)
+			}
+			*p = d
+			return nil
+		},
+		get: func() string { return p.String() },
+		check: func() error {
+			if check == nil {
+				return nil
+			}
+			return check(*p)
+		},
+	})
+}
+
+// Float регистрирует параметр с плавающей точкой.
+func (s *settings) Float(p *float64, key, env string, def float64, usage string, check func(float64) error) {
+	*p = def
+	s.add(&setting{
+		key: key, env: env, usage: usage,
+		set: func(v string) error {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("не число: %q", v)
+			}
+			*p = f
+			return nil
+		},
+		get: func() string { return strconv.FormatFloat(*p, 'g', -1, 64) },
+		check: func() error {
+			if check == nil {
+				return nil
+			}
+			return check(*p)
+		},
+	})
+}
+
+// Bool регистрирует логический параметр.
+func (s *settings) Bool(p *bool, key, env string, def bool, usage string) {
+	*p = def
+	s.add(&setting{
+		key: key, env: env, usage: usage,
+		set: func(v string) error {
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("не логическое значение: %q", v)
+			}
+			*p = b
+			return nil
+		},
+		get:   func() string { return strconv.FormatBool(*p) },
+		check: func() error { return nil },
+	})
+}
+
+// flagName возвращает имя флага командной строки для ключа конфигурации.
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// load заполняет зарегистрированные параметры значениями по умолчанию, из файла конфигурации,
+// файла .env, переменных окружения и флагов командной строки в порядке возрастания приоритета,
+// а затем проверяет их.
+func (s *settings) load(common *Common, args []string) error {
+	fs := flag.NewFlagSet(s.section, flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the config file (env CONFIG_FILE)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+
+	flagValues := make(map[string]string)
+	for _, st := range s.list {
+		key := st.key
+		fs.Func(flagName(key), fmt.Sprintf("%s (env %s)", st.usage, st.env), func(v string) error {
+			flagValues[key] = v
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 && !s.allowArgs {
+		return fmt.Errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+	}
+	s.args = fs.Args()
+
+	// .env читается при каждой загрузке и не копируется в окружение процесса,
+	// поэтому при перечитывании конфигурации его изменения применяются
+	envFile, err := godotenv.Read()
+	common.EnvFileLoaded = err == nil
+	common.PrintConfig = *printConfig
+	common.settings = s
+
+	if *configPath == "" {
+		*configPath = lookupEnv("CONFIG_FILE", envFile)
+	}
+	if *configPath == "" && s.defaultFile != "" {
+		if _, err := os.Stat(s.defaultFile); err == nil {
+			*configPath = s.defaultFile
+		}
+	}
+	fileValues, err := s.fileValues(*configPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, st := range s.list {
+		if v, ok := fileValues[st.key]; ok {
+			errs = append(errs, st.apply(v, sourceFile, *configPath))
+		}
+		if v, ok := os.LookupEnv(st.env); ok {
+			errs = append(errs, st.apply(v, sourceEnv, st.env))
+		} else if v, ok := envFile[st.env]; ok {
+			errs = append(errs, st.apply(v, sourceEnvFile, ".env"))
+		}
+		if v, ok := flagValues[st.key]; ok {
+			errs = append(errs, st.apply(v, sourceFlag, "--"+flagName(st.key)))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for _, st := range s.list {
+		if err := st.check(); err != nil {
+			errs = append(errs, fmt.Errorf("некорректный %s (%s, источник %s): %v", st.key, st.env, st.source, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isSet сообщает, задано ли значение параметра явно, а не взято по умолчанию.
+func (s *settings) isSet(key string) bool {
+	for _, st := range s.list {
+		if st.key == key {
+			return st.source != sourceDefault
+		}
+	}
+	return false
+}
+
+// lookupEnv возвращает переменную окружения, а если она не задана — значение из файла .env.
+func lookupEnv(key string, envFile map[string]string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return envFile[key]
+}
+
+// apply разбирает значение из заданного источника.
+func (st *setting) apply(value, source, origin string) error {
+	if err := st.set(value); err != nil {
+		return fmt.Errorf("некорректный %s в %s: %v", st.key, origin, err)
+	}
+	st.source = source
+	return nil
+}
+
+// fileValues возвращает ключи верхнего уровня файла конфигурации, объединенные с ключами
+// таблицы этого бинарника. Неизвестные ключи и таблицы отклоняются.
+func (s *settings) fileValues(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	if path == "" {
+		return values, nil
+	}
+
+	sections, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(s.list))
+	for _, st := range s.list {
+		known[st.key] = true
+	}
+
+	for name, section := range sections {
+		if name != "" && !knownSections[name] {
+			return nil, fmt.Errorf("%s: неизвестная таблица [%s]", path, name)
+		}
+		if name != "" && name != s.section {
+			continue
+		}
+		for key, value := range section {
+			if !known[key] {
+				if name == "" {
+					// Ключи верхнего уровня могут относиться к другому бинарнику
+					continue
+				}
+				return nil, fmt.Errorf("%s: неизвестный ключ %q в [%s]", path, key, name)
+			}
+			if _, exists := values[key]; !exists || name != "" {
+				values[key] = value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// print выводит итоговую конфигурацию в формате файла конфигурации с указанием
+// источника каждого значения. Секреты скрываются.
+func (s *settings) print(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "[%s]\n", s.section); err != nil {
+		return err
+	}
+
+	for _, st := range s.list {
+		value := st.get()
+		if st.secret && value != "" {
+			value = "***"
+		}
+		if st.quoted || st.secret {
+			value = strconv.Quote(value)
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s # %s, env %s\n", st.key, value, st.source, st.env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
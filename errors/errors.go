@@ -32,4 +32,18 @@ const (
 	ErrInvalidExpression = "Invalid expression"
 	ErrMalformedJSON     = "Malformed JSON"
 	ErrUnsupportedMethod = "Unsupported HTTP method"
+	ErrTooLargeBatch     = "Batch is too large"
+	ErrTooLargeBody      = "Request body is too large"
+
+	ErrUnauthorized             = "Unauthorized"
+	ErrForbidden                = "Forbidden"
+	ErrInvalidCredentials       = "Invalid login or password"
+	ErrInvalidCredentialsFormat = "Login must not be empty and password must be at least 8 characters"
+	ErrUserExists               = "User already exists"
+	ErrInvalidAPIKey            = "Invalid API key"
+	ErrUnknownKeyOwner          = "API key owner must be an existing user"
+	ErrRateLimitExceeded        = "Rate limit exceeded"
+
+	ErrIdempotencyMismatch   = "Idempotency key was already used with a different request body"
+	ErrIdempotencyInProgress = "A request with this idempotency key is already in progress"
 )
@@ -1,16 +1,37 @@
 package agent
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
-	"io"
-	"log"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
 	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoTasks возвращается транспортом, когда у оркестратора нет готовых задач
+var ErrNoTasks = errors.New("нет доступных задач")
+
+// ErrResultRejected возвращается транспортом, когда оркестратор окончательно отклонил результат
+// и повторная отправка бессмысленна
+var ErrResultRejected = errors.New("оркестратор отклонил результат")
+
+// errCircuitOpen возвращается, когда запросы к оркестратору приостановлены выключателем
+var errCircuitOpen = errors.New("запросы к оркестратору приостановлены")
+
+const (
+	// pollInterval — пауза между опросами, когда у оркестратора нет задач
+	pollInterval = 1 * time.Second
+	// outboxInterval — период проверки результатов, ожидающих повторной отправки
+	outboxInterval = 200 * time.Millisecond
 )
 
 // Agent представляет агента, выполняющего задачи
@@ -19,177 +40,374 @@ type Agent struct {
 	computingPower  int
 	client          *http.Client
 	wg              sync.WaitGroup
+	authToken       string // Общий секрет агентов для внутреннего API
+	id              string // Идентификатор агента в логах и запросах к оркестратору
+	logger          *zap.Logger
+
+	polls    *metrics.CounterVec   // Запросы задач по результату
+	execTime *metrics.HistogramVec // Время выполнения задач по операции
+	results  *metrics.CounterVec   // Отправленные результаты по исходу
+
+	tracer *tracing.Tracer // Трассировщик выполнения задач (может быть nil)
+
+	outbox  *Outbox         // Недоставленные результаты
+	backoff Backoff         // Задержки повторных попыток
+	breaker *CircuitBreaker // Выключатель запросов к недоступному оркестратору
+
+	executors *Executors // Исполнители операций
+
+	concurrency Concurrency // Границы количества воркеров
+	ctrl        controller  // Состояние управления воркерами
+
+	transport Transport // Связь с оркестратором
 }
 
-// NewAgent создает нового агента
+// execBuckets — границы гистограммы времени выполнения задач (в секундах)
+var execBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// NewAgent создает нового агента, обращающегося к оркестратору по HTTP
 func NewAgent(orchestratorURL string, computingPower int) *Agent {
-	return &Agent{
+	a := &Agent{
 		orchestratorURL: orchestratorURL,
 		computingPower:  computingPower,
+		id:              defaultAgentID(),
+		logger:          zap.NewNop(),
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		outbox:    &Outbox{entries: make(map[int]OutboxEntry)},
+		backoff:   Backoff{Base: 500 * time.Millisecond, Max: 30 * time.Second},
+		breaker:   NewCircuitBreaker(5, 10*time.Second),
+		executors: NewBuiltinExecutors(SimulatedDelay),
+		concurrency: Concurrency{
+			Min: computingPower,
+			Max: computingPower,
+		},
 	}
+	a.transport = &httpTransport{agent: a}
+	return a
 }
 
-// Start запускает агента
-func (a *Agent) Start() {
-	log.Printf("Запуск агента с %d воркерами\n", a.computingPower)
+// defaultAgentID возвращает идентификатор агента вида <hostname>-<pid>
+func defaultAgentID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "agent"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
 
-	// Запускаем воркеры
-	for i := 0; i < a.computingPower; i++ {
-		a.wg.Add(1)
-		go a.worker(i)
+// SetID задает идентификатор агента
+func (a *Agent) SetID(id string) {
+	if id != "" {
+		a.id = id
 	}
+}
+
+// SetLogger задает логгер агента
+func (a *Agent) SetLogger(logger *zap.Logger) {
+	a.logger = logger
+}
+
+// SetOutbox задает очередь недоставленных результатов, например сохраняемую в файле
+func (a *Agent) SetOutbox(outbox *Outbox) {
+	a.outbox = outbox
+}
 
-	// Ожидаем завершения всех воркеров
+// SetRetryPolicy задает задержки повторных попыток и параметры выключателя: после threshold
+// ошибок подряд запросы к оркестратору приостанавливаются на cooldown
+func (a *Agent) SetRetryPolicy(backoff Backoff, threshold int, cooldown time.Duration) {
+	a.backoff = backoff
+	a.breaker = NewCircuitBreaker(threshold, cooldown)
+}
+
+// SetExecutors задает исполнителей операций
+func (a *Agent) SetExecutors(executors *Executors) {
+	a.executors = executors
+}
+
+// SetAuthToken задает общий секрет, предъявляемый оркестратору
+func (a *Agent) SetAuthToken(token string) {
+	a.authToken = token
+}
+
+// SetMetrics регистрирует метрики агента в реестре
+func (a *Agent) SetMetrics(registry *metrics.Registry) {
+	a.polls = registry.NewCounterVec("agent_polls_total",
+		"Запросы задач у оркестратора по результату (task, empty или error).", "result")
+	a.execTime = registry.NewHistogramVec("agent_task_execution_seconds",
+		"Время выполнения задач агентом.", execBuckets, "operation")
+	a.results = registry.NewCounterVec("agent_results_total",
+		"Исходы отправки результатов задач (ok, error, retry или rejected).", "result")
+	registry.NewGaugeFunc("agent_outbox_pending", "Результаты, ожидающие доставки оркестратору.", func() float64 {
+		return float64(a.outbox.Len())
+	})
+	registry.NewGaugeFunc("agent_circuit_open", "Приостановлены ли запросы к оркестратору (1 — да).", func() float64 {
+		if a.breaker.State() == breakerOpen {
+			return 1
+		}
+		return 0
+	})
+	registry.NewGaugeFunc("agent_workers", "Количество работающих воркеров агента.", func() float64 {
+		return float64(a.Workers())
+	})
+}
+
+// SetTracer включает трассировку выполнения задач, продолжающую трассу из traceparent задачи
+func (a *Agent) SetTracer(tracer *tracing.Tracer) {
+	a.tracer = tracer
+}
+
+// count увеличивает счетчик, если метрики включены
+func count(counter *metrics.CounterVec, label string) {
+	if counter != nil {
+		counter.WithLabelValues(label).Inc()
+	}
+}
+
+// Start запускает воркеры агента и блокируется до отмены ctx. После отмены воркеры
+// перестают запрашивать задачи, но завершают текущие и отправляют их результаты
+func (a *Agent) Start(ctx context.Context) {
+	// Запускаем повторную отправку результатов, в том числе сохраненных до перезапуска
+	outboxDone := make(chan struct{})
+	go func() {
+		a.runOutbox(ctx)
+		close(outboxDone)
+	}()
+
+	// Запускаем воркеры и пересчет их количества
+	a.startWorkers(ctx)
+	a.logger.Info("Agent started",
+		logging.AgentID(a.id),
+		zap.Int("workers", a.Workers()),
+		zap.Int("min_workers", a.concurrency.Min),
+		zap.Int("max_workers", a.concurrency.Max),
+		zap.Int("outbox_pending", a.outbox.Len()),
+	)
+	controllerDone := make(chan struct{})
+	go func() {
+		a.runController(ctx)
+		close(controllerDone)
+	}()
+
+	// Ожидаем завершения всех воркеров и делаем последнюю попытку доставить результаты.
+	// Воркеры запускаются только контроллером, поэтому сначала дожидаемся его
+	<-controllerDone
 	a.wg.Wait()
+	<-outboxDone
+	a.flushOutbox(time.Time{})
+
+	fields := []zap.Field{logging.AgentID(a.id)}
+	if pending := a.outbox.Len(); pending > 0 {
+		fields = append(fields, zap.Int("outbox_pending", pending))
+	}
+	a.logger.Info("Agent stopped", fields...)
 }
 
 // Worker представляет горутину, выполняющую задачи
-func (a *Agent) worker(id int) {
+func (a *Agent) worker(ctx context.Context, id int) {
 	defer a.wg.Done()
 
-	log.Printf("Воркер %d запущен\n", id)
+	logger := a.logger.With(logging.AgentID(a.id), logging.WorkerID(id))
+	logger.Debug("Worker started")
+	defer logger.Debug("Worker stopped")
 
-	for {
-		// Запрашиваем задачу
-		task, err := a.getTask()
-		if err != nil {
-			log.Printf("Воркер %d: ошибка получения задачи: %v\n", id, err)
-			time.Sleep(1 * time.Second) // Пауза перед следующей попыткой
+	failures := 0 // Ошибки опроса подряд
+	for ctx.Err() == nil {
+		// Запрашиваем задачу. Начатый запрос не прерывается отменой ctx: иначе выданная
+		// оркестратором задача осталась бы невыполненной
+		task, err := a.pollTask(context.WithoutCancel(ctx))
+		switch {
+		case err == nil:
+			failures = 0
+		case errors.Is(err, ErrNoTasks):
+			failures = 0
+			logger.Debug("No tasks available")
+			sleep(ctx, pollInterval)
+			continue
+		case errors.Is(err, errCircuitOpen):
+			sleep(ctx, pollInterval)
+			continue
+		default:
+			// Повторяем опрос с экспоненциальной задержкой
+			failures++
+			delay := a.backoff.Delay(failures)
+			logger.Warn("Task polling failed", zap.Error(err), zap.Int("attempt", failures), zap.Duration("retry_in", delay))
+			sleep(ctx, delay)
 			continue
 		}
 
-		log.Printf("Воркер %d: получена задача %d (%s %s %s)\n", id, task.ID, task.Arg1, task.Operation, task.Arg2)
+		taskLogger := logger.With(logging.TaskID(task.ID), logging.ExpressionID(task.ExpressionID))
+		taskLogger.Debug("Task received",
+			zap.String("operation", string(task.Operation)),
+			zap.String("arg1", task.Arg1),
+			zap.String("arg2", task.Arg2),
+		)
 
-		// Выполняем задачу
+		// Выполняем задачу в спане, продолжающем трассу оркестратора. Полученная задача
+		// выполняется и отправляется даже после отмены ctx, чтобы результат не потерялся
+		start := time.Now()
+		span := a.startSpan(task, start)
 		result, err := a.executeTask(task)
+		if a.execTime != nil {
+			a.execTime.WithLabelValues(string(task.Operation)).Observe(time.Since(start).Seconds())
+		}
+		entry := OutboxEntry{TaskID: task.ID, Result: result}
+		if sc := span.SpanContext(); sc.IsValid() {
+			entry.TraceParent = sc.Traceparent()
+		}
 		if err != nil {
-			log.Printf("Воркер %d: ошибка выполнения задачи %d: %v\n", id, task.ID, err)
-			// Отправляем информацию об ошибке
-			if sendErr := a.sendResult(task.ID, 0, err.Error()); sendErr != nil {
-				log.Printf("Воркер %d: ошибка отправки результата задачи %d: %v\n", id, task.ID, sendErr)
-			}
-			continue
+			span.SetError(err.Error())
+			taskLogger.Info("Task failed", zap.Error(err))
+			entry.Result, entry.Error = 0, err.Error()
+		} else {
+			taskLogger.Debug("Task completed", zap.Float64("result", result))
 		}
+		span.End()
 
-		log.Printf("Воркер %d: задача %d выполнена, результат: %f\n", id, task.ID, result)
-
-		// Отправляем результат
-		if err := a.sendResult(task.ID, result, ""); err != nil {
-			log.Printf("Воркер %d: ошибка отправки результата задачи %d: %v\n", id, task.ID, err)
+		// Сохраняем результат в outbox до отправки, чтобы он не потерялся при ошибке доставки.
+		// Повторная отправка из outbox откладывается, пока идет первая попытка
+		entry.NextAttempt = time.Now().Add(a.client.Timeout)
+		if err := a.outbox.Put(entry); err != nil {
+			taskLogger.Error("Saving result to outbox failed", zap.Error(err))
 		}
+		a.deliver(entry, taskLogger)
 	}
 }
 
-// getTask запрашивает задачу у оркестратора
-func (a *Agent) getTask() (*models.Task, error) {
-	resp, err := a.client.Get(fmt.Sprintf("%s/internal/task", a.orchestratorURL))
-	if err != nil {
-		return nil, err
+// pollTask запрашивает задачу, если выключатель разрешает обращение к оркестратору
+func (a *Agent) pollTask(ctx context.Context) (*models.Task, error) {
+	if !a.breaker.Allow() {
+		return nil, errCircuitOpen
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Printf("Ошибка закрытия тела ответа: %v\n", err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("нет доступных задач")
+	task, backlog, err := a.transport.GetTask(ctx, a.id)
+	switch {
+	case err == nil:
+		a.breaker.Success()
+		a.observePoll(true, backlog)
+		count(a.polls, "task")
+	case errors.Is(err, ErrNoTasks):
+		a.breaker.Success()
+		a.observePoll(false, backlog)
+		count(a.polls, "empty")
+	default:
+		a.breaker.Failure()
+		count(a.polls, "error")
 	}
+	return task, err
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("неожиданный код ответа: %d", resp.StatusCode)
+// sleep ожидает d или отмены ctx
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
 	}
+}
 
-	var taskResp models.TaskResponse
-	if err := json.NewDecoder(resp.Body).Decode(&taskResp); err != nil {
-		return nil, err
+// deliver отправляет результат из outbox. Доставленный или отклоненный оркестратором результат
+// удаляется из outbox, при временной ошибке планируется повторная попытка
+func (a *Agent) deliver(entry OutboxEntry, logger *zap.Logger) {
+	if !a.breaker.Allow() {
+		return
 	}
 
-	return taskResp.Task, nil
-}
+	ctx := context.Background()
+	if parent, err := tracing.ParseTraceparent(entry.TraceParent); err == nil {
+		ctx = tracing.ContextWithSpanContext(ctx, parent)
+	}
 
-// executeTask выполняет задачу и возвращает результат
-func (a *Agent) executeTask(task *models.Task) (float64, error) {
-	// Замеряем время начала
-	start := time.Now()
-
-	// Парсим аргументы
-	arg1, err1 := strconv.ParseFloat(task.Arg1, 64)
-	arg2, err2 := strconv.ParseFloat(task.Arg2, 64)
-
-	var result float64
-	var execError error
-
-	// Проверяем корректность аргументов
-	if err1 != nil {
-		execError = fmt.Errorf("некорректный аргумент 1: %s", task.Arg1)
-	} else if err2 != nil {
-		execError = fmt.Errorf("некорректный аргумент 2: %s", task.Arg2)
-	} else {
-		// Выполняем операцию
-		switch task.Operation {
-		case models.OperationAdd:
-			result = arg1 + arg2
-		case models.OperationSubtract:
-			result = arg1 - arg2
-		case models.OperationMultiply:
-			result = arg1 * arg2
-		case models.OperationDivide:
-			if arg2 == 0 {
-				execError = fmt.Errorf("деление на ноль")
-			} else {
-				result = arg1 / arg2
-			}
-		default:
-			execError = fmt.Errorf("неизвестная операция: %s", task.Operation)
+	err := a.sendResult(ctx, entry.TaskID, entry.Result, entry.Error)
+	switch {
+	case err == nil:
+		a.breaker.Success()
+		if entry.Error != "" {
+			count(a.results, "error")
+		} else {
+			count(a.results, "ok")
+		}
+	case errors.Is(err, ErrResultRejected):
+		a.breaker.Success()
+		count(a.results, "rejected")
+		logger.Warn("Task result rejected", zap.Error(err))
+	default:
+		a.breaker.Failure()
+		count(a.results, "retry")
+		entry.Attempts++
+		delay := a.backoff.Delay(entry.Attempts)
+		entry.NextAttempt = time.Now().Add(delay)
+		logger.Warn("Sending task result failed, will retry",
+			zap.Error(err), zap.Int("attempt", entry.Attempts), zap.Duration("retry_in", delay))
+		if err := a.outbox.Put(entry); err != nil {
+			logger.Error("Saving result to outbox failed", zap.Error(err))
 		}
+		return
 	}
 
-	// Проверяем время выполнения
-	elapsed := time.Since(start)
-	remainingTime := time.Duration(task.OperationTime)*time.Millisecond - elapsed
-	if remainingTime > 0 {
-		time.Sleep(remainingTime)
+	if err := a.outbox.Remove(entry.TaskID); err != nil {
+		logger.Error("Removing result from outbox failed", zap.Error(err))
 	}
-
-	return result, execError
 }
 
-// sendResult отправляет результат задачи оркестратору
-func (a *Agent) sendResult(taskID int, result float64, errMsg string) error {
-	reqBody := models.TaskResultRequest{
-		ID:     taskID,
-		Result: result,
-		Error:  errMsg,
+// runOutbox периодически повторяет отправку недоставленных результатов до отмены ctx
+func (a *Agent) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.flushOutbox(now)
+		}
 	}
+}
 
-	reqData, err := json.Marshal(reqBody)
-	if err != nil {
-		return err
+// flushOutbox отправляет результаты, время повторной попытки которых наступило к now.
+// Нулевое now означает все результаты
+func (a *Agent) flushOutbox(now time.Time) {
+	for _, entry := range a.outbox.Due(now) {
+		a.deliver(entry, a.logger.With(logging.AgentID(a.id), logging.TaskID(entry.TaskID)))
 	}
+}
 
-	resp, err := a.client.Post(
-		fmt.Sprintf("%s/internal/task", a.orchestratorURL),
-		"application/json",
-		bytes.NewBuffer(reqData),
-	)
+// startSpan начинает спан выполнения задачи, дочерний к traceparent из задачи.
+// Задачи без контекста трассировки не трассируются
+func (a *Agent) startSpan(task *models.Task, start time.Time) *tracing.Span {
+	if a.tracer == nil || task.TraceParent == "" {
+		return nil
+	}
+	parent, err := tracing.ParseTraceparent(task.TraceParent)
 	if err != nil {
-		return err
+		return nil
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Printf("Ошибка закрытия тела ответа: %v\n", err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("неожиданный код ответа: %d", resp.StatusCode)
-	}
+	span := a.tracer.StartAt(parent, "agent.execute", tracing.SpanKindInternal, start)
+	span.SetAttribute("task.id", strconv.Itoa(task.ID))
+	span.SetAttribute("task.operation", string(task.Operation))
+	return span
+}
+
+// Ping проверяет доступность оркестратора
+func (a *Agent) Ping(ctx context.Context) error {
+	return a.transport.Ping(ctx)
+}
+
+// executeTask выполняет задачу исполнителем ее операции и возвращает результат
+func (a *Agent) executeTask(task *models.Task) (float64, error) {
+	return a.executors.Execute(context.Background(), task)
+}
 
-	return nil
+// sendResult отправляет результат задачи оркестратору, передавая контекст трассировки из ctx
+func (a *Agent) sendResult(ctx context.Context, taskID int, result float64, errMsg string) error {
+	return a.transport.SendResult(ctx, a.id, models.TaskResultRequest{
+		ID:     taskID,
+		Result: result,
+		Error:  errMsg,
+	})
 }
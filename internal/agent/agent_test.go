@@ -1,7 +1,15 @@
 package agent
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -124,3 +132,86 @@ func TestExecuteTask(t *testing.T) {
 		})
 	}
 }
+
+// TestTracePropagation проверяет, что агент продолжает трассу из задачи
+// и передает ее оркестратору вместе с результатом
+func TestTracePropagation(t *testing.T) {
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(tracing.TraceparentHeader)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	a := NewAgent(server.URL, 1)
+	a.SetTracer(tracing.NewTracer("agent", tracing.NewWriterExporter(&buf)))
+
+	span := a.startSpan(&models.Task{ID: 7, Operation: models.OperationAdd, TraceParent: parent}, time.Now())
+	span.End()
+	ctx := tracing.ContextWithSpanContext(context.Background(), span.SpanContext())
+	if err := a.sendResult(ctx, 7, 3, ""); err != nil {
+		t.Fatalf("Ошибка отправки результата: %v", err)
+	}
+
+	header := <-received
+	sc, err := tracing.ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("Результат отправлен без traceparent: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc != span.SpanContext() {
+		t.Errorf("traceparent %s не соответствует спану агента", header)
+	}
+	if !strings.Contains(buf.String(), `"parentSpanId":"00f067aa0ba902b7"`) || !strings.Contains(buf.String(), `"name":"agent.execute"`) {
+		t.Errorf("Спан агента не экспортирован как дочерний:\n%s", buf.String())
+	}
+}
+
+// TestStartGracefulStop проверяет, что после отмены контекста агент перестает запрашивать
+// задачи, но отправляет результат уже полученной
+func TestStartGracefulStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutex sync.Mutex
+	polls, results := 0, 0
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.Method == http.MethodPost {
+			results++
+			return
+		}
+		polls++
+		if polls > 1 {
+			http.Error(w, "Нет доступных задач", http.StatusNotFound)
+			return
+		}
+		// Останавливаем агента, пока задача выполняется
+		cancel()
+		json.NewEncoder(w).Encode(models.TaskResponse{Task: &models.Task{
+			ID: 1, Arg1: "2", Arg2: "2", Operation: models.OperationAdd, OperationTime: 50,
+		}})
+	}))
+	defer orchestrator.Close()
+
+	done := make(chan struct{})
+	go func() {
+		NewAgent(orchestrator.URL, 1).Start(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("агент не остановился после отмены контекста")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if polls != 1 || results != 1 {
+		t.Errorf("polls = %d, results = %d; want 1 and 1", polls, results)
+	}
+}
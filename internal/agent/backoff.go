@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Backoff вычисляет экспоненциальную задержку между повторными попытками со случайным
+// разбросом, чтобы агенты не обращались к восстановившемуся оркестратору одновременно
+type Backoff struct {
+	Base time.Duration // Задержка перед второй попыткой
+	Max  time.Duration // Верхняя граница задержки
+}
+
+// Delay возвращает задержку перед повторной попыткой с номером attempt (начиная с 1):
+// случайное значение из [d/2, d], где d = Base·2^(attempt-1), но не больше Max
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Max
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt <= 32 {
+		if exp := b.Base << (attempt - 1); exp > 0 && exp < b.Max {
+			d = exp
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Состояния автоматического выключателя
+const (
+	breakerClosed   = "closed"    // Запросы выполняются
+	breakerOpen     = "open"      // Запросы не выполняются до истечения паузы
+	breakerHalfOpen = "half_open" // Выполняется пробный запрос
+)
+
+// CircuitBreaker прекращает обращения к оркестратору после нескольких ошибок подряд.
+// По истечении паузы пропускает один пробный запрос: при успехе обращения возобновляются,
+// при ошибке пауза начинается заново
+type CircuitBreaker struct {
+	threshold int           // Количество ошибок подряд до размыкания
+	cooldown  time.Duration // Пауза перед пробным запросом
+	state     string
+	failures  int
+	changedAt time.Time // Момент размыкания или начала пробного запроса
+	mutex     sync.Mutex
+}
+
+// NewCircuitBreaker создает замкнутый выключатель
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     breakerClosed,
+	}
+}
+
+// Allow сообщает, можно ли выполнить запрос к оркестратору
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerClosed {
+		return true
+	}
+	// Если пробный запрос не завершился за время паузы, разрешаем новый
+	if time.Since(b.changedAt) < b.cooldown {
+		return false
+	}
+	b.state = breakerHalfOpen
+	b.changedAt = time.Now()
+	return true
+}
+
+// Success отмечает успешный запрос и замыкает выключатель
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// Failure отмечает неудачный запрос. Выключатель размыкается после threshold ошибок подряд
+// или после неудачного пробного запроса
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.changedAt = time.Now()
+	}
+}
+
+// State возвращает текущее состояние выключателя: closed, open или half_open
+func (b *CircuitBreaker) State() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
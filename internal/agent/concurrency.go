@@ -0,0 +1,239 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// adjustInterval — период пересчета количества воркеров
+const adjustInterval = time.Second
+
+// Concurrency задает границы адаптивного количества воркеров
+type Concurrency struct {
+	Min       int     // Минимальное количество воркеров
+	Max       int     // Максимальное количество воркеров
+	CPUBudget float64 // Доступные агенту ядра процессора (0 — без ограничения)
+}
+
+// adaptive сообщает, может ли количество воркеров меняться
+func (c Concurrency) adaptive() bool {
+	return c.Max > c.Min
+}
+
+// target вычисляет количество воркеров по итогам интервала: при превышении бюджета процессора
+// воркеров становится меньше, при очереди у оркестратора — больше (не более чем вдвое за интервал),
+// при пустых опросах — меньше. backlog < 0 означает, что оркестратор не сообщил размер очереди
+func (c Concurrency) target(current, backlog, polls, empty int, cpuUsage float64) int {
+	next := current
+	switch {
+	case c.CPUBudget > 0 && cpuUsage > c.CPUBudget:
+		next--
+	case backlog > 0:
+		next += min(backlog, current)
+	case backlog < 0 && polls > 0 && empty == 0:
+		next++
+	case empty > 0:
+		next--
+	}
+	return max(c.Min, min(c.Max, next))
+}
+
+// Status — текущее состояние агента
+type Status struct {
+	ID            string  `json:"id"`
+	Workers       int     `json:"workers"`              // Работающие воркеры
+	MinWorkers    int     `json:"min_workers"`          // Нижняя граница количества воркеров
+	MaxWorkers    int     `json:"max_workers"`          // Верхняя граница количества воркеров
+	Backlog       int     `json:"backlog"`              // Готовые задачи у оркестратора по последнему опросу (-1 — неизвестно)
+	CPUUsage      float64 `json:"cpu_usage"`            // Ядер процессора использовано за последний интервал
+	CPUBudget     float64 `json:"cpu_budget,omitempty"` // Доступные агенту ядра процессора
+	OutboxPending int     `json:"outbox_pending"`       // Недоставленные результаты
+	Breaker       string  `json:"breaker"`              // Состояние выключателя запросов к оркестратору
+}
+
+// controller управляет воркерами агента и собирает сведения для пересчета их количества
+type controller struct {
+	workers  map[int]context.CancelFunc // Остановка работающих воркеров
+	nextID   int                        // ID следующего воркера
+	backlog  int                        // Размер очереди оркестратора по последнему опросу
+	polls    int                        // Опросы за текущий интервал
+	empty    int                        // Пустые опросы за текущий интервал
+	cpuTime  time.Duration              // Процессорное время процесса на начало интервала
+	cpuAt    time.Time                  // Начало интервала измерения процессорного времени
+	cpuUsage float64                    // Ядер процессора использовано за последний интервал
+	mutex    sync.Mutex
+}
+
+// SetConcurrency задает границы количества воркеров. Начальное количество — computingPower,
+// приведенное к границам
+func (a *Agent) SetConcurrency(c Concurrency) {
+	c.Min = max(c.Min, 1)
+	c.Max = max(c.Max, c.Min)
+	a.concurrency = c
+}
+
+// spawnWorker запускает воркер со своим контекстом, отмена которого останавливает только его.
+// Вызывается под блокировкой мьютекса контроллера
+func (a *Agent) spawnWorker(ctx context.Context) {
+	workerCtx, cancel := context.WithCancel(ctx)
+	id := a.ctrl.nextID
+	a.ctrl.nextID++
+	a.ctrl.workers[id] = cancel
+
+	a.wg.Add(1)
+	go func() {
+		defer cancel()
+		a.worker(workerCtx, id)
+
+		a.ctrl.mutex.Lock()
+		delete(a.ctrl.workers, id)
+		a.ctrl.mutex.Unlock()
+	}()
+}
+
+// stopWorker останавливает последний запущенный воркер: он завершает текущую задачу и выходит.
+// Вызывается под блокировкой мьютекса контроллера
+func (a *Agent) stopWorker() {
+	last := -1
+	for id := range a.ctrl.workers {
+		last = max(last, id)
+	}
+	if last >= 0 {
+		a.ctrl.workers[last]()
+		delete(a.ctrl.workers, last)
+	}
+}
+
+// startWorkers запускает начальное количество воркеров
+func (a *Agent) startWorkers(ctx context.Context) {
+	a.ctrl.mutex.Lock()
+	defer a.ctrl.mutex.Unlock()
+
+	a.ctrl.workers = make(map[int]context.CancelFunc)
+	a.ctrl.backlog = -1
+	a.ctrl.cpuTime, _ = processCPUTime()
+	a.ctrl.cpuAt = time.Now()
+
+	initial := max(a.concurrency.Min, min(a.concurrency.Max, a.computingPower))
+	for i := 0; i < initial; i++ {
+		a.spawnWorker(ctx)
+	}
+}
+
+// runController раз в adjustInterval пересчитывает количество воркеров до отмены ctx
+func (a *Agent) runController(ctx context.Context) {
+	if !a.concurrency.adaptive() && a.concurrency.CPUBudget == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(adjustInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.adjust(ctx)
+		}
+	}
+}
+
+// adjust доводит количество воркеров до вычисленного по итогам интервала
+func (a *Agent) adjust(ctx context.Context) {
+	a.ctrl.mutex.Lock()
+	defer a.ctrl.mutex.Unlock()
+
+	a.sampleCPU()
+	current := len(a.ctrl.workers)
+	next := a.concurrency.target(current, a.ctrl.backlog, a.ctrl.polls, a.ctrl.empty, a.ctrl.cpuUsage)
+	a.ctrl.polls, a.ctrl.empty = 0, 0
+	if next == current || ctx.Err() != nil {
+		return
+	}
+
+	for len(a.ctrl.workers) < next {
+		a.spawnWorker(ctx)
+	}
+	for len(a.ctrl.workers) > next {
+		a.stopWorker()
+	}
+	a.logger.Debug("Workers adjusted",
+		logging.AgentID(a.id),
+		zap.Int("from", current),
+		zap.Int("to", next),
+		zap.Int("backlog", a.ctrl.backlog),
+		zap.Float64("cpu_usage", a.ctrl.cpuUsage),
+	)
+}
+
+// sampleCPU вычисляет использование процессора с начала интервала.
+// Вызывается под блокировкой мьютекса контроллера
+func (a *Agent) sampleCPU() {
+	cpuTime, ok := processCPUTime()
+	now := time.Now()
+	if wall := now.Sub(a.ctrl.cpuAt); ok && wall > 0 {
+		a.ctrl.cpuUsage = float64(cpuTime-a.ctrl.cpuTime) / float64(wall)
+	}
+	a.ctrl.cpuTime, a.ctrl.cpuAt = cpuTime, now
+}
+
+// observePoll учитывает результат опроса и размер очереди, о котором сообщил оркестратор
+// (backlog < 0 — не сообщил)
+func (a *Agent) observePoll(found bool, backlog int) {
+	a.ctrl.mutex.Lock()
+	defer a.ctrl.mutex.Unlock()
+
+	a.ctrl.polls++
+	if !found {
+		a.ctrl.empty++
+	}
+	if backlog >= 0 {
+		a.ctrl.backlog = backlog
+	}
+}
+
+// Workers возвращает количество работающих воркеров
+func (a *Agent) Workers() int {
+	a.ctrl.mutex.Lock()
+	defer a.ctrl.mutex.Unlock()
+
+	return len(a.ctrl.workers)
+}
+
+// Status возвращает текущее состояние агента
+func (a *Agent) Status() Status {
+	a.ctrl.mutex.Lock()
+	status := Status{
+		ID:         a.id,
+		Workers:    len(a.ctrl.workers),
+		MinWorkers: a.concurrency.Min,
+		MaxWorkers: a.concurrency.Max,
+		Backlog:    a.ctrl.backlog,
+		CPUUsage:   a.ctrl.cpuUsage,
+		CPUBudget:  a.concurrency.CPUBudget,
+	}
+	a.ctrl.mutex.Unlock()
+
+	status.OutboxPending = a.outbox.Len()
+	status.Breaker = a.breaker.State()
+	return status
+}
+
+// StatusHandler отдает текущее состояние агента в формате JSON
+func (a *Agent) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a.Status())
+	})
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrency_Target(t *testing.T) {
+	c := Concurrency{Min: 1, Max: 8, CPUBudget: 2}
+
+	tests := []struct {
+		name                           string
+		current, backlog, polls, empty int
+		cpuUsage                       float64
+		want                           int
+	}{
+		{"backlog doubles workers", 2, 10, 5, 0, 0.5, 4},
+		{"backlog smaller than workers", 4, 1, 5, 0, 0.5, 5},
+		{"growth capped by max", 6, 100, 5, 0, 0.5, 8},
+		{"over cpu budget", 4, 10, 5, 0, 2.5, 3},
+		{"empty polls", 4, 0, 5, 3, 0.5, 3},
+		{"not below min", 1, 0, 5, 5, 0.5, 1},
+		{"steady", 3, 0, 5, 0, 0.5, 3},
+		{"unknown backlog, all polls busy", 3, -1, 5, 0, 0.5, 4},
+	}
+
+	for _, tt := range tests {
+		if got := c.target(tt.current, tt.backlog, tt.polls, tt.empty, tt.cpuUsage); got != tt.want {
+			t.Errorf("%s: target() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAdaptiveWorkers(t *testing.T) {
+	var depth atomic.Int64
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(models.QueueDepthHeader, strconv.FormatInt(depth.Load(), 10))
+		http.Error(w, "Нет доступных задач", http.StatusNotFound)
+	}))
+	defer orchestrator.Close()
+
+	a := NewAgent(orchestrator.URL, 1)
+	a.SetConcurrency(Concurrency{Min: 1, Max: 4})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.startWorkers(ctx)
+	defer func() {
+		cancel()
+		a.wg.Wait()
+	}()
+
+	queue := func(n int) int {
+		depth.Store(int64(n))
+		return n
+	}
+
+	// Очередь у оркестратора увеличивает количество воркеров до верхней границы
+	a.observePoll(true, queue(10))
+	for _, want := range []int{2, 4, 4} {
+		a.adjust(ctx)
+		if got := a.Workers(); got != want {
+			t.Fatalf("with backlog: workers = %d, want %d", got, want)
+		}
+	}
+
+	// Пустые опросы уменьшают количество воркеров
+	a.observePoll(false, queue(0))
+	a.adjust(ctx)
+	if got := a.Workers(); got != 3 {
+		t.Errorf("after empty poll: workers = %d, want 3", got)
+	}
+
+	rr := httptest.NewRecorder()
+	a.StatusHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	var status Status
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Workers != 3 || status.MinWorkers != 1 || status.MaxWorkers != 4 || status.Backlog != 0 {
+		t.Errorf("status = %+v", status)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "time"
+
+// processCPUTime не поддерживается на этой платформе: бюджет процессора не учитывается
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
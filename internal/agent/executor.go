@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Executor выполняет одну операцию над двумя аргументами
+type Executor interface {
+	Execute(ctx context.Context, arg1, arg2 float64) (float64, error)
+}
+
+// ExecutorFunc позволяет использовать обычную функцию как Executor
+type ExecutorFunc func(arg1, arg2 float64) (float64, error)
+
+// Execute вызывает f(arg1, arg2)
+func (f ExecutorFunc) Execute(_ context.Context, arg1, arg2 float64) (float64, error) {
+	return f(arg1, arg2)
+}
+
+// Timing моделирует стоимость операции: расходует время, оставшееся до operation_time задачи
+// после выполнения самой операции
+type Timing func(ctx context.Context, remaining time.Duration)
+
+// SimulatedDelay ожидает оставшееся время, не нагружая процессор
+func SimulatedDelay(ctx context.Context, remaining time.Duration) {
+	sleep(ctx, remaining)
+}
+
+// CPUWork нагружает ядро процессора на оставшееся время. Используется для нагрузочного
+// тестирования вместо имитации задержки
+func CPUWork(ctx context.Context, remaining time.Duration) {
+	deadline := time.Now().Add(remaining)
+	x := 1.0
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		for i := 0; i < 1000; i++ {
+			x = math.Sqrt(x + float64(i))
+		}
+	}
+	workSink = x
+}
+
+// workSink не дает компилятору выбросить вычисления CPUWork
+var workSink float64
+
+// NoDelay не добавляет задержку: стоимость операции определяется самим исполнителем
+func NoDelay(context.Context, time.Duration) {}
+
+// Режимы стоимости встроенных операций
+const (
+	WorkModeSleep = "sleep" // Имитация задержкой
+	WorkModeCPU   = "cpu"   // Реальная нагрузка на процессор
+)
+
+// TimingForMode возвращает модель стоимости для режима WorkModeSleep или WorkModeCPU
+func TimingForMode(mode string) (Timing, error) {
+	switch mode {
+	case WorkModeSleep:
+		return SimulatedDelay, nil
+	case WorkModeCPU:
+		return CPUWork, nil
+	default:
+		return nil, fmt.Errorf("неизвестный режим выполнения: %q", mode)
+	}
+}
+
+// registration — зарегистрированный исполнитель операции и модель ее стоимости
+type registration struct {
+	executor Executor
+	timing   Timing
+}
+
+// Executors сопоставляет операциям их исполнителей
+type Executors struct {
+	executors map[models.Operation]registration
+	mutex     sync.RWMutex
+}
+
+// NewExecutors создает пустой набор исполнителей
+func NewExecutors() *Executors {
+	return &Executors{executors: make(map[models.Operation]registration)}
+}
+
+// NewBuiltinExecutors создает набор исполнителей четырех арифметических операций
+// со стоимостью, моделируемой timing
+func NewBuiltinExecutors(timing Timing) *Executors {
+	e := NewExecutors()
+	e.Register(models.OperationAdd, ExecutorFunc(func(a, b float64) (float64, error) { return a + b, nil }), timing)
+	e.Register(models.OperationSubtract, ExecutorFunc(func(a, b float64) (float64, error) { return a - b, nil }), timing)
+	e.Register(models.OperationMultiply, ExecutorFunc(func(a, b float64) (float64, error) { return a * b, nil }), timing)
+	e.Register(models.OperationDivide, ExecutorFunc(func(a, b float64) (float64, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("деление на ноль")
+		}
+		return a / b, nil
+	}), timing)
+	return e
+}
+
+// Register добавляет исполнителя операции или заменяет уже зарегистрированного.
+// Оркестратор создает задачи только операций ADD, SUBTRACT, MULTIPLY и DIVIDE, поэтому
+// исполнители других операций не получат задач. При nil timing стоимость не моделируется
+func (e *Executors) Register(op models.Operation, executor Executor, timing Timing) {
+	if timing == nil {
+		timing = NoDelay
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.executors[op] = registration{executor: executor, timing: timing}
+}
+
+// Operations возвращает операции, для которых зарегистрированы исполнители
+func (e *Executors) Operations() []models.Operation {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	ops := make([]models.Operation, 0, len(e.executors))
+	for op := range e.executors {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Execute выполняет задачу исполнителем ее операции и расходует оставшееся до
+// operation_time время согласно модели стоимости
+func (e *Executors) Execute(ctx context.Context, task *models.Task) (float64, error) {
+	start := time.Now()
+
+	e.mutex.RLock()
+	reg, exists := e.executors[task.Operation]
+	e.mutex.RUnlock()
+
+	// Парсим аргументы и выполняем операцию
+	arg1, err1 := strconv.ParseFloat(task.Arg1, 64)
+	arg2, err2 := strconv.ParseFloat(task.Arg2, 64)
+
+	var result float64
+	var execError error
+	switch {
+	case err1 != nil:
+		execError = fmt.Errorf("некорректный аргумент 1: %s", task.Arg1)
+	case err2 != nil:
+		execError = fmt.Errorf("некорректный аргумент 2: %s", task.Arg2)
+	case !exists:
+		execError = fmt.Errorf("неизвестная операция: %s", task.Operation)
+	default:
+		result, execError = reg.executor.Execute(ctx, arg1, arg2)
+	}
+
+	// Переполнение дает бесконечность, которую оркестратор не примет как результат
+	if execError == nil && (math.IsInf(result, 0) || math.IsNaN(result)) {
+		execError = fmt.Errorf("результат вне допустимого диапазона")
+	}
+	if execError != nil {
+		result = 0
+	}
+
+	// Расходуем оставшееся время операции
+	if exists {
+		remaining := time.Duration(task.OperationTime)*time.Millisecond - time.Since(start)
+		if remaining > 0 {
+			reg.timing(ctx, remaining)
+		}
+	}
+
+	return result, execError
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExecutors_Register(t *testing.T) {
+	executors := NewBuiltinExecutors(NoDelay)
+	executors.Register("POW", ExecutorFunc(func(a, b float64) (float64, error) {
+		return math.Pow(a, b), nil
+	}), SimulatedDelay)
+
+	tests := []struct {
+		name      string
+		task      models.Task
+		want      float64
+		wantError bool
+	}{
+		{"plugin operation", models.Task{Arg1: "2", Arg2: "10", Operation: "POW", OperationTime: 20}, 1024, false},
+		{"builtin without delay", models.Task{Arg1: "2", Arg2: "3", Operation: models.OperationMultiply, OperationTime: 1000}, 6, false},
+		{"unknown operation", models.Task{Arg1: "2", Arg2: "3", Operation: "MOD"}, 0, true},
+		{"overflow", models.Task{Arg1: "10", Arg2: "400", Operation: "POW"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			got, err := executors.Execute(context.Background(), &tt.task)
+			if (err != nil) != tt.wantError || got != tt.want {
+				t.Errorf("Execute() = %v, %v; want %v, error %v", got, err, tt.want, tt.wantError)
+			}
+
+			// Каждая операция расходует время по своей модели стоимости
+			elapsed := time.Since(start)
+			if tt.task.Operation == "POW" && elapsed < time.Duration(tt.task.OperationTime)*time.Millisecond {
+				t.Errorf("plugin took %v, want at least %dms", elapsed, tt.task.OperationTime)
+			}
+			if tt.task.Operation == models.OperationMultiply && elapsed > 500*time.Millisecond {
+				t.Errorf("builtin with NoDelay took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestCPUWork(t *testing.T) {
+	start := time.Now()
+	CPUWork(context.Background(), 30*time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("CPUWork took %v, want at least 30ms", elapsed)
+	}
+
+	if _, err := TimingForMode("gpu"); err == nil {
+		t.Error("TimingForMode should reject unknown modes")
+	}
+}
+
+// TestProcessExecutor запускает в качестве внешнего исполнителя сам тестовый бинарник
+// (см. TestHelperExecutor)
+func TestProcessExecutor(t *testing.T) {
+	executors, err := ParseProcessExecutors(os.Args[0] + " -test.run=^TestHelperExecutor$")
+	if err == nil {
+		t.Fatal("spec without operation should be rejected")
+	}
+
+	if _, err := ParseProcessExecutors("POW=" + os.Args[0]); err == nil {
+		t.Fatal("operation the orchestrator never issues should be rejected")
+	}
+
+	executors, err = ParseProcessExecutors("divide=" + os.Args[0] + " -test.run=^TestHelperExecutor$; ")
+	if err != nil || len(executors) != 1 || executors[0].Operation != models.OperationDivide {
+		t.Fatalf("ParseProcessExecutors() = %+v, %v", executors, err)
+	}
+	executor := executors[0]
+	t.Setenv("AGENT_HELPER_EXECUTOR", "1")
+
+	if got, err := executor.Execute(context.Background(), 7, 2); err != nil || got != 3.5 {
+		t.Errorf("Execute(7, 2) = %v, %v; want 3.5", got, err)
+	}
+	if _, err := executor.Execute(context.Background(), 1, 0); err == nil || err.Error() != "деление на ноль" {
+		t.Errorf("Execute(1, 0) error = %v, want деление на ноль", err)
+	}
+}
+
+// TestHelperExecutor — внешний исполнитель деления для TestProcessExecutor
+func TestHelperExecutor(t *testing.T) {
+	if os.Getenv("AGENT_HELPER_EXECUTOR") != "1" {
+		return
+	}
+
+	var req ProcessRequest
+	json.NewDecoder(os.Stdin).Decode(&req)
+	resp := ProcessResponse{}
+	if req.Arg2 == 0 {
+		resp.Error = "деление на ноль"
+	} else {
+		resp.Result = req.Arg1 / req.Arg2
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
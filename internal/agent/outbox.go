@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutboxEntry — результат задачи, ожидающий доставки оркестратору
+type OutboxEntry struct {
+	TaskID      int       `json:"task_id"`
+	Result      float64   `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	TraceParent string    `json:"traceparent,omitempty"` // Контекст спана выполнения задачи
+	Attempts    int       `json:"attempts"`              // Количество неудачных попыток доставки
+	NextAttempt time.Time `json:"next_attempt"`          // Время следующей попытки
+}
+
+// Outbox хранит недоставленные результаты задач. Если задан файл, содержимое сохраняется
+// в нем после каждого изменения, и результаты переживают перезапуск агента
+type Outbox struct {
+	path    string
+	entries map[int]OutboxEntry
+	mutex   sync.Mutex
+}
+
+// NewOutbox создает очередь результатов и загружает сохраненные в файле path.
+// При пустом path результаты хранятся только в памяти
+func NewOutbox(path string) (*Outbox, error) {
+	o := &Outbox{path: path, entries: make(map[int]OutboxEntry)}
+	if path == "" {
+		return o, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения outbox: %w", err)
+	}
+
+	var entries []OutboxEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("поврежденный файл outbox %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		o.entries[entry.TaskID] = entry
+	}
+	return o, nil
+}
+
+// Put добавляет или обновляет результат задачи
+func (o *Outbox) Put(entry OutboxEntry) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.entries[entry.TaskID] = entry
+	return o.save()
+}
+
+// Remove удаляет доставленный или отклоненный результат
+func (o *Outbox) Remove(taskID int) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if _, exists := o.entries[taskID]; !exists {
+		return nil
+	}
+	delete(o.entries, taskID)
+	return o.save()
+}
+
+// Due возвращает результаты, время повторной попытки которых наступило к now, в порядке
+// этого времени. Нулевое now означает все результаты
+func (o *Outbox) Due(now time.Time) []OutboxEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	due := make([]OutboxEntry, 0)
+	for _, entry := range o.entries {
+		if now.IsZero() || !entry.NextAttempt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	return due
+}
+
+// Len возвращает количество недоставленных результатов
+func (o *Outbox) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.entries)
+}
+
+// save атомарно записывает содержимое в файл. Вызывается под блокировкой мьютекса
+func (o *Outbox) save() error {
+	if o.path == "" {
+		return nil
+	}
+
+	entries := make([]OutboxEntry, 0, len(o.entries))
+	for _, entry := range o.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].TaskID < entries[j].TaskID
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return fmt.Errorf("ошибка сохранения outbox: %w", err)
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("ошибка сохранения outbox: %w", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("ошибка сохранения outbox: %w", err)
+	}
+	return nil
+}
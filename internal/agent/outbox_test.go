@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{5, 500 * time.Millisecond, time.Second},   // 1.6s ограничено сверху
+		{100, 500 * time.Millisecond, time.Second}, // без переполнения
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := b.Delay(tt.attempt); d < tt.min || d > tt.max {
+				t.Errorf("Delay(%d) = %v, want in [%v, %v]", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(2, 50*time.Millisecond)
+
+	b.Failure()
+	if !b.Allow() {
+		t.Fatal("breaker should stay closed below threshold")
+	}
+	b.Failure()
+	if b.Allow() || b.State() != breakerOpen {
+		t.Fatalf("breaker should open after threshold, state = %s", b.State())
+	}
+
+	// После паузы пропускается один пробный запрос
+	time.Sleep(60 * time.Millisecond)
+	if !b.Allow() || b.Allow() {
+		t.Fatal("breaker should allow exactly one probe after cooldown")
+	}
+	b.Failure()
+	if b.State() != breakerOpen {
+		t.Fatalf("failed probe should reopen breaker, state = %s", b.State())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	b.Allow()
+	b.Success()
+	if b.State() != breakerClosed || !b.Allow() {
+		t.Fatalf("successful probe should close breaker, state = %s", b.State())
+	}
+}
+
+func TestOutbox_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "outbox.json")
+
+	outbox, err := NewOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox.Put(OutboxEntry{TaskID: 1, Result: 4})
+	outbox.Put(OutboxEntry{TaskID: 2, Error: "деление на ноль", NextAttempt: time.Now().Add(time.Hour)})
+	outbox.Remove(1)
+	outbox.Put(OutboxEntry{TaskID: 3, Result: 9})
+
+	// Результаты переживают перезапуск агента
+	reopened, err := NewOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", reopened.Len())
+	}
+	due := reopened.Due(time.Now())
+	if len(due) != 1 || due[0].TaskID != 3 || due[0].Result != 9 {
+		t.Errorf("Due() = %+v, want only task 3", due)
+	}
+	if all := reopened.Due(time.Time{}); len(all) != 2 {
+		t.Errorf("Due(zero) = %d entries, want 2", len(all))
+	}
+}
+
+// TestDeliveryRetry проверяет, что результат, который не удалось отправить, остается в outbox
+// и доставляется повторно, когда оркестратор снова доступен
+func TestDeliveryRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutex sync.Mutex
+	polls, attempts := 0, 0
+	var delivered []models.TaskResultRequest
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if r.Method == http.MethodGet {
+			polls++
+			if polls > 1 {
+				http.Error(w, "Нет доступных задач", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(models.TaskResponse{Task: &models.Task{
+				ID: 7, Arg1: "6", Arg2: "7", Operation: models.OperationMultiply, OperationTime: 1,
+			}})
+			return
+		}
+
+		// Первые две попытки отправки результата завершаются ошибкой сервера
+		attempts++
+		if attempts <= 2 {
+			http.Error(w, "Временная ошибка", http.StatusServiceUnavailable)
+			return
+		}
+		var req models.TaskResultRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		delivered = append(delivered, req)
+		cancel()
+	}))
+	defer orchestrator.Close()
+
+	a := NewAgent(orchestrator.URL, 1)
+	a.SetRetryPolicy(Backoff{Base: 10 * time.Millisecond, Max: 20 * time.Millisecond}, 10, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		a.Start(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("результат не был доставлен")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(delivered) != 1 || delivered[0].ID != 7 || delivered[0].Result != 42 {
+		t.Errorf("delivered = %+v, want one result 42 for task 7", delivered)
+	}
+	if a.outbox.Len() != 0 {
+		t.Errorf("outbox has %d pending results, want 0", a.outbox.Len())
+	}
+}
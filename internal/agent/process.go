@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultProcessTimeout ограничивает время работы внешнего исполнителя
+const defaultProcessTimeout = 10 * time.Second
+
+// ProcessRequest — запрос, который внешний исполнитель получает в stdin
+type ProcessRequest struct {
+	Operation models.Operation `json:"operation"`
+	Arg1      float64          `json:"arg1"`
+	Arg2      float64          `json:"arg2"`
+}
+
+// ProcessResponse — ответ, который внешний исполнитель пишет в stdout
+type ProcessResponse struct {
+	Result float64 `json:"result"`
+	Error  string  `json:"error,omitempty"` // Ошибка вычисления, например деление на ноль
+}
+
+// ProcessExecutor выполняет операцию внешней программой: для каждой задачи запускает
+// Command, передает ProcessRequest в stdin и читает ProcessResponse из stdout
+type ProcessExecutor struct {
+	Operation models.Operation
+	Command   []string      // Программа и ее аргументы
+	Timeout   time.Duration // Предельное время работы (0 — defaultProcessTimeout)
+}
+
+// Execute запускает внешнюю программу и возвращает вычисленный ею результат
+func (p *ProcessExecutor) Execute(ctx context.Context, arg1, arg2 float64) (float64, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultProcessTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(ProcessRequest{Operation: p.Operation, Arg1: arg1, Arg2: arg2})
+	if err != nil {
+		return 0, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return 0, fmt.Errorf("исполнитель %s: %w: %s", p.Operation, err, msg)
+		}
+		return 0, fmt.Errorf("исполнитель %s: %w", p.Operation, err)
+	}
+
+	var resp ProcessResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return 0, fmt.Errorf("исполнитель %s: некорректный ответ: %w", p.Operation, err)
+	}
+	if resp.Error != "" {
+		return 0, errors.New(resp.Error)
+	}
+	return resp.Result, nil
+}
+
+// ParseProcessExecutors разбирает описание внешних исполнителей вида
+// "OP=команда аргументы;OP2=команда2". Оркестратор создает задачи только встроенных
+// операций, поэтому внешний исполнитель может лишь заменить одну из них
+func ParseProcessExecutors(spec string) ([]*ProcessExecutor, error) {
+	executors := make([]*ProcessExecutor, 0)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		op, command, found := strings.Cut(item, "=")
+		operation := models.Operation(strings.ToUpper(strings.TrimSpace(op)))
+		fields := strings.Fields(command)
+		if !found || len(fields) == 0 {
+			return nil, fmt.Errorf("некорректное описание исполнителя: %q", item)
+		}
+		if !builtinOperation(operation) {
+			return nil, fmt.Errorf("неизвестная операция %q: поддерживаются ADD, SUBTRACT, MULTIPLY и DIVIDE", op)
+		}
+		executors = append(executors, &ProcessExecutor{
+			Operation: operation,
+			Command:   fields,
+		})
+	}
+	return executors, nil
+}
+
+// builtinOperation сообщает, является ли op одной из операций, задачи которых создает оркестратор
+func builtinOperation(op models.Operation) bool {
+	switch op {
+	case models.OperationAdd, models.OperationSubtract, models.OperationMultiply, models.OperationDivide:
+		return true
+	default:
+		return false
+	}
+}
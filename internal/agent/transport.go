@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Transport связывает агента с оркестратором: по HTTP или напрямую в одном процессе
+type Transport interface {
+	// GetTask выдает задачу агенту agentID и сообщает, сколько готовых задач осталось в очереди
+	// (-1 — неизвестно). Если задач нет, возвращает ErrNoTasks
+	GetTask(ctx context.Context, agentID string) (*models.Task, int, error)
+	// SendResult передает результат задачи. Окончательный отказ оборачивает ErrResultRejected,
+	// остальные ошибки считаются временными
+	SendResult(ctx context.Context, agentID string, result models.TaskResultRequest) error
+	// Ping проверяет доступность оркестратора
+	Ping(ctx context.Context) error
+}
+
+// SetTransport задает транспорт агента вместо HTTP-клиента оркестратора
+func (a *Agent) SetTransport(transport Transport) {
+	a.transport = transport
+}
+
+// httpTransport обращается к внутреннему API оркестратора по HTTP
+type httpTransport struct {
+	agent *Agent
+}
+
+// newRequest создает запрос к внутреннему API оркестратора с заголовками аутентификации
+// и идентификатора агента
+func (t *httpTransport) newRequest(ctx context.Context, method, agentID string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/internal/task", t.agent.orchestratorURL), body)
+	if err != nil {
+		return nil, err
+	}
+	if t.agent.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.agent.authToken)
+	}
+	req.Header.Set(models.AgentIDHeader, agentID)
+	return req, nil
+}
+
+// closeBody закрывает тело ответа, записывая ошибку в лог
+func (t *httpTransport) closeBody(body io.ReadCloser) {
+	if err := body.Close(); err != nil {
+		t.agent.logger.Warn("Response body close error", zap.Error(err))
+	}
+}
+
+// GetTask запрашивает задачу у оркестратора
+func (t *httpTransport) GetTask(ctx context.Context, agentID string) (*models.Task, int, error) {
+	req, err := t.newRequest(ctx, http.MethodGet, agentID, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	resp, err := t.agent.client.Do(req)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer t.closeBody(resp.Body)
+
+	backlog := -1
+	if depth, err := strconv.Atoi(resp.Header.Get(models.QueueDepthHeader)); err == nil {
+		backlog = depth
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, backlog, ErrNoTasks
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, backlog, fmt.Errorf("оркестратор отклонил учетные данные агента: %d", resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, backlog, fmt.Errorf("неожиданный код ответа: %d", resp.StatusCode)
+	}
+
+	var taskResp models.TaskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&taskResp); err != nil {
+		return nil, backlog, err
+	}
+
+	return taskResp.Task, backlog, nil
+}
+
+// SendResult отправляет результат задачи оркестратору, передавая контекст трассировки из ctx
+func (t *httpTransport) SendResult(ctx context.Context, agentID string, result models.TaskResultRequest) error {
+	reqData, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	req, err := t.newRequest(ctx, http.MethodPost, agentID, bytes.NewBuffer(reqData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := t.agent.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer t.closeBody(resp.Body)
+
+	// Результат неизвестной или уже завершенной задачи повторно отправлять бессмысленно,
+	// ошибки сервера и ограничение частоты запросов — временные
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: код ответа %d", ErrResultRejected, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("неожиданный код ответа: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Ping проверяет доступность оркестратора по его эндпоинту /healthz
+func (t *httpTransport) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.agent.orchestratorURL+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.agent.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("оркестратор недоступен: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("оркестратор недоступен: код ответа %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// TaskSource — хранилище задач оркестратора, из которого агент берет задачи напрямую.
+// Ему соответствует *orchestrator.Storage
+type TaskSource interface {
+	GetReadyTaskForAgent(agentID string) (*models.Task, error)
+	AcceptTaskResult(agentID string, id int, result float64, errorMsg string) error
+	ReadyQueueDepth() int
+}
+
+// LocalTransport передает задачи и результаты через хранилище оркестратора в том же процессе,
+// без HTTP. Выдача задач и проверка результатов выполняются тем же кодом хранилища
+type LocalTransport struct {
+	source TaskSource
+}
+
+// NewLocalTransport создает транспорт агента, встроенного в процесс оркестратора
+func NewLocalTransport(source TaskSource) *LocalTransport {
+	return &LocalTransport{source: source}
+}
+
+// GetTask выдает агенту готовую задачу из хранилища
+func (t *LocalTransport) GetTask(_ context.Context, agentID string) (*models.Task, int, error) {
+	task, err := t.source.GetReadyTaskForAgent(agentID)
+	backlog := t.source.ReadyQueueDepth()
+	if err != nil {
+		return nil, backlog, ErrNoTasks
+	}
+	return task, backlog, nil
+}
+
+// SendResult передает результат в хранилище. Хранилище отклоняет результат окончательно
+// (неизвестная задача, повтор, завершенное выражение), поэтому любая ошибка — отказ
+func (t *LocalTransport) SendResult(_ context.Context, agentID string, result models.TaskResultRequest) error {
+	if err := t.source.AcceptTaskResult(agentID, result.ID, result.Result, result.Error); err != nil {
+		return fmt.Errorf("%w: %v", ErrResultRejected, err)
+	}
+	return nil
+}
+
+// Ping всегда успешен: хранилище находится в том же процессе
+func (t *LocalTransport) Ping(context.Context) error {
+	return nil
+}
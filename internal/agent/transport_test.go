@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+	"testing"
+	"time"
+)
+
+func TestLocalTransport(t *testing.T) {
+	storage := orchestrator.NewStorage()
+
+	a := NewAgent("", 2)
+	a.SetID("embedded-1")
+	a.SetTransport(NewLocalTransport(storage))
+	a.SetExecutors(NewBuiltinExecutors(NoDelay))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	const expr = "(2+3)*4-6/3"
+	id, _ := storage.AddExpression(expr)
+	tasks, err := orchestrator.NewParser(orchestrator.OperationTimes{}).ParseExpression(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.AddTasks(id, tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		expr, err := storage.GetExpression(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expr.Status.IsTerminal() {
+			if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != "18" {
+				t.Fatalf("expression = %+v", expr)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expression was not computed by embedded agent")
+}
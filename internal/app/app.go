@@ -0,0 +1,285 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/config"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/agent"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// LoadFunc загружает конфигурацию API-сервера из аргументов командной строки (без имени программы)
+type LoadFunc func(args []string) (*config.OrchestratorConfig, error)
+
+// Run запускает API-сервер: асинхронное вычисление выражений агентами, синхронное вычисление,
+// веб-интерфейс, внутренний и административный API. Работает до SIGINT или SIGTERM,
+// по SIGHUP перечитывает конфигурацию функцией load
+func Run(load LoadFunc, args []string) {
+	// Загружаем конфигурацию из файла, окружения и флагов командной строки
+	cfg, err := load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		zapLogger, _ := zap.NewProduction()
+		zapLogger.Fatal("Configuration error", zap.Error(err))
+	}
+	cfg.PrintAndExit()
+
+	// Инициализируем логгер с заданными форматом и изменяемым во время работы уровнем
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logLevel := zap.NewAtomicLevelAt(level)
+	logger, err := logging.New(logging.Options{AtomicLevel: logLevel, Format: cfg.LogFormat})
+	if err != nil {
+		zapLogger, _ := zap.NewProduction()
+		zapLogger.Fatal("Logger initialization error", zap.Error(err))
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+
+	if cfg.EnvFileLoaded {
+		logger.Info(".env file successfully loaded")
+	} else {
+		logger.Info(".env file not found")
+	}
+
+	// Получаем времена выполнения операций
+	opTimes := operationTimes(cfg)
+
+	// Создаем компоненты сервера
+	storage := orchestrator.NewStorage()
+	parser := orchestrator.NewParser(opTimes)
+	server := orchestrator.NewServer(storage, parser)
+	server.SetLogger(logger)
+	server.SetLogLevel(logLevel)
+	server.SetReadiness(cfg.ReadyMinAgents, cfg.AgentActivityWindow)
+
+	// Включаем метрики, кеш результатов одинаковых задач и режим аудита
+	server.SetMetrics(orchestrator.NewMetrics(metrics.NewRegistry(), storage))
+	storage.EnableResultCache(cfg.ResultCacheEnabled)
+	storage.EnableAudit(cfg.AuditMode)
+	storage.SetAuditTimeout(cfg.AuditTimeout)
+	storage.SetLeaseTimeout(cfg.LeaseTimeout)
+	if cfg.AuditMode {
+		logger.Info("Audit mode enabled, every task runs on two agents")
+		if cfg.EmbeddedAgents == 1 {
+			logger.Warn("Audit mode needs a second agent, without external agents single results are accepted after the audit timeout",
+				zap.Duration("audit_timeout", cfg.AuditTimeout))
+		}
+	}
+
+	// Настраиваем трассировку: OTLP/HTTP-коллектор или локальный файл
+	exporter, err := tracing.NewExporter(cfg.Tracing.OTLPEndpoint, cfg.Tracing.File)
+	if err != nil {
+		logger.Fatal("Tracing setup error", zap.Error(err))
+	}
+	var tracer *tracing.Tracer
+	if exporter != nil {
+		tracer = tracing.NewTracer(cfg.Tracing.ServiceName, exporter)
+		defer tracer.Shutdown()
+		server.SetTracer(tracer)
+	}
+
+	// Настраиваем webhook-уведомления
+	if cfg.WebhookSecret == "" {
+		logger.Warn("WEBHOOK_SECRET is not set, webhooks are disabled and callback_url is rejected")
+	}
+	notifier := orchestrator.NewNotifier(storage, cfg.WebhookSecret)
+	if err := notifier.SetAllowedHosts(cfg.WebhookAllowedHosts); err != nil {
+		logger.Fatal("Webhook configuration error", zap.Error(err))
+	}
+	server.SetNotifier(notifier)
+
+	// Настраиваем аутентификацию пользователей, агентов и администратора
+	if cfg.JWTSecret == "" && cfg.AgentSecret == "" && cfg.AdminSecret == "" {
+		logger.Warn("JWT_SECRET, AGENT_SECRET and ADMIN_SECRET are not set, authentication is disabled")
+	} else {
+		authenticator := auth.NewAuthenticator(cfg.JWTSecret, cfg.JWTTTL, cfg.AgentSecret, cfg.AdminSecret)
+		authenticator.DefaultQuota = auth.Quota{
+			ExpressionsPerMinute: cfg.APIKeyExpressionsPerMinute,
+			TasksPerDay:          cfg.APIKeyTasksPerDay,
+			MaxExpressionLength:  cfg.APIKeyMaxExpressionLength,
+		}
+		server.SetAuthenticator(authenticator)
+	}
+	if cfg.AdminSecret == "" {
+		logger.Warn("ADMIN_SECRET is not set, admin API is disabled")
+	}
+	if cfg.JWTSecret != "" && cfg.AgentSecret == "" {
+		logger.Warn("JWT_SECRET is set without AGENT_SECRET, external agents are rejected; only embedded agents run tasks")
+	}
+
+	// Настраиваем ограничение частоты запросов по IP-адресу и API-ключу
+	if cfg.RateLimitRPS > 0 {
+		rps, burst := float64(cfg.RateLimitRPS), cfg.RateLimitBurst
+		server.SetRateLimiters(ratelimit.NewLimiter(rps, burst), ratelimit.NewLimiter(rps, burst))
+	}
+
+	// Настраиваем политику хранения завершенных выражений
+	retention := orchestrator.RetentionPolicy{
+		MaxAge:      cfg.RetentionMaxAge,
+		ErrorMaxAge: cfg.RetentionErrorMaxAge,
+		MaxCount:    cfg.RetentionMaxCount,
+	}
+	sweeper := orchestrator.NewSweeper(storage, retention, cfg.RetentionSweepInterval)
+	sweeper.Start()
+	defer sweeper.Stop()
+	server.SetSweeper(sweeper)
+
+	// Включаем повторную обработку отправки выражений с ключом идемпотентности и настраиваем маршруты
+	server.SetIdempotencyStore(middleware.NewIdempotencyStore(cfg.IdempotencyTTL))
+	handler := server.SetupRoutes()
+
+	// Перечитываем конфигурацию по SIGHUP
+	go reloadOnSignal(logger, load, args, parser, logLevel)
+
+	// Запускаем сервер
+	logger.Info("Server started",
+		zap.String("service", cfg.Tracing.ServiceName),
+		zap.String("port", cfg.Port),
+		zap.Int("time_addition_ms", opTimes.Addition),
+		zap.Int("time_subtraction_ms", opTimes.Subtraction),
+		zap.Int("time_multiplication_ms", opTimes.Multiplication),
+		zap.Int("time_division_ms", opTimes.Division),
+	)
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: handler}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// Запускаем встроенных агентов, которые берут задачи из хранилища напрямую
+	agentsCtx, stopAgents := context.WithCancel(context.Background())
+	defer stopAgents()
+	agentsDone := startEmbeddedAgents(agentsCtx, cfg, storage, logger, tracer)
+
+	// Ожидаем сигнала остановки
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serverErr:
+		logger.Fatal("Server error", zap.Error(err))
+	case <-ctx.Done():
+	}
+	stop() // Повторный сигнал завершает процесс немедленно
+
+	// Корректная остановка: прекращаем прием выражений и выдачу задач, ждем результатов
+	// выданных задач, затем останавливаем HTTP-сервер. Хранилище находится в памяти, поэтому
+	// не вернувшиеся задачи теряются. Трассы и логи сбрасываются отложенными вызовами
+	logger.Info("Shutting down", zap.Duration("timeout", cfg.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Встроенные агенты перестают брать задачи и завершают текущие
+	stopAgents()
+	server.Shutdown(shutdownCtx)
+	select {
+	case <-agentsDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Shutdown deadline exceeded, embedded agents abandoned")
+	}
+	// Доставляем уведомления, поставленные в очередь до остановки
+	if err := notifier.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Shutdown deadline exceeded, pending webhooks dropped")
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server shutdown error", zap.Error(err))
+		return
+	}
+	logger.Info("Server stopped")
+}
+
+// startEmbeddedAgents запускает cfg.EmbeddedAgents агентов, связанных с хранилищем без HTTP.
+// Возвращаемый канал закрывается, когда все агенты остановились после отмены ctx
+func startEmbeddedAgents(ctx context.Context, cfg *config.OrchestratorConfig, storage *orchestrator.Storage, logger *zap.Logger, tracer *tracing.Tracer) <-chan struct{} {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 1; i <= cfg.EmbeddedAgents; i++ {
+		a := agent.NewAgent("", cfg.EmbeddedWorkers)
+		a.SetID(fmt.Sprintf("embedded-%d", i))
+		a.SetLogger(logger)
+		a.SetTransport(agent.NewLocalTransport(storage))
+		if tracer != nil {
+			a.SetTracer(tracer)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.Start(ctx)
+		}()
+	}
+	if cfg.EmbeddedAgents > 0 {
+		logger.Info("Embedded agents started",
+			zap.Int("agents", cfg.EmbeddedAgents),
+			zap.Int("workers_per_agent", cfg.EmbeddedWorkers),
+		)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// operationTimes возвращает время выполнения операций из конфигурации
+func operationTimes(cfg *config.OrchestratorConfig) orchestrator.OperationTimes {
+	return orchestrator.OperationTimes{
+		Addition:       cfg.TimeAdditionMS,
+		Subtraction:    cfg.TimeSubtractionMS,
+		Multiplication: cfg.TimeMultiplicationMS,
+		Division:       cfg.TimeDivisionMS,
+	}
+}
+
+// reloadOnSignal перечитывает конфигурацию при получении SIGHUP
+func reloadOnSignal(logger *zap.Logger, load LoadFunc, args []string, parser *orchestrator.Parser, logLevel zap.AtomicLevel) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		reload(logger, load, args, parser, logLevel)
+	}
+}
+
+// reload перечитывает конфигурацию и применяет время выполнения операций и уровень логирования.
+// Остальные параметры вступают в силу только после перезапуска.
+// При ошибке в конфигурации действующие значения сохраняются
+func reload(logger *zap.Logger, load LoadFunc, args []string, parser *orchestrator.Parser, logLevel zap.AtomicLevel) {
+	cfg, err := load(args)
+	if err != nil {
+		logger.Error("Configuration reload failed, keeping current settings", zap.Error(err))
+		return
+	}
+
+	opTimes := operationTimes(cfg)
+	parser.SetOperationTimes(opTimes)
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logLevel.SetLevel(level)
+
+	logger.Info("Configuration reloaded",
+		zap.String("log_level", level.String()),
+		zap.Int("time_addition_ms", opTimes.Addition),
+		zap.Int("time_subtraction_ms", opTimes.Subtraction),
+		zap.Int("time_multiplication_ms", opTimes.Multiplication),
+		zap.Int("time_division_ms", opTimes.Division),
+	)
+}
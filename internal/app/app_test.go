@@ -0,0 +1,74 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mpkelevra23/arithmetic-web-service/config"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+
+	"go.uber.org/zap"
+)
+
+// chdir переходит в каталог на время теста
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// writeFiles записывает .env и файл конфигурации в текущий каталог
+func writeFiles(t *testing.T, envFile, configFile string) {
+	t.Helper()
+	if err := os.WriteFile(".env", []byte(envFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("config.toml", []byte(configFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload_OperationTimes(t *testing.T) {
+	for _, key := range []string{"CONFIG_FILE", "LOG_LEVEL", "TIME_ADDITION_MS", "TIME_SUBTRACTION_MS", "TIME_MULTIPLICATIONS_MS", "TIME_DIVISIONS_MS"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	chdir(t, t.TempDir())
+	args := []string{"--config", filepath.Join(".", "config.toml")}
+
+	writeFiles(t, "TIME_ADDITION_MS=5000\n", "[orchestrator]\ntime_subtraction_ms = 5000\n")
+	cfg, err := config.LoadOrchestratorConfig(args)
+	if err != nil {
+		t.Fatalf("LoadOrchestratorConfig: %v", err)
+	}
+	parser := orchestrator.NewParser(operationTimes(cfg))
+	if got := parser.OperationTimes(); got.Addition != 5000 || got.Subtraction != 5000 {
+		t.Fatalf("initial operation times = %+v", got)
+	}
+
+	// Значения из .env не попадают в окружение и не перекрывают измененные файлы
+	writeFiles(t, "TIME_ADDITION_MS=100\n", "[orchestrator]\ntime_subtraction_ms = 100\nlog_level = \"debug\"\n")
+	logLevel := zap.NewAtomicLevel()
+	reload(zap.NewNop(), config.LoadOrchestratorConfig, args, parser, logLevel)
+
+	if got := parser.OperationTimes(); got.Addition != 100 || got.Subtraction != 100 {
+		t.Errorf("operation times after reload = %+v, want 100 ms from both files", got)
+	}
+	if logLevel.Level() != zap.DebugLevel {
+		t.Errorf("log level after reload = %s, want debug", logLevel.Level())
+	}
+
+	// Некорректная конфигурация не меняет действующие значения
+	writeFiles(t, "TIME_ADDITION_MS=-1\n", "")
+	reload(zap.NewNop(), config.LoadOrchestratorConfig, args, parser, logLevel)
+	if got := parser.OperationTimes(); got.Addition != 100 {
+		t.Errorf("invalid reload changed addition time to %d", got.Addition)
+	}
+}
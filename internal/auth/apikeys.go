@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+)
+
+// apiKeyPrefix — префикс выдаваемых API-ключей
+const apiKeyPrefix = "ak_"
+
+// Quota задает ограничения для API-ключа (0 — без ограничения)
+type Quota struct {
+	ExpressionsPerMinute int `json:"expressions_per_minute"` // Выражений в минуту
+	TasksPerDay          int `json:"tasks_per_day"`          // Задач в сутки (UTC)
+	MaxExpressionLength  int `json:"max_expression_length"`  // Максимальная длина выражения в байтах
+}
+
+// APIKey описывает выданный API-ключ без его секретного значения
+type APIKey struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   int       `json:"owner_id,omitempty"` // Пользователь, от имени которого действует ключ
+	Quota     Quota     `json:"quota"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QuotaError сообщает о превышении квоты API-ключа
+type QuotaError struct {
+	Reason     string        // Какая квота превышена
+	Limit      int           // Значение квоты
+	Remaining  int           // Остаток квоты
+	RetryAfter time.Duration // Когда запрос может быть повторен (0 — повтор не поможет)
+	Reset      time.Duration // Когда квота восстановится полностью
+}
+
+// Error возвращает описание превышенной квоты
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("превышена квота: %s (лимит %d)", e.Reason, e.Limit)
+}
+
+// apiKeyRecord хранит ключ вместе со счетчиками использования
+type apiKeyRecord struct {
+	key         APIKey
+	expressions *ratelimit.TokenBucket // Выражения в минуту
+	tasksUsed   int                    // Задач за текущие сутки
+	day         time.Time              // Начало текущих суток (UTC)
+}
+
+// APIKeyStore выдает API-ключи и учитывает расход квот
+type APIKeyStore struct {
+	byHash    map[[sha256.Size]byte]*apiKeyRecord // Ключи по хешу секрета
+	byID      map[int]*apiKeyRecord               // Ключи по ID
+	idCounter int                                 // Счетчик для ID ключей
+	mutex     sync.Mutex                          // Мьютекс для защиты данных
+	now       func() time.Time
+}
+
+// NewAPIKeyStore создает пустое хранилище API-ключей
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		byHash: make(map[[sha256.Size]byte]*apiKeyRecord),
+		byID:   make(map[int]*apiKeyRecord),
+		now:    time.Now,
+	}
+}
+
+// Issue выпускает новый ключ и возвращает его описание и секретное значение.
+// Секрет хранится только в виде хеша и не может быть получен повторно
+func (s *APIKeyStore) Issue(name string, ownerID int, quota Quota) (APIKey, string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return APIKey{}, "", err
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(raw)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.idCounter++
+	key := APIKey{
+		ID:        s.idCounter,
+		Name:      name,
+		OwnerID:   ownerID,
+		Quota:     quota,
+		CreatedAt: now.UTC(),
+	}
+
+	record := &apiKeyRecord{key: key, day: startOfDay(now)}
+	if quota.ExpressionsPerMinute > 0 {
+		record.expressions = ratelimit.NewTokenBucket(float64(quota.ExpressionsPerMinute)/60, quota.ExpressionsPerMinute, now)
+	}
+
+	s.byHash[sha256.Sum256([]byte(secret))] = record
+	s.byID[key.ID] = record
+
+	return key, secret, nil
+}
+
+// Lookup находит ключ по секретному значению
+func (s *APIKeyStore) Lookup(secret string) (APIKey, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.byHash[sha256.Sum256([]byte(secret))]
+	if !exists {
+		return APIKey{}, false
+	}
+	return record.key, true
+}
+
+// List возвращает все выданные ключи, упорядоченные по ID
+func (s *APIKeyStore) List() []APIKey {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]APIKey, 0, len(s.byID))
+	for _, record := range s.byID {
+		keys = append(keys, record.key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// Charge списывает с квоты ключа одно выражение длиной exprLength, состоящее из tasks задач.
+// При превышении любой из квот ничего не списывается и возвращается *QuotaError
+func (s *APIKeyStore) Charge(keyID, exprLength, tasks int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.byID[keyID]
+	if !exists {
+		return fmt.Errorf("API-ключ %d не найден", keyID)
+	}
+	quota := record.key.Quota
+	now := s.now()
+
+	if quota.MaxExpressionLength > 0 && exprLength > quota.MaxExpressionLength {
+		return &QuotaError{Reason: "max_expression_length", Limit: quota.MaxExpressionLength}
+	}
+
+	// Суточный счетчик задач сбрасывается в полночь UTC
+	if day := startOfDay(now); day.After(record.day) {
+		record.day = day
+		record.tasksUsed = 0
+	}
+	if quota.TasksPerDay > 0 && record.tasksUsed+tasks > quota.TasksPerDay {
+		untilReset := record.day.Add(24 * time.Hour).Sub(now)
+		return &QuotaError{
+			Reason:     "tasks_per_day",
+			Limit:      quota.TasksPerDay,
+			Remaining:  quota.TasksPerDay - record.tasksUsed,
+			RetryAfter: untilReset,
+			Reset:      untilReset,
+		}
+	}
+
+	if record.expressions != nil {
+		if result := record.expressions.Take(1, now); !result.Allowed {
+			return &QuotaError{
+				Reason:     "expressions_per_minute",
+				Limit:      result.Limit,
+				Remaining:  result.Remaining,
+				RetryAfter: result.RetryAfter,
+				Reset:      result.Reset,
+			}
+		}
+	}
+
+	record.tasksUsed += tasks
+	return nil
+}
+
+// startOfDay возвращает начало суток (UTC) для момента t
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// apiKeyContextKey — ключ контекста для API-ключа
+type apiKeyContextKey struct{}
+
+// WithAPIKey возвращает контекст с API-ключом, которым аутентифицирован запрос
+func WithAPIKey(ctx context.Context, key APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext возвращает API-ключ, которым аутентифицирован запрос
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(APIKey)
+	return key, ok
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Authenticator объединяет компоненты аутентификации пользователей и сервисов
+type Authenticator struct {
+	Users        *UserStore   // Учетные записи пользователей
+	Tokens       *TokenIssuer // Выпуск и проверка JWT
+	AgentSecret  string       // Общий секрет агентов для внутреннего API
+	AdminSecret  string       // Секрет для административного API
+	APIKeys      *APIKeyStore // Выданные API-ключи
+	DefaultQuota Quota        // Квоты новых API-ключей по умолчанию
+}
+
+// NewAuthenticator создает аутентификатор с новым хранилищем пользователей.
+// Если jwtSecret пуст, аутентификация пользователей отключена
+func NewAuthenticator(jwtSecret string, tokenTTL time.Duration, agentSecret, adminSecret string) *Authenticator {
+	a := &Authenticator{
+		Users:       NewUserStore(),
+		APIKeys:     NewAPIKeyStore(),
+		AgentSecret: agentSecret,
+		AdminSecret: adminSecret,
+	}
+	if jwtSecret != "" {
+		a.Tokens = NewTokenIssuer(jwtSecret, tokenTTL)
+	}
+	return a
+}
+
+// contextKey — тип ключей контекста пакета auth
+type contextKey struct{}
+
+// WithUser возвращает контекст с аутентифицированным пользователем
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext возвращает аутентифицированного пользователя из контекста
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// TestPBKDF2SHA256 проверяет реализацию PBKDF2 на эталонных значениях (RFC 7914, раздел 11)
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		iterations int
+		want       string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2SHA256([]byte("password"), []byte("salt"), tt.iterations, 32))
+		if got != tt.want {
+			t.Errorf("pbkdf2SHA256(c=%d) = %s, want %s", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+// TestUserStore проверяет регистрацию и вход пользователей
+func TestUserStore(t *testing.T) {
+	store := NewUserStore()
+
+	user, err := store.Register("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if _, err := store.Register("alice", "another password"); err != ErrUserExists {
+		t.Errorf("Register() error = %v, want %v", err, ErrUserExists)
+	}
+	if _, err := store.Register("bob", "short"); err != ErrWeakPassword {
+		t.Errorf("Register() error = %v, want %v", err, ErrWeakPassword)
+	}
+
+	got, err := store.Authenticate("alice", "correct horse")
+	if err != nil || got != user {
+		t.Errorf("Authenticate() = %+v, %v, want %+v", got, err, user)
+	}
+	if _, err := store.Authenticate("alice", "wrong password"); err != ErrInvalidCredentials {
+		t.Errorf("Authenticate() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+// TestTokenIssuer проверяет выпуск и проверку JWT
+func TestTokenIssuer(t *testing.T) {
+	issuer := NewTokenIssuer("secret", time.Hour)
+	user := User{ID: 7, Login: "alice"}
+
+	token, _, err := issuer.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	claims, err := issuer.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, _ := claims.User(); got != user {
+		t.Errorf("User() = %+v, want %+v", got, user)
+	}
+
+	// Токен, подписанный другим ключом, отклоняется
+	if _, err := NewTokenIssuer("other", time.Hour).Parse(token); err != ErrInvalidToken {
+		t.Errorf("Parse() with wrong key error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// Просроченный токен отклоняется
+	issuer.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := issuer.Parse(token); err != ErrInvalidToken {
+		t.Errorf("Parse() expired error = %v, want %v", err, ErrInvalidToken)
+	}
+}
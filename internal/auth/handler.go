@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+)
+
+// CredentialsRequest представляет запрос на регистрацию или вход
+type CredentialsRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// RegisterResponse представляет ответ на успешную регистрацию
+type RegisterResponse struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+}
+
+// LoginResponse представляет ответ с выпущенным токеном
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RegisterHandler обрабатывает POST-запросы на регистрацию пользователя
+func RegisterHandler(a *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeCredentials(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := a.Users.Register(req.Login, req.Password)
+		switch {
+		case stderrors.Is(err, ErrUserExists):
+			errors.WriteErrorResponse(w, http.StatusConflict, errors.ErrUserExists)
+			return
+		case stderrors.Is(err, ErrInvalidLogin), stderrors.Is(err, ErrWeakPassword):
+			errors.WriteErrorResponse(w, http.StatusUnprocessableEntity, errors.ErrInvalidCredentialsFormat)
+			return
+		case err != nil:
+			errors.WriteErrorResponse(w, http.StatusInternalServerError, errors.ErrInternalServer)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(RegisterResponse{ID: user.ID, Login: user.Login})
+	}
+}
+
+// LoginHandler обрабатывает POST-запросы на вход и выдает JWT
+func LoginHandler(a *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeCredentials(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := a.Users.Authenticate(req.Login, req.Password)
+		if err != nil {
+			errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrInvalidCredentials)
+			return
+		}
+
+		token, expiresAt, err := a.Tokens.Issue(user)
+		if err != nil {
+			errors.WriteErrorResponse(w, http.StatusInternalServerError, errors.ErrInternalServer)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LoginResponse{Token: token, ExpiresAt: expiresAt.UTC()})
+	}
+}
+
+// decodeCredentials проверяет метод и декодирует тело запроса с учетными данными
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (CredentialsRequest, bool) {
+	var req CredentialsRequest
+
+	if r.Method != http.MethodPost {
+		errors.WriteErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrUnsupportedMethod)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.WriteErrorResponse(w, http.StatusBadRequest, errors.ErrMalformedJSON)
+		return req, false
+	}
+
+	return req, true
+}
+
+// IssueAPIKeyRequest представляет запрос на выпуск API-ключа
+type IssueAPIKeyRequest struct {
+	Name    string `json:"name"`
+	OwnerID int    `json:"owner_id,omitempty"`
+	Quota   *Quota `json:"quota,omitempty"` // Квоты ключа (по умолчанию — Authenticator.DefaultQuota)
+}
+
+// IssueAPIKeyResponse представляет выпущенный ключ вместе с его секретным значением
+type IssueAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeysResponse представляет список выданных ключей
+type APIKeysResponse struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// APIKeysHandler обрабатывает выпуск (POST) и просмотр (GET) API-ключей.
+// Квоты из запроса заменяют квоты по умолчанию целиком
+func APIKeysHandler(a *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIKeysResponse{Keys: a.APIKeys.List()})
+
+		case http.MethodPost:
+			var req IssueAPIKeyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				errors.WriteErrorResponse(w, http.StatusBadRequest, errors.ErrMalformedJSON)
+				return
+			}
+
+			// При включенных учетных записях ключ должен действовать от имени пользователя,
+			// иначе он получит доступ ко всем выражениям
+			if a.Tokens != nil && !a.Users.Exists(req.OwnerID) {
+				errors.WriteErrorResponse(w, http.StatusUnprocessableEntity, errors.ErrUnknownKeyOwner)
+				return
+			}
+
+			quota := a.DefaultQuota
+			if req.Quota != nil {
+				quota = *req.Quota
+			}
+
+			key, secret, err := a.APIKeys.Issue(req.Name, req.OwnerID, quota)
+			if err != nil {
+				errors.WriteErrorResponse(w, http.StatusInternalServerError, errors.ErrInternalServer)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(IssueAPIKeyResponse{APIKey: key, Key: secret})
+
+		default:
+			errors.WriteErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrUnsupportedMethod)
+		}
+	}
+}
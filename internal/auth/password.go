@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+const (
+	// passwordIterations — количество итераций PBKDF2 при хешировании паролей
+	passwordIterations = 100_000
+	// passwordKeyLength — длина производного ключа в байтах
+	passwordKeyLength = 32
+	// passwordSaltLength — длина соли в байтах
+	passwordSaltLength = 16
+)
+
+// hashPassword вычисляет хеш пароля с новой случайной солью
+func hashPassword(password string) (hash, salt []byte, err error) {
+	salt = make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	return pbkdf2SHA256([]byte(password), salt, passwordIterations, passwordKeyLength), salt, nil
+}
+
+// checkPassword сравнивает пароль с сохраненным хешем за постоянное время
+func checkPassword(password string, hash, salt []byte) bool {
+	candidate := pbkdf2SHA256([]byte(password), salt, passwordIterations, len(hash))
+	return subtle.ConstantTimeCompare(candidate, hash) == 1
+}
+
+// pbkdf2SHA256 реализует PBKDF2 (RFC 8018) с HMAC-SHA256
+func pbkdf2SHA256(password, salt []byte, iterations, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLength := prf.Size()
+	blocks := (keyLength + hashLength - 1) / hashLength
+
+	key := make([]byte, 0, blocks*hashLength)
+	buf := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:keyLength]
+}
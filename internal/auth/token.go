@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken возвращается для поддельных, поврежденных или просроченных токенов
+var ErrInvalidToken = errors.New("недействительный токен")
+
+// jwtHeader — заголовок JWT, подписанного HMAC-SHA256
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims представляет полезную нагрузку JWT
+type Claims struct {
+	Subject   string `json:"sub"`   // ID пользователя
+	Login     string `json:"login"` // Логин пользователя
+	IssuedAt  int64  `json:"iat"`   // Время выпуска (Unix)
+	ExpiresAt int64  `json:"exp"`   // Время истечения (Unix)
+}
+
+// User возвращает пользователя, которому выдан токен
+func (c Claims) User() (User, error) {
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	return User{ID: id, Login: c.Login}, nil
+}
+
+// TokenIssuer выпускает и проверяет JWT (HS256)
+type TokenIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewTokenIssuer создает издателя токенов с ключом secret и временем жизни ttl
+func NewTokenIssuer(secret string, ttl time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secret: []byte(secret),
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// Issue выпускает токен для пользователя
+func (t *TokenIssuer) Issue(user User) (string, time.Time, error) {
+	now := t.now()
+	expiresAt := now.Add(t.ttl)
+	claims := Claims{
+		Subject:   strconv.Itoa(user.ID),
+		Login:     user.Login,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + t.sign(signingInput), expiresAt, nil
+}
+
+// Parse проверяет подпись и срок действия токена и возвращает его полезную нагрузку
+func (t *TokenIssuer) Parse(token string) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return claims, ErrInvalidToken
+	}
+
+	expected := t.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return claims, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+
+	if t.now().Unix() >= claims.ExpiresAt {
+		return claims, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// sign вычисляет подпись HMAC-SHA256 в кодировке base64url
+func (t *TokenIssuer) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
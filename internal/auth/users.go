@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Ошибки управления пользователями
+var (
+	ErrUserExists         = errors.New("пользователь уже существует")
+	ErrInvalidCredentials = errors.New("неверный логин или пароль")
+	ErrInvalidLogin       = errors.New("логин не может быть пустым")
+	ErrWeakPassword       = errors.New("пароль должен содержать не менее 8 символов")
+)
+
+// minPasswordLength — минимальная длина пароля
+const minPasswordLength = 8
+
+// User представляет учетную запись пользователя
+type User struct {
+	ID    int
+	Login string
+}
+
+// userRecord хранит учетную запись вместе с хешем пароля
+type userRecord struct {
+	user User
+	hash []byte
+	salt []byte
+}
+
+// UserStore хранит учетные записи пользователей в памяти
+type UserStore struct {
+	users     map[string]userRecord // Учетные записи по логину
+	ids       map[int]string        // Логины по ID
+	idCounter int                   // Счетчик для ID пользователей
+	mutex     sync.RWMutex          // Мьютекс для защиты данных
+}
+
+// NewUserStore создает новое хранилище пользователей
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users: make(map[string]userRecord),
+		ids:   make(map[int]string),
+	}
+}
+
+// Register создает нового пользователя
+func (s *UserStore) Register(login, password string) (User, error) {
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return User{}, ErrInvalidLogin
+	}
+	if len(password) < minPasswordLength {
+		return User{}, ErrWeakPassword
+	}
+
+	// Хешируем пароль до блокировки: это дорогая операция
+	hash, salt, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.users[login]; exists {
+		return User{}, ErrUserExists
+	}
+
+	s.idCounter++
+	user := User{ID: s.idCounter, Login: login}
+	s.users[login] = userRecord{user: user, hash: hash, salt: salt}
+	s.ids[user.ID] = login
+
+	return user, nil
+}
+
+// Authenticate проверяет логин и пароль и возвращает пользователя
+func (s *UserStore) Authenticate(login, password string) (User, error) {
+	s.mutex.RLock()
+	record, exists := s.users[strings.TrimSpace(login)]
+	s.mutex.RUnlock()
+
+	if !exists || !checkPassword(password, record.hash, record.salt) {
+		return User{}, ErrInvalidCredentials
+	}
+
+	return record.user, nil
+}
+
+// Exists сообщает, существует ли пользователь с заданным ID
+func (s *UserStore) Exists(id int) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, exists := s.ids[id]
+	return exists
+}
+
+// Lookup возвращает пользователя с заданным ID
+func (s *UserStore) Lookup(id int) (User, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	login, exists := s.ids[id]
+	if !exists {
+		return User{}, false
+	}
+	return s.users[login].user, true
+}
@@ -1,8 +1,11 @@
 package calculator
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TokenType представляет тип токена.
@@ -23,20 +26,52 @@ const (
 type Token struct {
 	Type TokenType // Тип токена
 	Name string    // Строковое представление токена
+	Pos  int       // Позиция первого символа токена в выражении (в символах)
+}
+
+// SyntaxError описывает ошибку разбора выражения и позицию, на которой она обнаружена.
+type SyntaxError struct {
+	Msg    string // Описание ошибки
+	Offset int    // Позиция в выражении (в символах); длина выражения — ошибка в конце
+}
+
+// Error возвращает описание ошибки.
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
+// syntaxError создает ошибку разбора на токене pos. За пределами списка токенов
+// позиция остается -1 и заменяется длиной выражения в Eval.
+func syntaxError(tokens []Token, pos int, format string, args ...any) error {
+	offset := -1
+	if pos < len(tokens) {
+		offset = tokens[pos].Pos
+	}
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Offset: offset}
 }
 
 // Calc вычисляет результат арифметического выражения.
 // Возвращает результат вычисления и ошибку, если она возникла.
 func Calc(expression string) (float64, error) {
+	return Eval(expression, nil)
+}
+
+// Eval вычисляет арифметическое выражение, в котором могут встречаться переменные из vars.
+// Ошибки разбора возвращаются как *SyntaxError с позицией ошибки.
+func Eval(expression string, vars map[string]float64) (float64, error) {
 	tokens := tokenize(expression)
 
-	result, pos, err := parseExpression(tokens, 0)
-	if err != nil {
-		return 0, err
+	result, pos, err := parseExpression(tokens, 0, vars)
+	if err == nil && pos != len(tokens) {
+		err = syntaxError(tokens, pos, "unexpected token: %s", tokens[pos].Name)
 	}
 
-	if pos != len(tokens) {
-		return 0, fmt.Errorf("unexpected token: %s", tokens[pos].Name)
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Offset < 0 {
+		syntaxErr.Offset = utf8.RuneCountInString(expression)
+	}
+	if err != nil {
+		return 0, err
 	}
 
 	return result, nil
@@ -46,35 +81,41 @@ func Calc(expression string) (float64, error) {
 func tokenize(expression string) []Token {
 	var tokens []Token
 	var currentToken string
+	var currentPos int
 
+	pos := 0
 	for _, char := range expression {
 		switch char {
 		case '+', '-', '*', '/', '(', ')':
 			if currentToken != "" {
-				tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken})
+				tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken, Pos: currentPos})
 				currentToken = ""
 			}
-			tokens = append(tokens, Token{Type: TokenType(char), Name: string(char)})
+			tokens = append(tokens, Token{Type: TokenType(char), Name: string(char), Pos: pos})
 		case ' ', '\t':
 			if currentToken != "" {
-				tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken})
+				tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken, Pos: currentPos})
 				currentToken = ""
 			}
 		default:
+			if currentToken == "" {
+				currentPos = pos
+			}
 			currentToken += string(char)
 		}
+		pos++
 	}
 
 	if currentToken != "" {
-		tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken})
+		tokens = append(tokens, Token{Type: TokenNumber, Name: currentToken, Pos: currentPos})
 	}
 
 	return tokens
 }
 
 // parseExpression обрабатывает сложение и вычитание.
-func parseExpression(tokens []Token, pos int) (float64, int, error) {
-	left, pos, err := parseTerm(tokens, pos)
+func parseExpression(tokens []Token, pos int, vars map[string]float64) (float64, int, error) {
+	left, pos, err := parseTerm(tokens, pos, vars)
 	if err != nil {
 		return 0, pos, err
 	}
@@ -82,14 +123,14 @@ func parseExpression(tokens []Token, pos int) (float64, int, error) {
 	for pos < len(tokens) {
 		switch tokens[pos].Type {
 		case TokenPlus:
-			right, newPos, err := parseTerm(tokens, pos+1)
+			right, newPos, err := parseTerm(tokens, pos+1, vars)
 			if err != nil {
 				return 0, newPos, err
 			}
 			left += right
 			pos = newPos
 		case TokenMinus:
-			right, newPos, err := parseTerm(tokens, pos+1)
+			right, newPos, err := parseTerm(tokens, pos+1, vars)
 			if err != nil {
 				return 0, newPos, err
 			}
@@ -104,8 +145,8 @@ func parseExpression(tokens []Token, pos int) (float64, int, error) {
 }
 
 // parseTerm обрабатывает умножение и деление.
-func parseTerm(tokens []Token, pos int) (float64, int, error) {
-	left, pos, err := parseFactor(tokens, pos)
+func parseTerm(tokens []Token, pos int, vars map[string]float64) (float64, int, error) {
+	left, pos, err := parseFactor(tokens, pos, vars)
 	if err != nil {
 		return 0, pos, err
 	}
@@ -113,14 +154,14 @@ func parseTerm(tokens []Token, pos int) (float64, int, error) {
 	for pos < len(tokens) {
 		switch tokens[pos].Type {
 		case TokenMultiply:
-			right, newPos, err := parseFactor(tokens, pos+1)
+			right, newPos, err := parseFactor(tokens, pos+1, vars)
 			if err != nil {
 				return 0, newPos, err
 			}
 			left *= right
 			pos = newPos
 		case TokenDivide:
-			right, newPos, err := parseFactor(tokens, pos+1)
+			right, newPos, err := parseFactor(tokens, pos+1, vars)
 			if err != nil {
 				return 0, newPos, err
 			}
@@ -139,15 +180,15 @@ func parseTerm(tokens []Token, pos int) (float64, int, error) {
 
 // parseFactor обрабатывает числа, унарные операторы и скобки.
 // Возвращает значение, позицию после обработки и ошибку, если она возникла.
-func parseFactor(tokens []Token, pos int) (float64, int, error) {
+func parseFactor(tokens []Token, pos int, vars map[string]float64) (float64, int, error) {
 	if pos >= len(tokens) {
-		return 0, pos, fmt.Errorf("insufficient tokens")
+		return 0, pos, syntaxError(tokens, pos, "insufficient tokens")
 	}
 
 	// Обработка унарных операторов
 	if tokens[pos].Type == TokenPlus || tokens[pos].Type == TokenMinus {
 		operator := tokens[pos].Type
-		value, newPos, err := parseFactor(tokens, pos+1)
+		value, newPos, err := parseFactor(tokens, pos+1, vars)
 		if err != nil {
 			return 0, newPos, err
 		}
@@ -159,25 +200,44 @@ func parseFactor(tokens []Token, pos int) (float64, int, error) {
 
 	// Обработка скобок
 	if tokens[pos].Type == TokenLParen {
-		result, newPos, err := parseExpression(tokens, pos+1)
+		result, newPos, err := parseExpression(tokens, pos+1, vars)
 		if err != nil {
 			return 0, newPos, err
 		}
 		if newPos >= len(tokens) || tokens[newPos].Type != TokenRParen {
-			return 0, newPos, fmt.Errorf("missing closing parenthesis")
+			return 0, newPos, syntaxError(tokens, newPos, "missing closing parenthesis")
 		}
 		return result, newPos + 1, nil
 	}
 
 	// Обработка чисел
 	if tokens[pos].Type != TokenNumber {
-		return 0, pos, fmt.Errorf("invalid token: %s", tokens[pos].Name)
+		return 0, pos, syntaxError(tokens, pos, "invalid token: %s", tokens[pos].Name)
 	}
 
 	num, err := strconv.ParseFloat(tokens[pos].Name, 64)
 	if err != nil {
-		return 0, pos, fmt.Errorf("invalid number: %s", tokens[pos].Name)
+		// Обработка переменных
+		name := tokens[pos].Name
+		if value, ok := vars[name]; ok {
+			return value, pos + 1, nil
+		}
+		if vars != nil && IsIdentifier(name) {
+			return 0, pos, syntaxError(tokens, pos, "unknown variable: %s", name)
+		}
+		return 0, pos, syntaxError(tokens, pos, "invalid number: %s", name)
 	}
 
 	return num, pos + 1, nil
 }
+
+// IsIdentifier сообщает, может ли строка быть именем переменной:
+// буква или подчеркивание, за которыми следуют буквы, цифры или подчеркивания.
+func IsIdentifier(name string) bool {
+	for i, char := range name {
+		if char != '_' && !unicode.IsLetter(char) && (i == 0 || !unicode.IsDigit(char)) {
+			return false
+		}
+	}
+	return name != ""
+}
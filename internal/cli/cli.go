@@ -0,0 +1,181 @@
+// Package cli реализует клиент командной строки arithctl поверх пакета pkg/client
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/config"
+	"github.com/mpkelevra23/arithmetic-web-service/pkg/client"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Коды завершения
+const (
+	exitOK    = 0 // Команда выполнена
+	exitError = 1 // Ошибка API или вычисления
+	exitUsage = 2 // Неверные аргументы
+)
+
+// usage — справка по командам
+const usage = `Usage: arithctl [flags] <command> [arguments]
+
+Commands:
+  eval <expression>        evaluate an expression synchronously
+  submit [-f file] [expr]  submit expressions from arguments or a file (- is stdin)
+  get <id>                 show an expression
+  watch <id>               follow an expression and its task progress until it finishes
+  cancel <id>              cancel an unfinished expression
+  ls [flags]               list expressions (ls -h for filters)
+  graph <id>               print the task graph in Graphviz DOT format
+  agents                   list agents (requires admin_token)
+  repl [-remote]           interactive calculator with variables and line history
+
+Flags go before the command; run "arithctl -h" to list them. Settings are read from
+--config, CONFIG_FILE or the [arithctl] table of ~/.config/arithctl/config.toml,
+then from ARITHCTL_* environment variables and flags.
+`
+
+// app хранит настройки и потоки ввода-вывода одного запуска
+type app struct {
+	cfg    *config.CLIConfig
+	client *client.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command — обработчик команды; args — аргументы после имени команды
+type command func(a *app, ctx context.Context, args []string) error
+
+// commands сопоставляет имена команд с обработчиками
+var commands = map[string]command{
+	"eval":   (*app).eval,
+	"submit": (*app).submit,
+	"get":    (*app).get,
+	"watch":  (*app).watch,
+	"cancel": (*app).cancel,
+	"ls":     (*app).list,
+	"graph":  (*app).graph,
+	"agents": (*app).agents,
+	"repl":   (*app).repl,
+}
+
+// usageError — ошибка в аргументах команды
+type usageError struct {
+	msg string
+}
+
+// Error возвращает описание ошибки
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usagef создает ошибку в аргументах команды
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Run выполняет команду arithctl с аргументами args (без имени программы)
+// и возвращает код завершения процесса
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := config.LoadCLIConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stderr, "\n"+usage)
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "arithctl: %v\n", err)
+		return exitUsage
+	}
+	cfg.PrintAndExit()
+
+	if len(cfg.Args) == 0 || cfg.Args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(cfg.Args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	name := cfg.Args[0]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "arithctl: unknown command %q\n\n%s", name, usage)
+		return exitUsage
+	}
+
+	a := &app{
+		cfg:    cfg,
+		client: newClient(cfg, cfg.Token),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	err = cmd(a, ctx, cfg.Args[1:])
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "arithctl %s: %v\n", name, err)
+		return exitUsage
+	case errors.Is(err, errReported):
+		return exitError
+	default:
+		fmt.Fprintf(stderr, "arithctl %s: %v\n", name, err)
+		return exitError
+	}
+}
+
+// errReported означает, что команда уже вывела сообщения об ошибках и должна завершиться с кодом 1
+var errReported = errors.New("errors reported")
+
+// newClient создает клиента API с токеном token и API-ключом из конфигурации
+func newClient(cfg *config.CLIConfig, token string) *client.Client {
+	return client.New(cfg.Endpoint,
+		client.WithToken(token),
+		client.WithAPIKey(cfg.APIKey),
+		client.WithHTTPClient(&http.Client{Timeout: cfg.Timeout}),
+	)
+}
+
+// newFlagSet создает набор флагов команды, печатающий справку в stderr
+func (a *app) newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: arithctl %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseID разбирает единственный аргумент команды — ID выражения
+func parseID(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, usagef("expected an expression ID")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, usagef("invalid expression ID %q", args[0])
+	}
+	return id, nil
+}
+
+// isTerminal сообщает, выводится ли w на терминал
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/testutil"
+	"github.com/mpkelevra23/arithmetic-web-service/pkg/client"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer запускает оркестратор с аутентификацией администратора и агентом
+// в том же процессе и изолирует тест от настроек arithctl пользователя
+func newTestServer(t *testing.T) (*httptest.Server, *orchestrator.Storage) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, key := range []string{"CONFIG_FILE", "ARITHCTL_ENDPOINT", "ARITHCTL_TOKEN", "ARITHCTL_API_KEY", "ARITHCTL_ADMIN_TOKEN", "ARITHCTL_OUTPUT"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	storage := orchestrator.NewStorage()
+	server := orchestrator.NewServer(storage, orchestrator.NewParser(orchestrator.OperationTimes{}))
+	server.SetAuthenticator(auth.NewAuthenticator("", time.Hour, "", "admin-secret"))
+	ts := httptest.NewServer(server.SetupRoutes())
+	t.Cleanup(ts.Close)
+	return ts, storage
+}
+
+// run выполняет arithctl с адресом тестового сервера и возвращает код завершения и вывод
+func run(t *testing.T, ts *httptest.Server, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--endpoint", ts.URL}, args...)
+	code := Run(ctx, args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Commands(t *testing.T) {
+	ts, storage := newTestServer(t)
+	testutil.StartAgent(t, storage)
+
+	if code, out, _ := run(t, ts, "", "eval", "2+2*2"); code != exitOK || out != "6\n" {
+		t.Errorf("eval = %d, %q; want 6", code, out)
+	}
+
+	// Ошибочные строки файла не мешают отправке остальных, но дают код 1
+	code, out, _ := run(t, ts, "# выражения\n(2+3)*4\n\n1+\n1/0\n", "submit", "-f", "-")
+	if code != exitError || !strings.Contains(out, "(2+3)*4") || strings.Count(out, "\n") != 4 {
+		t.Errorf("submit = %d, %q", code, out)
+	}
+
+	code, out, _ = run(t, ts, "", "watch", "-interval", "10ms", "1")
+	if code != exitOK || !strings.Contains(out, "2/2 tasks") || !strings.Contains(out, "Result:      20") {
+		t.Errorf("watch = %d, %q", code, out)
+	}
+	if code, _, _ := run(t, ts, "", "watch", "-interval", "10ms", "2"); code != exitError {
+		t.Errorf("watch of failed expression exit code = %d, want %d", code, exitError)
+	}
+
+	code, out, _ = run(t, ts, "", "--output", "json", "ls", "--status", "error")
+	var page client.Page
+	if err := json.Unmarshal([]byte(out), &page); code != exitOK || err != nil {
+		t.Fatalf("ls = %d, %q, %v", code, out, err)
+	}
+	if len(page.Expressions) != 1 || page.Expressions[0].Expression != "1/0" {
+		t.Errorf("ls --status error = %+v", page.Expressions)
+	}
+
+	code, out, _ = run(t, ts, "", "graph", "1")
+	for _, want := range []string{`digraph "expression 1"`, `t1 [label="#1: 2 + 3\n= 5"`, "t1 -> t2;", "t2 -> expr;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("graph output lacks %q:\n%s", want, out)
+		}
+	}
+	if code != exitOK {
+		t.Errorf("graph exit code = %d", code)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"no command", nil, exitUsage, "Usage: arithctl"},
+		{"unknown command", []string{"frobnicate"}, exitUsage, `unknown command "frobnicate"`},
+		{"bad id", []string{"get", "x"}, exitUsage, `invalid expression ID "x"`},
+		{"not found", []string{"get", "999"}, exitError, "404"},
+		{"invalid expression", []string{"eval", "2+"}, exitError, "422"},
+		{"agents without admin token", []string{"agents"}, exitUsage, "admin_token is required"},
+		{"agents with wrong admin token", []string{"--admin-token", "wrong", "agents"}, exitError, "403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := run(t, ts, "", tt.args...)
+			if code != tt.code || !strings.Contains(stderr, tt.want) {
+				t.Errorf("code = %d, stderr = %q; want %d and %q", code, stderr, tt.code, tt.want)
+			}
+		})
+	}
+
+	if code, out, _ := run(t, ts, "", "--admin-token", "admin-secret", "agents"); code != exitOK || !strings.HasPrefix(out, "ID") {
+		t.Errorf("agents = %d, %q", code, out)
+	}
+}
+
+func TestRun_REPL(t *testing.T) {
+	ts, storage := newTestServer(t)
+	testutil.StartAgent(t, storage)
+
+	code, out, stderr := run(t, ts, "x = 2+3\nx*2\nans - 1\n\n2 + * 3\ny = (1 + x\nz + 1\n", "repl")
+	if out != "x = 5\n10\n9\n" {
+		t.Errorf("repl output = %q", out)
+	}
+	want := "  2 + * 3\n      ^\nerror: invalid token: *\n" +
+		"  y = (1 + x\n            ^\nerror: missing closing parenthesis\n" +
+		"  z + 1\n  ^\nerror: unknown variable: z\n"
+	if code != exitError || stderr != want {
+		t.Errorf("repl = %d, stderr %q; want %q", code, stderr, want)
+	}
+
+	// Отрицательное значение ans передается оркестратору как (0-4)
+	code, out, stderr = run(t, ts, "x = 2*3\nx - 10\nans * ans\nx\nexit\n1+\n", "repl", "-remote")
+	if code != exitOK || out != "x = 6\n-4\n16\n6\n" {
+		t.Errorf("repl -remote = %d, %q, %q", code, out, stderr)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	// Стрелка влево, вставка, переход по истории, Ctrl-A, Backspace, Ctrl-E,
+	// Ctrl-C прерывает строку, Ctrl-D на пустой строке завершает ввод
+	input := "12\x1b[D3\r" + "\x1b[A\x1b[A\x01x\x7f\x05+1\r" + "abc\x03" + "\x04"
+	e := &lineEditor{
+		in:      bufio.NewReader(strings.NewReader(input)),
+		out:     io.Discard,
+		history: []string{"1+1"},
+		raw:     func() (func(), error) { return func() {}, nil },
+	}
+
+	var lines []string
+	var err error
+	for err != io.EOF {
+		var line string
+		line, err = e.readLine()
+		switch {
+		case err == nil:
+			lines = append(lines, line)
+			e.addHistory(line)
+		case err == errInterrupted:
+			lines = append(lines, "^C")
+		case err != io.EOF:
+			t.Fatalf("readLine: %v", err)
+		}
+	}
+
+	want := []string{"132", "1+1+1", "^C"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
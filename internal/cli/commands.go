@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/pkg/client"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// eval синхронно вычисляет выражение: arithctl eval "2+2"
+func (a *app) eval(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("expected an expression")
+	}
+	expression := strings.Join(args, " ")
+
+	result, err := a.client.Evaluate(ctx, expression)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Output == "json" {
+		return a.printJSON(map[string]string{"expression": expression, "result": result})
+	}
+	fmt.Fprintln(a.stdout, result)
+	return nil
+}
+
+// submitted — результат отправки одного выражения
+type submitted struct {
+	ID         int    `json:"id,omitempty"`
+	Expression string `json:"expression"`
+	Error      string `json:"error,omitempty"`
+}
+
+// submit отправляет выражения на асинхронное вычисление: из аргументов и из файла -f,
+// по одному в строке. Пустые строки и строки, начинающиеся с #, пропускаются
+func (a *app) submit(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("submit", "[-f file] [expression ...]")
+	file := fs.String("f", "", "file with one expression per line (- reads stdin)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	expressions := fs.Args()
+	if *file != "" {
+		lines, err := a.readExpressions(*file)
+		if err != nil {
+			return err
+		}
+		expressions = append(expressions, lines...)
+	}
+	if len(expressions) == 0 {
+		return usagef("expected expressions or -f file")
+	}
+
+	results := make([]submitted, 0, len(expressions))
+	failed := false
+	for _, expression := range expressions {
+		id, err := a.client.Submit(ctx, expression)
+		item := submitted{ID: id, Expression: expression}
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			item.Error = err.Error()
+			failed = true
+		}
+		results = append(results, item)
+	}
+
+	if a.cfg.Output == "json" {
+		if err := a.printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		columns := []string{"ID", "EXPRESSION"}
+		if failed {
+			columns = append(columns, "ERROR")
+		}
+		t := a.newTable(columns...)
+		for _, item := range results {
+			if item.Error != "" {
+				t.row("-", item.Expression, item.Error)
+				continue
+			}
+			t.row(fmt.Sprint(item.ID), item.Expression)
+		}
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return errReported
+	}
+	return nil
+}
+
+// readExpressions читает выражения из файла или из stdin, если path равен "-"
+func (a *app) readExpressions(path string) ([]string, error) {
+	var r io.Reader = a.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var expressions []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		expressions = append(expressions, line)
+	}
+	return expressions, scanner.Err()
+}
+
+// get выводит выражение: arithctl get 1
+func (a *app) get(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+	expr, err := a.client.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.printExpression(expr)
+}
+
+// cancel отменяет незавершенное выражение: arithctl cancel 1
+func (a *app) cancel(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+	expr, err := a.client.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.printExpression(expr)
+}
+
+// list выводит страницу выражений или, с флагом --all, все выражения: arithctl ls --status ERROR
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("ls", "[flags]")
+	status := fs.String("status", "", "comma-separated statuses: PENDING, PROCESSING, COMPLETED, ERROR, CANCELLED")
+	search := fs.String("q", "", "substring of the expression")
+	sortBy := fs.String("sort", "", "sort field: id, created_at or duration")
+	desc := fs.Bool("desc", false, "sort in descending order")
+	limit := fs.Int("limit", 20, "page size")
+	cursor := fs.String("cursor", "", "cursor of the page to show")
+	all := fs.Bool("all", false, "fetch all pages")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	opts := client.ListOptions{
+		Search:     *search,
+		SortBy:     *sortBy,
+		Descending: *desc,
+		Limit:      *limit,
+		Cursor:     *cursor,
+	}
+	if *status != "" {
+		for _, s := range strings.Split(*status, ",") {
+			opts.Statuses = append(opts.Statuses, client.Status(strings.ToUpper(strings.TrimSpace(s))))
+		}
+	}
+
+	var expressions []client.Expression
+	for {
+		page, err := a.client.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		expressions = append(expressions, page.Expressions...)
+		if !*all || page.NextCursor == "" {
+			opts.Cursor = page.NextCursor
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	if a.cfg.Output == "json" {
+		return a.printJSON(client.Page{Expressions: expressions, NextCursor: opts.Cursor})
+	}
+
+	t := a.newTable("ID", "STATUS", "RESULT", "DURATION", "EXPRESSION")
+	for _, expr := range expressions {
+		t.row(fmt.Sprint(expr.ID), string(expr.Status), outcome(expr), duration(expr), expr.Expression)
+	}
+	if err := t.flush(); err != nil {
+		return err
+	}
+	if opts.Cursor != "" {
+		fmt.Fprintf(a.stderr, "more expressions: arithctl ls --cursor %s (or --all)\n", opts.Cursor)
+	}
+	return nil
+}
+
+// agents выводит агентов оркестратора. Запрос выполняется с секретом администратора
+func (a *app) agents(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	if a.cfg.AdminToken == "" {
+		return usagef("admin_token is required (ARITHCTL_ADMIN_TOKEN or --admin-token)")
+	}
+
+	agents, err := newClient(a.cfg, a.cfg.AdminToken).Agents(ctx)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Output == "json" {
+		return a.printJSON(agents)
+	}
+
+	t := a.newTable("ID", "ACTIVE", "TASKS", "LAST SEEN")
+	now := time.Now()
+	for _, agent := range agents {
+		lastSeen := "-"
+		if agent.LastSeen != nil {
+			lastSeen = now.Sub(*agent.LastSeen).Round(time.Second).String() + " ago"
+		}
+		t.row(agent.ID, fmt.Sprint(agent.Active), fmt.Sprint(agent.Tasks), lastSeen)
+	}
+	return t.flush()
+}
+
+// graph выводит граф задач выражения в формате DOT: arithctl graph 1 | dot -Tsvg > graph.svg
+func (a *app) graph(ctx context.Context, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+	expr, err := a.client.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	tasks, err := a.client.Tasks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if a.cfg.Output == "json" {
+		return a.printJSON(tasks)
+	}
+	_, err = io.WriteString(a.stdout, renderDOT(expr, tasks))
+	return err
+}
+
+// watch следит за выражением и ходом выполнения его задач до окончательного статуса.
+// Завершается с кодом 1, если выражение завершилось ошибкой
+func (a *app) watch(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("watch", "[-interval duration] <id>")
+	interval := fs.Duration("interval", 500*time.Millisecond, "polling interval")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := parseID(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return usagef("interval must be positive")
+	}
+
+	live := a.cfg.Output != "json" && isTerminal(a.stdout)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		expr, err := a.client.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		tasks, err := a.client.Tasks(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		p := newProgress(expr, tasks)
+		if line := p.line(); line != last {
+			last = line
+			if err := a.printProgress(p, live); err != nil {
+				return err
+			}
+		}
+
+		if expr.Status.IsTerminal() {
+			if live {
+				fmt.Fprintln(a.stdout)
+			}
+			if a.cfg.Output != "json" {
+				if err := a.printExpression(expr); err != nil {
+					return err
+				}
+			}
+			if expr.Status == client.StatusError || expr.Status == client.StatusCancelled {
+				return errReported
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if live {
+				fmt.Fprintln(a.stdout)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// printProgress выводит состояние выполнения: на терминале перерисовывает строку,
+// иначе добавляет строку или JSON-объект на каждое изменение
+func (a *app) printProgress(p progress, live bool) error {
+	switch {
+	case a.cfg.Output == "json":
+		return a.printJSONLine(p)
+	case live:
+		_, err := fmt.Fprintf(a.stdout, "\r\033[K%s", p.line())
+		return err
+	default:
+		_, err := fmt.Fprintln(a.stdout, p.line())
+		return err
+	}
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// historySize — максимальное число строк истории ввода
+const historySize = 1000
+
+// errInterrupted означает, что ввод строки прерван нажатием Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor читает строки ввода. На терминале поддерживает редактирование строки
+// и перемещение по истории ввода, иначе читает строки как есть
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	prompt  string
+	history []string
+
+	// raw переводит терминал в режим посимвольного ввода и возвращает функцию
+	// восстановления режима; nil — построчный ввод
+	raw func() (func(), error)
+}
+
+// readLine читает строку без символа перевода строки. Возвращает io.EOF, если ввод
+// закончился, и errInterrupted, если строка прервана нажатием Ctrl-C
+func (e *lineEditor) readLine() (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err == nil {
+			defer restore()
+			return e.edit()
+		}
+		// Терминал не поддерживает посимвольный ввод: строки читаются без редактирования
+		e.raw = nil
+	}
+
+	fmt.Fprint(e.out, e.prompt)
+	line, err := e.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// ctrl возвращает код символа, передаваемого терминалом при нажатии Ctrl и key
+func ctrl(key rune) rune {
+	return key & 0x1f
+}
+
+// edit читает строку в режиме посимвольного ввода, обрабатывая клавиши редактирования
+func (e *lineEditor) edit() (string, error) {
+	var buf []rune
+	cursor := 0
+	index := len(e.history) // Позиция в истории; len(e.history) — новая строка
+	var draft []rune        // Новая строка, сохраненная при переходе к истории
+
+	// recall заменяет строку записью истории с номером i
+	recall := func(i int) {
+		if i < 0 || i > len(e.history) || i == index {
+			return
+		}
+		if index == len(e.history) {
+			draft = buf
+		}
+		index = i
+		if i == len(e.history) {
+			buf = draft
+		} else {
+			buf = []rune(e.history[i])
+		}
+		cursor = len(buf)
+	}
+
+	e.refresh(buf, cursor)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return string(buf), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case ctrl('C'):
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case ctrl('D'):
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if cursor < len(buf) {
+				buf = append(buf[:cursor], buf[cursor+1:]...)
+			}
+		case 127, ctrl('H'):
+			if cursor > 0 {
+				buf = append(buf[:cursor-1], buf[cursor:]...)
+				cursor--
+			}
+		case ctrl('A'):
+			cursor = 0
+		case ctrl('E'):
+			cursor = len(buf)
+		case ctrl('B'):
+			cursor = max(cursor-1, 0)
+		case ctrl('F'):
+			cursor = min(cursor+1, len(buf))
+		case ctrl('K'):
+			buf = buf[:cursor]
+		case ctrl('U'):
+			buf = append([]rune(nil), buf[cursor:]...)
+			cursor = 0
+		case ctrl('P'):
+			recall(index - 1)
+		case ctrl('N'):
+			recall(index + 1)
+		case 0x1b:
+			switch e.readEscape() {
+			case "[A", "OA":
+				recall(index - 1)
+			case "[B", "OB":
+				recall(index + 1)
+			case "[C", "OC":
+				cursor = min(cursor+1, len(buf))
+			case "[D", "OD":
+				cursor = max(cursor-1, 0)
+			case "[H", "OH", "[1~", "[7~":
+				cursor = 0
+			case "[F", "OF", "[4~", "[8~":
+				cursor = len(buf)
+			case "[3~":
+				if cursor < len(buf) {
+					buf = append(buf[:cursor], buf[cursor+1:]...)
+				}
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			buf = append(buf[:cursor], append([]rune{r}, buf[cursor:]...)...)
+			cursor++
+		}
+		e.refresh(buf, cursor)
+	}
+}
+
+// readEscape читает управляющую последовательность после ESC, например "[A" для стрелки вверх
+func (e *lineEditor) readEscape() string {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+	seq := []rune{r}
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, r)
+		// Последовательность завершается символом из диапазона @..~
+		if r >= '@' && r <= '~' {
+			return string(seq)
+		}
+	}
+}
+
+// refresh перерисовывает строку ввода и ставит курсор на позицию cursor
+func (e *lineEditor) refresh(buf []rune, cursor int) {
+	line := "\r" + e.prompt + string(buf) + "\033[K"
+	if back := len(buf) - cursor; back > 0 {
+		line += fmt.Sprintf("\033[%dD", back)
+	}
+	io.WriteString(e.out, line)
+}
+
+// addHistory добавляет строку в историю, пропуская повтор предыдущей строки
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+}
+
+// defaultHistoryFile возвращает путь к файлу истории в каталоге настроек пользователя
+// или пустую строку, если каталог не определен
+func defaultHistoryFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "arithctl", "history")
+}
+
+// loadHistory читает последние historySize строк истории из файла. Отсутствующий
+// или нечитаемый файл дает пустую историю
+func loadHistory(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	return history
+}
+
+// saveHistory записывает историю в файл, создавая каталог при необходимости
+func saveHistory(path string, history []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, line := range history {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o600)
+}
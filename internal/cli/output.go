@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/pkg/client"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printJSON выводит значение в формате JSON с отступами
+func (a *app) printJSON(v any) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printJSONLine выводит значение одной строкой JSON
+func (a *app) printJSONLine(v any) error {
+	return json.NewEncoder(a.stdout).Encode(v)
+}
+
+// table выводит данные колонками, выровненными пробелами
+type table struct {
+	w *tabwriter.Writer
+}
+
+// newTable создает таблицу с заголовками columns
+func (a *app) newTable(columns ...string) *table {
+	t := &table{w: tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)}
+	t.row(columns...)
+	return t
+}
+
+// row добавляет строку таблицы
+func (t *table) row(cells ...string) {
+	fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+}
+
+// flush выводит накопленные строки
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+// printExpression выводит выражение в выбранном формате
+func (a *app) printExpression(expr client.Expression) error {
+	if a.cfg.Output == "json" {
+		return a.printJSON(expr)
+	}
+
+	t := &table{w: tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)}
+	t.row("ID:", fmt.Sprint(expr.ID))
+	t.row("Expression:", expr.Expression)
+	t.row("Status:", string(expr.Status))
+	if expr.Result != nil {
+		t.row("Result:", *expr.Result)
+	}
+	if expr.Error != "" {
+		t.row("Error:", expr.Error)
+	}
+	t.row("Created:", expr.CreatedAt.Local().Format(time.DateTime))
+	if d := duration(expr); d != "-" {
+		t.row("Duration:", d)
+	}
+	return t.flush()
+}
+
+// outcome возвращает результат выражения, причину ошибки или "-"
+func outcome(expr client.Expression) string {
+	switch {
+	case expr.Result != nil:
+		return *expr.Result
+	case expr.Error != "":
+		return expr.Error
+	default:
+		return "-"
+	}
+}
+
+// duration возвращает время вычисления завершенного выражения или "-"
+func duration(expr client.Expression) string {
+	if expr.CompletedAt == nil {
+		return "-"
+	}
+	return expr.CompletedAt.Sub(expr.CreatedAt).Round(time.Millisecond).String()
+}
+
+// progress — ход выполнения задач выражения
+type progress struct {
+	ID      int           `json:"id"`
+	Status  client.Status `json:"status"`
+	Done    int           `json:"tasks_done"`
+	Running int           `json:"tasks_running"`
+	Total   int           `json:"tasks_total"`
+	Result  *string       `json:"result,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// newProgress подсчитывает задачи выражения по стадиям
+func newProgress(expr client.Expression, tasks []client.Task) progress {
+	p := progress{ID: expr.ID, Status: expr.Status, Total: len(tasks), Result: expr.Result, Error: expr.Error}
+	for _, task := range tasks {
+		switch task.State {
+		case client.TaskDone:
+			p.Done++
+		case client.TaskRunning:
+			p.Running++
+		}
+	}
+	return p
+}
+
+// progressWidth — ширина полосы хода выполнения в символах
+const progressWidth = 20
+
+// line возвращает строку вида "#3 PROCESSING [##########----------] 2/4 tasks, 1 running"
+func (p progress) line() string {
+	filled := progressWidth
+	if p.Total > 0 {
+		filled = progressWidth * p.Done / p.Total
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressWidth-filled)
+	return fmt.Sprintf("#%d %-10s [%s] %d/%d tasks, %d running", p.ID, p.Status, bar, p.Done, p.Total, p.Running)
+}
+
+// stateColors — цвета узлов графа для стадий выполнения задач
+var stateColors = map[client.TaskState]string{
+	client.TaskWaiting: "white",
+	client.TaskReady:   "lightblue",
+	client.TaskRunning: "gold",
+	client.TaskDone:    "palegreen",
+	client.TaskSkipped: "lightgrey",
+}
+
+// operationSymbols — знаки операций для подписей узлов графа
+var operationSymbols = map[string]string{
+	"ADD":      "+",
+	"SUBTRACT": "-",
+	"MULTIPLY": "*",
+	"DIVIDE":   "/",
+}
+
+// renderDOT строит граф вычисления выражения в формате Graphviz DOT. Ребра ведут от задачи
+// к задаче, использующей ее результат; итоговая задача связана с узлом выражения
+func renderDOT(expr client.Expression, tasks []client.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph \"expression %d\" {\n", expr.ID)
+	b.WriteString("\trankdir=BT;\n")
+	b.WriteString("\tnode [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	exprLabel := expr.Expression + "\\n" + string(expr.Status)
+	if expr.Result != nil {
+		exprLabel += " = " + *expr.Result
+	} else if expr.Error != "" {
+		exprLabel += ": " + expr.Error
+	}
+	fmt.Fprintf(&b, "\texpr [label=%s, shape=ellipse, fillcolor=%s];\n", dotString(exprLabel), exprColor(expr.Status))
+
+	used := make(map[int]bool)
+	for _, task := range tasks {
+		for _, dep := range task.Dependencies {
+			used[dep] = true
+		}
+	}
+
+	for _, task := range tasks {
+		symbol, ok := operationSymbols[task.Operation]
+		if !ok {
+			symbol = task.Operation
+		}
+		label := fmt.Sprintf("#%d: %s %s %s", task.ID, dotArg(task.Arg1), symbol, dotArg(task.Arg2))
+		if task.Result != nil {
+			label += "\\n= " + strconv.FormatFloat(*task.Result, 'g', -1, 64)
+		} else {
+			label += "\\n" + string(task.State)
+		}
+		fmt.Fprintf(&b, "\tt%d [label=%s, fillcolor=%s];\n", task.ID, dotString(label), stateColors[task.State])
+	}
+
+	for _, task := range tasks {
+		for _, dep := range task.Dependencies {
+			fmt.Fprintf(&b, "\tt%d -> t%d;\n", dep, task.ID)
+		}
+		if !used[task.ID] {
+			fmt.Fprintf(&b, "\tt%d -> expr;\n", task.ID)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotArg заменяет ссылку на результат задачи res:N на #N
+func dotArg(arg string) string {
+	if ref, found := strings.CutPrefix(arg, "res:"); found {
+		return "#" + ref
+	}
+	return arg
+}
+
+// dotString заключает подпись в кавычки DOT. Последовательность \n в подписи сохраняется
+// как перевод строки Graphviz
+func dotString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// exprColor возвращает цвет узла выражения для его статуса
+func exprColor(status client.Status) string {
+	switch status {
+	case client.StatusCompleted:
+		return "palegreen"
+	case client.StatusError:
+		return "salmon"
+	case client.StatusCancelled:
+		return "lightgray"
+	default:
+		return "white"
+	}
+}
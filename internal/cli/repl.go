@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/calculator"
+	"github.com/mpkelevra23/arithmetic-web-service/pkg/client"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// replHelp — справка интерактивного режима
+const replHelp = `Enter an expression to evaluate it or "name = expression" to assign a variable.
+ans holds the previous result. Type exit or press Ctrl-D to quit.
+`
+
+// session — состояние интерактивного режима: переменные и способ вычисления
+type session struct {
+	client *client.Client
+	remote bool               // Вычислять выражения оркестратором
+	vars   map[string]float64 // Переменные, включая ans — результат предыдущего выражения
+}
+
+// repl запускает интерактивный режим: arithctl repl [-remote]. Выражения вычисляются
+// локально или, с флагом -remote, оркестратором. При чтении не с терминала завершается
+// с кодом 1, если хотя бы одна строка не вычислена
+func (a *app) repl(ctx context.Context, args []string) error {
+	fs := a.newFlagSet("repl", "[-remote] [-history file]")
+	remote := fs.Bool("remote", false, "evaluate expressions on the orchestrator instead of locally")
+	historyFile := fs.String("history", defaultHistoryFile(), "history file (empty disables saving)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	editor := &lineEditor{in: bufio.NewReader(a.stdin), out: a.stdout}
+	f, interactive := a.stdin.(*os.File)
+	interactive = interactive && isTerminal(f) && isTerminal(a.stdout)
+	if interactive {
+		editor.prompt = "> "
+		editor.raw = func() (func(), error) { return makeRaw(f) }
+		if *historyFile != "" {
+			editor.history = loadHistory(*historyFile)
+			defer func() {
+				if err := saveHistory(*historyFile, editor.history); err != nil {
+					fmt.Fprintf(a.stderr, "arithctl repl: saving history: %v\n", err)
+				}
+			}()
+		}
+	}
+
+	s := &session{client: a.client, remote: *remote, vars: make(map[string]float64)}
+	failed := false
+	for {
+		line, err := editor.readLine()
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Fprint(a.stdout, replHelp)
+			continue
+		}
+		editor.addHistory(line)
+
+		output, err := s.execute(ctx, line)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			a.printREPLError(line, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintln(a.stdout, output)
+	}
+
+	if failed && !interactive {
+		return errReported
+	}
+	return nil
+}
+
+// execute вычисляет строку — выражение или присваивание "name = expression" —
+// и возвращает текст для вывода
+func (s *session) execute(ctx context.Context, line string) (string, error) {
+	name, expression, assignment := strings.Cut(line, "=")
+	offset := 0 // Позиция выражения в строке
+	if assignment {
+		name = strings.TrimSpace(name)
+		if !calculator.IsIdentifier(name) || name == "ans" {
+			return "", fmt.Errorf("invalid variable name %q", name)
+		}
+		offset = utf8.RuneCountInString(line) - utf8.RuneCountInString(expression)
+	} else {
+		expression = line
+	}
+
+	result, err := s.evaluate(ctx, expression)
+	var syntaxErr *calculator.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return "", &calculator.SyntaxError{Msg: syntaxErr.Msg, Offset: offset + syntaxErr.Offset}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.vars["ans"] = result
+	if assignment {
+		s.vars[name] = result
+		return name + " = " + formatNumber(result), nil
+	}
+	return formatNumber(result), nil
+}
+
+// evaluate вычисляет выражение с переменными сессии. Выражение всегда разбирается
+// локально, чтобы ошибки разбора указывали позицию; в удаленном режиме переменные
+// подставляются в текст выражения и оно отправляется оркестратору
+func (s *session) evaluate(ctx context.Context, expression string) (float64, error) {
+	result, err := calculator.Eval(expression, s.vars)
+	var syntaxErr *calculator.SyntaxError
+	if !s.remote || errors.As(err, &syntaxErr) {
+		return result, err
+	}
+
+	id, err := s.client.Submit(ctx, substitute(expression, s.vars))
+	if err != nil {
+		return 0, err
+	}
+	expr, err := s.client.Wait(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if expr.Status != client.StatusCompleted || expr.Result == nil {
+		return 0, errors.New(expr.Error)
+	}
+	return strconv.ParseFloat(*expr.Result, 64)
+}
+
+// substitute заменяет имена переменных в выражении их значениями. Оркестратор
+// не поддерживает унарный минус, поэтому отрицательное значение записывается как (0-x)
+func substitute(expression string, vars map[string]float64) string {
+	var b, word strings.Builder
+	flush := func() {
+		value, ok := vars[word.String()]
+		switch {
+		case !ok:
+			b.WriteString(word.String())
+		case value < 0:
+			b.WriteString("(0-" + formatNumber(-value) + ")")
+		default:
+			b.WriteString(formatNumber(value))
+		}
+		word.Reset()
+	}
+
+	for _, char := range expression {
+		if strings.ContainsRune("+-*/() \t", char) {
+			flush()
+			b.WriteRune(char)
+			continue
+		}
+		word.WriteRune(char)
+	}
+	flush()
+	return b.String()
+}
+
+// formatNumber форматирует число так же, как синхронный API: целое число без дробной части
+func formatNumber(value float64) string {
+	if value == float64(int64(value)) {
+		return strconv.FormatInt(int64(value), 10)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// printREPLError выводит ошибку вычисления строки. Для ошибки разбора под строкой
+// выводится знак ^ на позиции ошибки
+func (a *app) printREPLError(line string, err error) {
+	var syntaxErr *calculator.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		fmt.Fprintf(a.stderr, "  %s\n  %s^\n", strings.ReplaceAll(line, "\t", " "), strings.Repeat(" ", syntaxErr.Offset))
+	}
+	fmt.Fprintf(a.stderr, "error: %v\n", err)
+}
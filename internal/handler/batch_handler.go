@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxBatchSize ограничивает количество выражений в одном пакете.
+	maxBatchSize = 1000
+	// maxBatchBodySize ограничивает размер тела пакетного запроса.
+	maxBatchBodySize = 10 << 20
+)
+
+// BatchResultItem представляет результат вычисления одного выражения из пакета.
+type BatchResultItem struct {
+	CorrelationID string `json:"correlation_id"`
+	Result        string `json:"result,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BatchCalculateResponse представляет ответ на пакетный запрос.
+type BatchCalculateResponse struct {
+	Results []BatchResultItem `json:"results"`
+}
+
+// BatchEvaluateHandler обрабатывает POST-запросы к эндпоинту /api/v1/evaluate/batch.
+// Выражения вычисляются параллельно пулом из workers горутин. Если quota не nil, каждое
+// выражение списывается с квоты клиента; отклоненные выражения получают ошибку в своем элементе.
+func BatchEvaluateHandler(logger *zap.Logger, workers int, quota QuotaCharger) http.HandlerFunc {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context(), logger)
+		// Проверка метода запроса
+		if r.Method != http.MethodPost {
+			logger.Warn("Unsupported HTTP method", zap.String("method", r.Method))
+			errors.WriteErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrUnsupportedMethod)
+			return
+		}
+
+		// Декодирование JSON-массива или NDJSON
+		items, err := models.DecodeBatchItems(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+		if err != nil {
+			logger.Error("Batch body decoding error", zap.Error(err))
+			errors.WriteErrorResponse(w, http.StatusBadRequest, errors.ErrMalformedJSON)
+			return
+		}
+
+		if len(items) > maxBatchSize {
+			logger.Error("Batch is too large", zap.Int("size", len(items)))
+			errors.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, errors.ErrTooLargeBatch)
+			return
+		}
+
+		// Списание квоты по порядку выражений в пакете
+		results := make([]BatchResultItem, len(items))
+		accepted := make([]int, 0, len(items))
+		for i, item := range items {
+			results[i] = BatchResultItem{CorrelationID: item.CorrelationID}
+			if quota != nil {
+				if err := quota.Charge(r, item.Expression); err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+			}
+			accepted = append(accepted, i)
+		}
+
+		// Вычисление выражений пулом воркеров
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+
+		for i := 0; i < workers && i < len(accepted); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := range jobs {
+					result, status, message := evaluateExpression(logger, items[idx].Expression)
+					if status != http.StatusOK {
+						results[idx].Error = message
+					} else {
+						results[idx].Result = result
+					}
+				}
+			}()
+		}
+
+		for _, i := range accepted {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		// Формирование ответа
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(BatchCalculateResponse{Results: results}); err != nil {
+			logger.Error("Response encoding error", zap.Error(err))
+		}
+	}
+}
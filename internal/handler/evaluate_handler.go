@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/calculator"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"go.uber.org/zap"
+)
+
+// CalculateRequest представляет структуру входящего запроса.
+type CalculateRequest struct {
+	Expression string `json:"expression"`
+}
+
+// CalculateResponse представляет структуру успешного ответа.
+type CalculateResponse struct {
+	Result string `json:"result"`
+}
+
+// QuotaCharger списывает выражение с квоты клиента перед синхронным вычислением.
+type QuotaCharger interface {
+	// Charge списывает выражение с квоты; ошибка означает, что квота исчерпана.
+	Charge(r *http.Request, expression string) error
+	// WriteError отправляет ответ на одиночное выражение, отклоненное по квоте.
+	WriteError(w http.ResponseWriter, err error)
+}
+
+// expressionRegex используется для валидации допустимых символов в выражении.
+var expressionRegex = regexp.MustCompile(`^[0-9+\-*/().\s]+$`)
+
+// EvaluateHandler обрабатывает POST-запросы к эндпоинту /api/v1/evaluate.
+// Если quota не nil, выражение перед вычислением списывается с квоты клиента.
+func EvaluateHandler(logger *zap.Logger, quota QuotaCharger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context(), logger)
+		var req CalculateRequest
+
+		// Пример искусственного вызова ошибки 500
+		if r.Header.Get("X-Trigger-500") == "true" {
+			logger.Error("Artificial internal server error triggered")
+			errors.WriteErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		// Проверка метода запроса
+		if r.Method != http.MethodPost {
+			logger.Warn("Unsupported HTTP method", zap.String("method", r.Method))
+			errors.WriteErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrUnsupportedMethod)
+			return
+		}
+
+		// Декодирование JSON тела запроса
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Request body decoding error", zap.Error(err))
+			errors.WriteErrorResponse(w, http.StatusBadRequest, errors.ErrMalformedJSON)
+			return
+		}
+
+		// Списание квоты
+		if quota != nil {
+			if err := quota.Charge(r, req.Expression); err != nil {
+				logger.Warn("Expression rejected by quota", zap.Error(err))
+				quota.WriteError(w, err)
+				return
+			}
+		}
+
+		// Очистка, проверка и вычисление выражения
+		result, status, message := evaluateExpression(logger, req.Expression)
+		if status != http.StatusOK {
+			errors.WriteErrorResponse(w, status, message)
+			return
+		}
+
+		// Формирование успешного ответа
+		resp := CalculateResponse{
+			Result: result,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("Response encoding error", zap.Error(err))
+		}
+	}
+}
+
+// evaluateExpression проверяет и вычисляет выражение.
+// Возвращает отформатированный результат либо HTTP-статус и сообщение об ошибке.
+func evaluateExpression(logger *zap.Logger, expression string) (string, int, string) {
+	// Очистка и проверка поля expression
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		logger.Error("Empty expression field")
+		return "", http.StatusUnprocessableEntity, errors.ErrMissingField
+	}
+
+	// Валидация выражения на допустимые символы
+	if !expressionRegex.MatchString(expression) {
+		logger.Error("Invalid characters in expression", zap.String("expression", expression))
+		return "", http.StatusUnprocessableEntity, errors.ErrInvalidInput
+	}
+
+	// Пример искусственного вызова ошибки 500 на основе длины выражения
+	if len(expression) > 500 && len(expression) <= 1000 {
+		logger.Error("Artificial internal server error for long expression")
+		return "", http.StatusInternalServerError, "Expression length triggered server error"
+	}
+
+	// Проверка длины выражения
+	if len(expression) > 1000 {
+		logger.Error("Expression is too long", zap.Int("length", len(expression)))
+		return "", http.StatusUnprocessableEntity, errors.ErrTooLongExpression
+	}
+
+	// Вычисление результата выражения
+	result, err := calculator.Calc(expression)
+	if err != nil {
+		logger.Error("Calculation error", zap.Error(err))
+		return "", http.StatusUnprocessableEntity, calculationErrorMessage(err)
+	}
+
+	return formatResult(result), http.StatusOK, ""
+}
+
+// calculationErrorMessage возвращает сообщение API для ошибки вычисления выражения.
+func calculationErrorMessage(err error) string {
+	switch err.Error() {
+	case "division by zero":
+		return errors.ErrDivisionByZero
+	default:
+		return errors.ErrInvalidExpression
+	}
+}
+
+// formatResult форматирует результат вычисления, убирая лишние нули.
+func formatResult(result float64) string {
+	if result == float64(int64(result)) {
+		return strconv.FormatInt(int64(result), 10)
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64)
+}
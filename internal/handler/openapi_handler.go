@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/web"
+)
+
+// OpenAPIHandler отдает описание API в формате OpenAPI 3 (GET /api/openapi.json)
+func OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			errors.WriteErrorResponse(w, http.StatusMethodNotAllowed, errors.ErrUnsupportedMethod)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(web.OpenAPI)
+	}
+}
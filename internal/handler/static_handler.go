@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
 	"github.com/mpkelevra23/arithmetic-web-service/web"
 	"go.uber.org/zap"
 )
@@ -14,7 +15,7 @@ func StaticHandler(logger *zap.Logger) http.Handler {
 	if err != nil {
 		logger.Error("Failed to get web filesystem", zap.Error(err))
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			errors.WriteErrorResponse(w, http.StatusInternalServerError, errors.ErrInternalServer)
 		})
 	}
 
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// checkTimeout ограничивает время выполнения всех проверок готовности
+const checkTimeout = 2 * time.Second
+
+// Статусы в ответах /healthz и /readyz
+const (
+	StatusOK       = "ok"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Check описывает одну проверку готовности сервиса
+type Check struct {
+	Name  string                          // Имя проверки в ответе
+	Check func(ctx context.Context) error // Возвращает ошибку, если сервис не готов
+}
+
+// Response — тело ответов /healthz и /readyz
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // Результат каждой проверки: "ok" или текст ошибки
+}
+
+// LivenessHandler возвращает обработчик /healthz: процесс жив, пока способен ответить
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, Response{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler возвращает обработчик /readyz. Сервис готов, если прошли все проверки;
+// иначе отвечает 503 с результатами каждой проверки
+func ReadinessHandler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		resp := Response{Status: StatusReady, Checks: make(map[string]string, len(checks))}
+		for _, check := range checks {
+			if err := check.Check(ctx); err != nil {
+				resp.Status = StatusNotReady
+				resp.Checks[check.Name] = err.Error()
+				continue
+			}
+			resp.Checks[check.Name] = StatusOK
+		}
+
+		status := http.StatusOK
+		if resp.Status != StatusReady {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, resp)
+	})
+}
+
+// Register добавляет в mux эндпоинты /healthz, /readyz и /version
+func Register(mux *http.ServeMux, service string, checks ...Check) {
+	mux.Handle("/healthz", LivenessHandler())
+	mux.Handle("/readyz", ReadinessHandler(checks...))
+	mux.Handle("/version", VersionHandler(service))
+}
+
+// writeJSON отправляет ответ в формате JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	ok := Check{Name: "storage", Check: func(context.Context) error { return nil }}
+	failing := Check{Name: "agents", Check: func(context.Context) error { return errors.New("нет агентов") }}
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{"без проверок", nil, http.StatusOK, StatusReady, map[string]string{}},
+		{"все проверки прошли", []Check{ok}, http.StatusOK, StatusReady, map[string]string{"storage": "ok"}},
+		{"проверка не прошла", []Check{ok, failing}, http.StatusServiceUnavailable, StatusNotReady,
+			map[string]string{"storage": "ok", "agents": "нет агентов"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ReadinessHandler(tt.checks...).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var resp Response
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if rr.Code != tt.wantCode || resp.Status != tt.wantStatus {
+				t.Errorf("code = %d, status = %q; want %d, %q", rr.Code, resp.Status, tt.wantCode, tt.wantStatus)
+			}
+			for name, want := range tt.wantChecks {
+				if resp.Checks[name] != want {
+					t.Errorf("check %s = %q, want %q", name, resp.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Version, Commit, BuildTime = "v1.2.3", "abc123", "2026-01-02T03:04:05Z"
+	defer func() { Version, Commit, BuildTime = "dev", "", "" }()
+
+	mux := http.NewServeMux()
+	Register(mux, "agent")
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("/healthz: status = %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var info BuildInfo
+	if err := json.NewDecoder(rr.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	want := BuildInfo{Service: "agent", Version: "v1.2.3", Commit: "abc123", BuildTime: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	info.Modified = false
+	if info != want {
+		t.Errorf("/version = %+v, want %+v", info, want)
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Сведения о сборке, задаются при сборке флагами компоновщика:
+//
+//	go build -ldflags "-X github.com/mpkelevra23/arithmetic-web-service/internal/health.Version=v1.2.0 \
+//	  -X github.com/mpkelevra23/arithmetic-web-service/internal/health.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/mpkelevra23/arithmetic-web-service/internal/health.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// BuildInfo — тело ответа /version
+type BuildInfo struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Сборка из рабочей копии с незафиксированными изменениями
+	GoVersion string `json:"go_version"`
+}
+
+// Build возвращает сведения о сборке сервиса. Коммит и время, не заданные флагами
+// компоновщика, берутся из информации о VCS, которую записывает go build
+func Build(service string) BuildInfo {
+	info := BuildInfo{
+		Service:   service,
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	return info
+}
+
+// VersionHandler возвращает обработчик /version
+func VersionHandler(service string) http.Handler {
+	info := Build(service)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, info)
+	})
+}
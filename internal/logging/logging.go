@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// Имена полей, общие для всех сервисов
+const (
+	FieldExpressionID = "expression_id"
+	FieldTaskID       = "task_id"
+	FieldAgentID      = "agent_id"
+	FieldWorkerID     = "worker_id"
+	FieldRequestID    = "request_id"
+)
+
+// Форматы вывода логов
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Options задает уровень, формат и места вывода логов.
+type Options struct {
+	Level            string          // debug, info, warn или error
+	AtomicLevel      zap.AtomicLevel // Изменяемый во время работы уровень; если задан, Level не используется
+	Format           string          // json или console
+	OutputPaths      []string        // Пути для всех записей (stdout, stderr или файлы)
+	ErrorOutputPaths []string        // Пути для внутренних ошибок логгера
+}
+
+// New создает логгер Zap. Директории для файлов вывода создаются при необходимости.
+func New(opts Options) (*zap.Logger, error) {
+	// Убедимся, что директории для логов существуют
+	for _, path := range append(append([]string(nil), opts.OutputPaths...), opts.ErrorOutputPaths...) {
+		if path == "stdout" || path == "stderr" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	// Настройка конфигурации логгера
+	zapConfig := zap.NewProductionConfig()
+	if opts.AtomicLevel != (zap.AtomicLevel{}) {
+		zapConfig.Level = opts.AtomicLevel
+	} else {
+		level, err := ParseLevel(opts.Level)
+		if err != nil {
+			return nil, err
+		}
+		zapConfig.Level = zap.NewAtomicLevelAt(level)
+	}
+
+	switch opts.Format {
+	case "", FormatJSON:
+		zapConfig.Encoding = FormatJSON
+	case FormatConsole:
+		zapConfig.Encoding = FormatConsole
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", opts.Format)
+	}
+
+	// Настройка путей вывода логов
+	if len(opts.OutputPaths) > 0 {
+		zapConfig.OutputPaths = opts.OutputPaths
+	}
+	if len(opts.ErrorOutputPaths) > 0 {
+		zapConfig.ErrorOutputPaths = opts.ErrorOutputPaths
+	}
+
+	// Конфигурация формата времени в логах
+	zapConfig.EncoderConfig = zap.NewProductionEncoderConfig()
+	zapConfig.EncoderConfig.TimeKey = "timestamp"
+	zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return zapConfig.Build()
+}
+
+// ParseLevel преобразует название уровня логирования. Пустая строка означает info.
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zap.DebugLevel, nil
+	case "", "info":
+		return zap.InfoLevel, nil
+	case "warn":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	default:
+		return zap.InfoLevel, fmt.Errorf("unknown log level: %q", level)
+	}
+}
+
+// ExpressionID возвращает поле с ID выражения.
+func ExpressionID(id int) zap.Field {
+	return zap.Int(FieldExpressionID, id)
+}
+
+// TaskID возвращает поле с ID задачи.
+func TaskID(id int) zap.Field {
+	return zap.Int(FieldTaskID, id)
+}
+
+// AgentID возвращает поле с идентификатором агента.
+func AgentID(id string) zap.Field {
+	return zap.String(FieldAgentID, id)
+}
+
+// WorkerID возвращает поле с номером воркера агента.
+func WorkerID(id int) zap.Field {
+	return zap.Int(FieldWorkerID, id)
+}
+
+// RequestID возвращает поле с идентификатором запроса.
+func RequestID(id string) zap.Field {
+	return zap.String(FieldRequestID, id)
+}
+
+// requestIDKey — ключ контекста для идентификатора запроса
+type requestIDKey struct{}
+
+// NewRequestID генерирует случайный идентификатор запроса.
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID сохраняет идентификатор запроса в контексте.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса из контекста.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext возвращает логгер с полем request_id, если он есть в контексте.
+func FromContext(ctx context.Context, base *zap.Logger) *zap.Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return base.With(RequestID(id))
+	}
+	return base
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestNew проверяет проверку уровня и формата логов.
+func TestNew(t *testing.T) {
+	for _, opts := range []Options{
+		{Level: "debug", Format: FormatJSON, OutputPaths: []string{"stdout"}},
+		{Level: "", Format: FormatConsole, OutputPaths: []string{"stdout"}},
+	} {
+		if _, err := New(opts); err != nil {
+			t.Errorf("New(%+v): неожиданная ошибка: %v", opts, err)
+		}
+	}
+
+	for _, opts := range []Options{
+		{Level: "verbose"},
+		{Level: "info", Format: "xml"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v): ожидалась ошибка", opts)
+		}
+	}
+}
+
+// TestFromContext проверяет добавление request_id к логгеру.
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core)
+
+	FromContext(context.Background(), base).Info("without id")
+	FromContext(WithRequestID(context.Background(), "abc"), base).Info("with id", TaskID(7))
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("Ожидалось 2 записи, получено %d", len(entries))
+	}
+	if fields := entries[0].ContextMap(); len(fields) != 0 {
+		t.Errorf("Неожиданные поля: %v", fields)
+	}
+	fields := entries[1].ContextMap()
+	if fields[FieldRequestID] != "abc" || fields[FieldTaskID] != int64(7) {
+		t.Errorf("Неожиданные поля: %v", fields)
+	}
+}
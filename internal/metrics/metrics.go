@@ -0,0 +1,338 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets — границы гистограмм по умолчанию (в секундах) для длительности HTTP-запросов
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector формирует одно семейство метрик в текстовом формате Prometheus
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry хранит метрики и отдает их в текстовом формате Prometheus
+type Registry struct {
+	collectors []collector
+	mutex      sync.Mutex
+}
+
+// NewRegistry создает пустой реестр метрик
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register добавляет семейство метрик в реестр
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText записывает все метрики в текстовом формате Prometheus 0.0.4
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mutex.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler возвращает HTTP-обработчик эндпоинта /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// desc описывает семейство метрик
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// writeHeader записывает строки HELP и TYPE
+func (d desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// labelString формирует набор меток вида {a="1",b="2"}
+func labelString(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelEscaper экранирует значения меток по правилам текстового формата
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel экранирует значение метки и удаляет некорректные последовательности UTF-8
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(strings.ToValidUTF8(value, ""))
+}
+
+// formatFloat форматирует значение метрики
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesKey объединяет значения меток в ключ серии
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// checkLabels проверяет количество значений меток
+func checkLabels(d desc, values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s ожидает %d меток, получено %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// Counter — монотонно возрастающий счетчик
+type Counter struct {
+	mutex sync.Mutex
+	value float64
+}
+
+// Inc увеличивает счетчик на 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add увеличивает счетчик на v (v должно быть неотрицательным)
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mutex.Lock()
+	c.value += v
+	c.mutex.Unlock()
+}
+
+// Value возвращает текущее значение счетчика
+func (c *Counter) Value() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.value
+}
+
+// CounterVec — семейство счетчиков с метками
+type CounterVec struct {
+	desc
+	series map[string]*Counter
+	values map[string][]string
+	mutex  sync.Mutex
+}
+
+// NewCounterVec регистрирует семейство счетчиков
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*Counter),
+		values: make(map[string][]string),
+	}
+	r.register(c)
+	return c
+}
+
+// WithLabelValues возвращает счетчик для заданных значений меток
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	checkLabels(c.desc, values)
+	key := seriesKey(values)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counter, exists := c.series[key]
+	if !exists {
+		counter = &Counter{}
+		c.series[key] = counter
+		c.values[key] = append([]string(nil), values...)
+	}
+	return counter
+}
+
+// write записывает семейство счетчиков
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+
+	c.mutex.Lock()
+	keys := sortedKeys(c.series)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelString(c.labels, c.values[key]), formatFloat(c.series[key].Value()))
+	}
+	c.mutex.Unlock()
+}
+
+// Histogram — распределение наблюдаемых значений по корзинам
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+	mutex   sync.Mutex
+}
+
+// Observe добавляет наблюдение
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// snapshot возвращает копию состояния гистограммы
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]uint64(nil), h.counts...), h.sum, h.count
+}
+
+// HistogramVec — семейство гистограмм с метками
+type HistogramVec struct {
+	desc
+	buckets []float64
+	series  map[string]*Histogram
+	values  map[string][]string
+	mutex   sync.Mutex
+}
+
+// NewHistogramVec регистрирует семейство гистограмм с заданными верхними границами корзин
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: sorted,
+		series:  make(map[string]*Histogram),
+		values:  make(map[string][]string),
+	}
+	r.register(h)
+	return h
+}
+
+// WithLabelValues возвращает гистограмму для заданных значений меток
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	checkLabels(h.desc, values)
+	key := seriesKey(values)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	histogram, exists := h.series[key]
+	if !exists {
+		histogram = &Histogram{buckets: h.buckets, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = histogram
+		h.values[key] = append([]string(nil), values...)
+	}
+	return histogram
+}
+
+// write записывает семейство гистограмм
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+
+	h.mutex.Lock()
+	keys := sortedKeys(h.series)
+	for _, key := range keys {
+		values := h.values[key]
+		counts, sum, count := h.series[key].snapshot()
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", formatFloat(upper)), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, values), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, values), count)
+	}
+	h.mutex.Unlock()
+}
+
+// gaugeFunc — датчик, значения которого вычисляются при каждом сборе
+type gaugeFunc struct {
+	desc
+	fn func() map[string]float64
+}
+
+// NewGaugeFunc регистрирует датчик без меток, значение которого возвращает fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{
+		desc: desc{name: name, help: help, kind: "gauge"},
+		fn: func() map[string]float64 {
+			return map[string]float64{"": fn()}
+		},
+	})
+}
+
+// NewGaugeVecFunc регистрирует датчик с одной меткой: fn возвращает значения по значению метки
+func (r *Registry) NewGaugeVecFunc(name, help, label string, fn func() map[string]float64) {
+	r.register(&gaugeFunc{
+		desc: desc{name: name, help: help, kind: "gauge", labels: []string{label}},
+		fn:   fn,
+	})
+}
+
+// write записывает значения датчика
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+
+	values := g.fn()
+	keys := sortedKeys(values)
+	for _, key := range keys {
+		labels := ""
+		if len(g.labels) > 0 {
+			labels = labelString(g.labels, []string{key})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatFloat(values[key]))
+	}
+}
+
+// sortedKeys возвращает ключи карты в отсортированном порядке
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
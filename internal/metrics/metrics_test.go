@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRegistry_WriteText проверяет текстовый формат экспозиции Prometheus
+func TestRegistry_WriteText(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounterVec("test_requests_total", "Обработанные запросы.", "method", "path")
+	requests.WithLabelValues("POST", "/b").Add(2)
+	requests.WithLabelValues("GET", `/a"\`+"\n").Inc()
+
+	latency := registry.NewHistogramVec("test_latency_seconds", "Задержка.", []float64{1, 0.1}, "op")
+	latency.WithLabelValues("ADD").Observe(0.05)
+	latency.WithLabelValues("ADD").Observe(0.5)
+	latency.WithLabelValues("ADD").Observe(3)
+
+	registry.NewGaugeFunc("test_queue_depth", "Глубина очереди.", func() float64 { return 7 })
+	registry.NewGaugeVecFunc("test_items", "Элементы по статусу.", "status", func() map[string]float64 {
+		return map[string]float64{"PENDING": 1, "ERROR": 0}
+	})
+
+	var b strings.Builder
+	if err := registry.WriteText(&b); err != nil {
+		t.Fatalf("Ошибка записи метрик: %v", err)
+	}
+
+	expected := `# HELP test_requests_total Обработанные запросы.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/a\"\\\n"} 1
+test_requests_total{method="POST",path="/b"} 2
+# HELP test_latency_seconds Задержка.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{op="ADD",le="0.1"} 1
+test_latency_seconds_bucket{op="ADD",le="1"} 2
+test_latency_seconds_bucket{op="ADD",le="+Inf"} 3
+test_latency_seconds_sum{op="ADD"} 3.55
+test_latency_seconds_count{op="ADD"} 3
+# HELP test_queue_depth Глубина очереди.
+# TYPE test_queue_depth gauge
+test_queue_depth 7
+# HELP test_items Элементы по статусу.
+# TYPE test_items gauge
+test_items{status="ERROR"} 0
+test_items{status="PENDING"} 1
+`
+	if b.String() != expected {
+		t.Errorf("Неожиданный вывод:\n%s\nожидалось:\n%s", b.String(), expected)
+	}
+}
+
+// TestRegistry_Handler проверяет заголовок Content-Type эндпоинта /metrics
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "Счетчик.").WithLabelValues().Inc()
+
+	rr := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Неожиданный Content-Type: %s", ct)
+	}
+	if !strings.Contains(rr.Body.String(), "\ntest_total 1\n") {
+		t.Errorf("Счетчик без меток не найден:\n%s", rr.Body.String())
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+)
+
+// RequireUser пропускает только запросы с действительным JWT в заголовке Authorization
+// и помещает аутентифицированного пользователя в контекст запроса. Пользователь из токена
+// должен существовать в users с тем же логином: после перезапуска хранилище выдает ID заново,
+// и токен, выпущенный до перезапуска, не должен открывать доступ к чужой учетной записи.
+func RequireUser(tokens *auth.TokenIssuer, users *auth.UserStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Запрос уже аутентифицирован API-ключом
+			if _, ok := auth.APIKeyFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			claims, err := tokens.Parse(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			user, err := claims.User()
+			if err != nil {
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+			if stored, ok := users.Lookup(user.ID); !ok || stored.Login != user.Login {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// APIKeyHeader — заголовок, в котором клиент передаёт API-ключ.
+const APIKeyHeader = "X-API-Key"
+
+// AuthenticateAPIKey проверяет API-ключ из заголовка X-API-Key и помещает его в контекст
+// вместе с пользователем-владельцем. Запросы без заголовка пропускаются без изменений.
+func AuthenticateAPIKey(keys *auth.APIKeyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := r.Header.Get(APIKeyHeader)
+			if secret == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, ok := keys.Lookup(secret)
+			if !ok {
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrInvalidAPIKey)
+				return
+			}
+
+			ctx := auth.WithAPIKey(r.Context(), key)
+			if key.OwnerID != 0 {
+				ctx = auth.WithUser(ctx, auth.User{ID: key.OwnerID})
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireSecret пропускает только запросы, предъявившие общий секрет в заголовке
+// Authorization: Bearer <secret>.
+func RequireSecret(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				errors.WriteErrorResponse(w, http.StatusUnauthorized, errors.ErrUnauthorized)
+				return
+			}
+
+			if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+				errors.WriteErrorResponse(w, http.StatusForbidden, errors.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken извлекает токен из заголовка Authorization: Bearer <token>.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
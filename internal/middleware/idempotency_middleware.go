@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+)
+
+// IdempotencyKeyHeader — заголовок, в котором клиент передаёт ключ идемпотентности.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBodySize ограничивает размер тела запроса, для которого считается хеш.
+const maxIdempotentBodySize = 10 << 20
+
+// idempotencyEntry хранит сохранённый ответ на запрос с ключом идемпотентности.
+type idempotencyEntry struct {
+	bodyHash   [sha256.Size]byte
+	statusCode int
+	header     http.Header
+	body       []byte
+	done       bool
+	expiresAt  time.Time
+}
+
+// IdempotencyStore хранит ответы на запросы с ключами идемпотентности в течение заданного окна.
+type IdempotencyStore struct {
+	ttl       time.Duration
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+	mutex     sync.Mutex
+	now       func() time.Time
+}
+
+// NewIdempotencyStore создаёт хранилище ключей идемпотентности со временем жизни ttl.
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// IdempotencyMiddleware повторно возвращает сохранённый ответ для POST-запросов с тем же
+// ключом Idempotency-Key и телом. Тот же ключ с другим телом отклоняется с кодом 409.
+// Ключи разных пользователей и API-ключей не пересекаются, поэтому middleware
+// подключается после аутентификации.
+func IdempotencyMiddleware(store *IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Читаем тело для вычисления хеша и восстанавливаем его для обработчика
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				errors.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, errors.ErrTooLargeBody)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Ключ действует в пределах отправителя, метода и пути
+			scopedKey := principal(r) + " " + r.Method + " " + r.URL.Path + " " + key
+			entry, found := store.begin(scopedKey, sha256.Sum256(body))
+			if found {
+				switch {
+				case entry == nil:
+					errors.WriteErrorResponse(w, http.StatusConflict, errors.ErrIdempotencyMismatch)
+				case !entry.done:
+					errors.WriteErrorResponse(w, http.StatusConflict, errors.ErrIdempotencyInProgress)
+				default:
+					replayResponse(w, entry)
+				}
+				return
+			}
+
+			// Выполняем запрос, одновременно сохраняя ответ
+			crw := &capturingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(crw, r)
+			store.finish(scopedKey, crw)
+		})
+	}
+}
+
+// principal возвращает отправителя запроса: API-ключ, пользователя или пустую строку,
+// если аутентификация отключена.
+func principal(r *http.Request) string {
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		return "key:" + strconv.Itoa(key.ID)
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	return ""
+}
+
+// begin резервирует ключ для нового запроса. Если ключ уже известен, возвращает
+// found=true и сохранённую запись, либо nil, если тело запроса отличается.
+func (s *IdempotencyStore) begin(key string, bodyHash [sha256.Size]byte) (*idempotencyEntry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if entry, exists := s.entries[key]; exists && now.Before(entry.expiresAt) {
+		if entry.bodyHash != bodyHash {
+			return nil, true
+		}
+		snapshot := *entry
+		return &snapshot, true
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		bodyHash:  bodyHash,
+		expiresAt: now.Add(s.ttl),
+	}
+	return nil, false
+}
+
+// finish сохраняет ответ на запрос. Сохраняются только успешные ответы и 422 —
+// окончательный результат разбора выражения. Остальные ответы (квота, частота запросов,
+// ошибки сервера) не сохраняются, чтобы клиент мог повторить запрос с тем же ключом.
+func (s *IdempotencyStore) finish(key string, crw *capturingResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	success := crw.statusCode >= 200 && crw.statusCode < 300
+	if !success && crw.statusCode != http.StatusUnprocessableEntity {
+		delete(s.entries, key)
+		return
+	}
+
+	entry, exists := s.entries[key]
+	if !exists {
+		return
+	}
+	entry.statusCode = crw.statusCode
+	entry.header = crw.Header().Clone()
+	entry.body = crw.body.Bytes()
+	entry.done = true
+}
+
+// sweep удаляет просроченные записи не чаще одного раза за окно ttl.
+// Вызывается под блокировкой мьютекса.
+func (s *IdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// replayResponse отправляет клиенту сохранённый ответ.
+func replayResponse(w http.ResponseWriter, entry *idempotencyEntry) {
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(entry.statusCode)
+	w.Write(entry.body)
+}
+
+// capturingResponseWriter оборачивает http.ResponseWriter для сохранения статуса и тела ответа.
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader сохраняет статус код ответа и передаёт его далее.
+func (crw *capturingResponseWriter) WriteHeader(code int) {
+	crw.statusCode = code
+	crw.ResponseWriter.WriteHeader(code)
+}
+
+// Write сохраняет тело ответа и передаёт его далее.
+func (crw *capturingResponseWriter) Write(b []byte) (int, error) {
+	crw.body.Write(b)
+	return crw.ResponseWriter.Write(b)
+}
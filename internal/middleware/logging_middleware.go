@@ -1,13 +1,20 @@
 package middleware
 
 import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
 )
 
 // LoggingMiddleware логирует все входящие HTTP-запросы с информацией о методе, URL, статусе и времени обработки.
-func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+// Запросы к quietPaths (например, частые опросы агентов) логируются на уровне debug.
+func LoggingMiddleware(logger *zap.Logger, quietPaths ...string) func(http.Handler) http.Handler {
+	quiet := make(map[string]bool, len(quietPaths))
+	for _, path := range quietPaths {
+		quiet[path] = true
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -18,7 +25,12 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 
 			duration := time.Since(start)
 
-			logger.Info("Request",
+			level := zap.InfoLevel
+			if quiet[r.URL.Path] {
+				level = zap.DebugLevel
+			}
+
+			logging.FromContext(r.Context(), logger).Log(level, "Request",
 				zap.String("method", r.Method),
 				zap.String("url", r.URL.String()),
 				zap.Int("status", lrw.statusCode),
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+)
+
+// MetricsMiddleware измеряет длительность HTTP-запросов по методу, маршруту и статусу ответа.
+// Метрика называется <namespace>_http_request_duration_seconds. По mux определяется,
+// зарегистрирован ли маршрут запроса.
+func MetricsMiddleware(registry *metrics.Registry, namespace string, mux *http.ServeMux) func(http.Handler) http.Handler {
+	durations := registry.NewHistogramVec(namespace+"_http_request_duration_seconds",
+		"Длительность обработки HTTP-запросов.", metrics.DefaultBuckets, "method", "route", "status")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			_, pattern := mux.Handler(r)
+
+			lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(lrw, r)
+
+			durations.WithLabelValues(r.Method, RouteLabel(r.URL.Path, pattern, lrw.statusCode), strconv.Itoa(lrw.statusCode)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// RouteLabel приводит путь запроса к шаблону маршрута, чтобы число серий метрик было ограничено.
+// Числовые сегменты пути заменяются на {id}. Для незарегистрированных путей возвращается "unmatched",
+// для ответов 404 и статических файлов — шаблон маршрута из mux.
+func RouteLabel(path, pattern string, status int) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if status == http.StatusNotFound || strings.HasPrefix(path, "/static/") {
+		return pattern
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
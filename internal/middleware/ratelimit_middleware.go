@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+)
+
+// RateLimitMiddleware ограничивает частоту запросов маркерной корзиной на каждый IP-адрес
+// и на каждый API-ключ. Любой из ограничителей может быть nil. При превышении возвращает 429
+// с заголовком Retry-After; состояние корзины передаётся в заголовках X-RateLimit-*.
+func RateLimitMiddleware(perIP, perKey *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if perIP != nil {
+				result := perIP.Allow("ip:" + clientIP(r))
+				if !checkRateLimit(w, result) {
+					return
+				}
+			}
+
+			if key, ok := auth.APIKeyFromContext(r.Context()); ok && perKey != nil {
+				result := perKey.Allow("key:" + strconv.Itoa(key.ID))
+				if !checkRateLimit(w, result) {
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteRateLimitHeaders устанавливает заголовки X-RateLimit-* и, при отказе, Retry-After.
+func WriteRateLimitHeaders(w http.ResponseWriter, limit, remaining int, reset, retryAfter time.Duration) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	}
+}
+
+// checkRateLimit записывает заголовки ограничителя и отвечает 429, если запрос запрещён.
+func checkRateLimit(w http.ResponseWriter, result ratelimit.Result) bool {
+	WriteRateLimitHeaders(w, result.Limit, result.Remaining, result.Reset, result.RetryAfter)
+	if !result.Allowed {
+		errors.WriteErrorResponse(w, http.StatusTooManyRequests, errors.ErrRateLimitExceeded)
+		return false
+	}
+	return true
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд.
+func ceilSeconds(d time.Duration) int {
+	if d >= time.Duration(math.MaxInt64) {
+		return math.MaxInt32
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// clientIP возвращает IP-адрес клиента из адреса соединения.
+// Заголовки прокси не учитываются, чтобы клиент не мог подменить адрес.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+)
+
+// maxRequestIDLength ограничивает длину идентификатора запроса, принятого от клиента
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware берет идентификатор запроса из заголовка X-Request-ID или генерирует новый,
+// сохраняет его в контексте запроса и возвращает в заголовке ответа.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+
+		w.Header().Set(logging.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID проверяет, что идентификатор не пуст, не слишком длинный
+// и состоит только из печатаемых ASCII-символов.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
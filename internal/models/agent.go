@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AgentInfo представляет агента, обращавшегося к оркестратору или выполняющего задачи
+type AgentInfo struct {
+	ID       string     `json:"id"`
+	LastSeen *time.Time `json:"last_seen,omitempty"` // Время последнего запроса (нет у встроенных агентов)
+	Active   bool       `json:"active"`              // Агент обращался к оркестратору в пределах окна активности или выполняет задачи
+	Tasks    int        `json:"tasks"`               // Количество выполняемых задач
+}
+
+// AgentsResponse представляет ответ со списком агентов
+type AgentsResponse struct {
+	Agents []AgentInfo `json:"agents"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BatchItemRequest представляет одно выражение в пакетном запросе
+type BatchItemRequest struct {
+	CorrelationID string `json:"correlation_id"` // Идентификатор, заданный клиентом
+	Expression    string `json:"expression"`
+}
+
+// BatchItem представляет результат приема одного выражения из пакета
+type BatchItem struct {
+	CorrelationID string `json:"correlation_id"`
+	ExpressionID  int    `json:"id,omitempty"`    // ID созданного выражения
+	Error         string `json:"error,omitempty"` // Ошибка разбора выражения
+}
+
+// Batch представляет пакет выражений, принятых одним запросом
+type Batch struct {
+	ID        int         `json:"id"`
+	Items     []BatchItem `json:"items"`
+	OwnerID   int         `json:"-"` // ID пользователя-владельца (0 — без владельца)
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// BatchResponse представляет ответ на пакетную отправку выражений
+type BatchResponse struct {
+	BatchID int         `json:"batch_id"`
+	Items   []BatchItem `json:"items"`
+}
+
+// BatchItemStatus представляет текущее состояние выражения из пакета
+type BatchItemStatus struct {
+	CorrelationID string  `json:"correlation_id"`
+	ExpressionID  int     `json:"id,omitempty"`
+	Status        Status  `json:"status,omitempty"`
+	Result        *string `json:"result,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// BatchStatusResponse представляет агрегированный статус пакета
+type BatchStatusResponse struct {
+	ID       int               `json:"id"`
+	Status   Status            `json:"status"`   // PROCESSING, пока есть незавершенные выражения
+	Total    int               `json:"total"`    // Всего выражений в пакете
+	Rejected int               `json:"rejected"` // Отклонено при разборе
+	Counts   map[Status]int    `json:"counts"`   // Количество выражений по статусам
+	Items    []BatchItemStatus `json:"items"`
+}
+
+// DecodeBatchItems читает выражения пакета из JSON-массива или потока NDJSON
+func DecodeBatchItems(r io.Reader) ([]BatchItemRequest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("пустой пакет")
+	}
+
+	if data[0] == '[' {
+		var items []BatchItemRequest
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+
+	items := make([]BatchItemRequest, 0)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var item BatchItemRequest
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
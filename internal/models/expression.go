@@ -1,17 +1,33 @@
 package models
 
+import "time"
+
 // Expression определяет текущий статус выражения
 type Expression struct {
-	ID       int     `json:"id"`                   // Уникальный идентификатор выражения
-	RawExpr  string  `json:"expression,omitempty"` // Исходное строковое выражение
-	Status   Status  `json:"status"`               // Текущий статус вычисления
-	Result   *string `json:"result,omitempty"`     // Результат вычисления (nil, если не вычислено)
-	ErrorMsg string  `json:"error,omitempty"`      // Сообщение об ошибке (если статус ERROR)
+	ID          int        `json:"id"`                     // Уникальный идентификатор выражения
+	RawExpr     string     `json:"expression,omitempty"`   // Исходное строковое выражение
+	Status      Status     `json:"status"`                 // Текущий статус вычисления
+	Result      *string    `json:"result,omitempty"`       // Результат вычисления (nil, если не вычислено)
+	ErrorMsg    string     `json:"error,omitempty"`        // Сообщение об ошибке (если статус ERROR или CANCELLED)
+	CreatedAt   time.Time  `json:"created_at"`             // Время добавления выражения
+	StartedAt   *time.Time `json:"started_at,omitempty"`   // Время выдачи первой задачи агенту
+	CompletedAt *time.Time `json:"completed_at,omitempty"` // Время перехода в окончательный статус
+	OwnerID     int        `json:"-"`                      // ID пользователя-владельца (0 — без владельца)
+}
+
+// Duration возвращает время от добавления до завершения выражения.
+// Для незавершенных выражений возвращает false
+func (e Expression) Duration() (time.Duration, bool) {
+	if e.CompletedAt == nil {
+		return 0, false
+	}
+	return e.CompletedAt.Sub(e.CreatedAt), true
 }
 
 // ExpressionRequest представляет запрос на добавление выражения
 type ExpressionRequest struct {
-	Expression string `json:"expression"`
+	Expression  string `json:"expression"`
+	CallbackURL string `json:"callback_url,omitempty"` // Адрес для уведомления о завершении
 }
 
 // ExpressionResponse представляет ответ с ID добавленного выражения
@@ -22,6 +38,27 @@ type ExpressionResponse struct {
 // ExpressionsResponse представляет ответ со списком выражений
 type ExpressionsResponse struct {
 	Expressions []Expression `json:"expressions"`
+	NextCursor  string       `json:"next_cursor,omitempty"` // Курсор следующей страницы
+}
+
+// Поля сортировки списка выражений
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "created_at"
+	SortByDuration  = "duration"
+)
+
+// ExpressionQuery описывает фильтрацию, сортировку и пагинацию списка выражений
+type ExpressionQuery struct {
+	OwnerID       int       // ID владельца (0 — любые выражения)
+	Statuses      []Status  // Допустимые статусы (пусто — любые)
+	CreatedAfter  time.Time // Нижняя граница времени добавления (включительно)
+	CreatedBefore time.Time // Верхняя граница времени добавления (не включительно)
+	Search        string    // Подстрока исходного выражения
+	SortBy        string    // Поле сортировки
+	Descending    bool      // Сортировка по убыванию
+	Limit         int       // Максимальный размер страницы, 0 — без ограничения
+	Cursor        string    // Курсор, полученный с предыдущей страницей
 }
 
 // ExpressionDetailResponse представляет ответ с деталями выражения
@@ -9,4 +9,10 @@ const (
 	StatusProcessing Status = "PROCESSING" // В процессе выполнения
 	StatusCompleted  Status = "COMPLETED"  // Вычисление завершено
 	StatusError      Status = "ERROR"      // Ошибка при вычислении
+	StatusCancelled  Status = "CANCELLED"  // Выражение отменено пользователем
 )
+
+// IsTerminal сообщает, является ли статус окончательным
+func (s Status) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusError || s == StatusCancelled
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Operation представляет тип операции в задаче
 type Operation string
 
@@ -11,6 +13,12 @@ const (
 	OperationDivide   Operation = "DIVIDE"
 )
 
+// AgentIDHeader — заголовок, которым агент сообщает свой идентификатор оркестратору
+const AgentIDHeader = "X-Agent-ID"
+
+// QueueDepthHeader — заголовок ответа на запрос задачи с количеством готовых задач в очереди оркестратора
+const QueueDepthHeader = "X-Queue-Depth"
+
 // Task представляет задачу на выполнение одной операции
 type Task struct {
 	ID            int       `json:"id"`                      // Уникальный идентификатор задачи
@@ -20,8 +28,14 @@ type Task struct {
 	Operation     Operation `json:"operation"`               // Операция
 	OperationTime int       `json:"operation_time"`          // Время выполнения в миллисекундах
 	Result        *float64  `json:"result,omitempty"`        // Результат выполнения
+	TraceParent   string    `json:"traceparent,omitempty"`   // Контекст трассировки W3C
 	Dependencies  []int     `json:"-"`                       // Зависимости от других задач
 	IsReady       bool      `json:"-"`                       // Готовность к выполнению
+	ReadyAt       time.Time `json:"-"`                       // Момент, когда задача стала готовой
+	IssuedAt      time.Time `json:"-"`                       // Момент выдачи задачи агенту
+	LeasedUntil   time.Time `json:"-"`                       // Срок, до которого задача закреплена за агентами
+	Leases        []string  `json:"-"`                       // Агенты, которым выдана задача
+	Released      []string  `json:"-"`                       // Агенты, у которых задача отозвана; их результат еще принимается
 }
 
 // TaskResponse представляет запрос на добавление задачи
@@ -35,3 +49,33 @@ type TaskResultRequest struct {
 	Result float64 `json:"result"`
 	Error  string  `json:"error,omitempty"`
 }
+
+// TaskState описывает стадию выполнения задачи в графе вычисления выражения
+type TaskState string
+
+// Стадии выполнения задачи
+const (
+	TaskWaiting TaskState = "WAITING" // Ожидает результатов зависимостей
+	TaskReady   TaskState = "READY"   // Готова к выдаче агенту
+	TaskRunning TaskState = "RUNNING" // Выполняется агентом
+	TaskDone    TaskState = "DONE"    // Результат получен
+	TaskSkipped TaskState = "SKIPPED" // Не будет выполнена: выражение завершилось ошибкой или отменено
+)
+
+// TaskInfo представляет задачу выражения со стадией выполнения. Аргументы вида res:N
+// ссылаются на результат задачи N
+type TaskInfo struct {
+	ID           int       `json:"id"`
+	Arg1         string    `json:"arg1"`
+	Arg2         string    `json:"arg2"`
+	Operation    Operation `json:"operation"`
+	Dependencies []int     `json:"dependencies"`     // Задачи, результаты которых нужны для выполнения
+	State        TaskState `json:"state"`            // Стадия выполнения
+	Result       *float64  `json:"result,omitempty"` // Результат выполнения
+	Agents       []string  `json:"agents,omitempty"` // Агенты, которым выдана задача
+}
+
+// ExpressionTasksResponse представляет ответ с задачами выражения
+type ExpressionTasksResponse struct {
+	Tasks []TaskInfo `json:"tasks"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Типы событий, доставляемых через webhook
+const (
+	EventExpressionCompleted = "expression.completed"
+	EventExpressionFailed    = "expression.failed"
+	EventExpressionCancelled = "expression.cancelled"
+)
+
+// WebhookEvent представляет уведомление о завершении выражения
+type WebhookEvent struct {
+	Event      string     `json:"event"`      // Тип события
+	Expression Expression `json:"expression"` // Состояние выражения на момент события
+	Timestamp  time.Time  `json:"timestamp"`  // Время формирования события
+}
+
+// WebhookDelivery представляет одну попытку доставки уведомления
+type WebhookDelivery struct {
+	Attempt    int       `json:"attempt"`               // Номер попытки, начиная с 1
+	URL        string    `json:"url"`                   // Адрес получателя
+	Event      string    `json:"event"`                 // Тип события
+	StatusCode int       `json:"status_code,omitempty"` // Код ответа получателя
+	Error      string    `json:"error,omitempty"`       // Ошибка доставки
+	Success    bool      `json:"success"`               // Успешность попытки
+	Timestamp  time.Time `json:"timestamp"`             // Время попытки
+}
+
+// WebhookDeliveriesResponse представляет журнал доставок для выражения
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
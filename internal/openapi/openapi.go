@@ -0,0 +1,347 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document — описание API в формате OpenAPI 3. Разбираются только части, нужные для проверки
+// ответов: пути, операции, ответы и схемы
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Operation — операция над путем
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Response — описание ответа с определенным кодом
+type Response struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType — схема тела ответа для одного типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema — подмножество JSON Schema, используемое в описании API
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"` // false, true или схема
+	Items                *Schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+}
+
+// methods — HTTP-методы, которые могут быть ключами элемента paths
+var methods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+// Load разбирает описание API и проверяет, что все ссылки на схемы разрешаются
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("некорректное описание API: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("неподдерживаемая версия OpenAPI: %q", doc.OpenAPI)
+	}
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if !slices.Contains(methods, method) {
+				continue
+			}
+			for code, resp := range op.Responses {
+				for _, media := range resp.Content {
+					if err := doc.checkRefs(media.Schema); err != nil {
+						return nil, fmt.Errorf("%s %s, ответ %s: %w", strings.ToUpper(method), path, code, err)
+					}
+				}
+			}
+		}
+	}
+	return &doc, nil
+}
+
+// checkRefs проверяет, что ссылки в схеме и вложенных схемах указывают на существующие схемы
+func (d *Document) checkRefs(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		_, err := d.resolve(schema)
+		return err
+	}
+	for _, prop := range schema.Properties {
+		if err := d.checkRefs(prop); err != nil {
+			return err
+		}
+	}
+	if additional, ok := schema.additional(); ok {
+		if err := d.checkRefs(additional); err != nil {
+			return err
+		}
+	}
+	return d.checkRefs(schema.Items)
+}
+
+// resolve возвращает схему, на которую ссылается $ref, или саму схему
+func (d *Document) resolve(schema *Schema) (*Schema, error) {
+	if schema.Ref == "" {
+		return schema, nil
+	}
+	name, found := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+	if !found {
+		return nil, fmt.Errorf("неподдерживаемая ссылка %q", schema.Ref)
+	}
+	target, ok := d.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("схема %q не описана", name)
+	}
+	return target, nil
+}
+
+// Operations возвращает все операции описания в виде "GET /path", отсортированными
+func (d *Document) Operations() []string {
+	ops := make([]string, 0)
+	for path, item := range d.Paths {
+		for method := range item {
+			if slices.Contains(methods, method) {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// FindOperation находит операцию для метода и пути запроса. Возвращает ключ вида "GET /path"
+// с шаблоном пути из описания
+func (d *Document) FindOperation(method, path string) (Operation, string, error) {
+	for template, item := range d.Paths {
+		if !matchPath(template, path) {
+			continue
+		}
+		op, ok := item[strings.ToLower(method)]
+		if !ok {
+			return Operation{}, "", fmt.Errorf("метод %s для %s не описан", method, template)
+		}
+		return op, method + " " + template, nil
+	}
+	return Operation{}, "", fmt.Errorf("путь %s не описан", path)
+}
+
+// matchPath сравнивает путь запроса с шаблоном вида /api/v1/expressions/{id}
+func matchPath(template, path string) bool {
+	tparts := strings.Split(strings.Trim(template, "/"), "/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(tparts) != len(parts) {
+		return false
+	}
+	for i, tpart := range tparts {
+		if strings.HasPrefix(tpart, "{") && strings.HasSuffix(tpart, "}") {
+			continue
+		}
+		if tpart != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateResponse проверяет, что ответ на запрос method path описан и соответствует схеме:
+// код ответа, тип содержимого и JSON-тело. Возвращает ключ проверенной операции
+func (d *Document) ValidateResponse(method, path string, resp *http.Response, body []byte) (string, error) {
+	op, key, err := d.FindOperation(method, path)
+	if err != nil {
+		return "", err
+	}
+
+	code := strconv.Itoa(resp.StatusCode)
+	spec, ok := op.Responses[code]
+	if !ok {
+		spec, ok = op.Responses["default"]
+	}
+	if !ok {
+		return key, fmt.Errorf("%s: код ответа %s не описан", key, code)
+	}
+
+	if len(spec.Content) == 0 {
+		if len(bytes.TrimSpace(body)) != 0 {
+			return key, fmt.Errorf("%s %s: ожидался пустой ответ, получено %q", key, code, body)
+		}
+		return key, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	media, ok := spec.Content[mediaType]
+	if !ok {
+		return key, fmt.Errorf("%s %s: тип содержимого %q не описан", key, code, mediaType)
+	}
+	if media.Schema == nil || mediaType != "application/json" {
+		return key, nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return key, fmt.Errorf("%s %s: некорректный JSON: %w", key, code, err)
+	}
+	if err := d.Validate(media.Schema, value); err != nil {
+		return key, fmt.Errorf("%s %s: %w", key, code, err)
+	}
+	return key, nil
+}
+
+// Validate проверяет значение, декодированное из JSON с UseNumber, по схеме
+func (d *Document) Validate(schema *Schema, value any) error {
+	return d.validate(schema, value, "$")
+}
+
+// validate проверяет значение по схеме; at — путь к значению для сообщения об ошибке
+func (d *Document) validate(schema *Schema, value any, at string) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: значение null не допускается", at)
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(v any) bool { return fmt.Sprint(v) == fmt.Sprint(value) }) {
+		return fmt.Errorf("%s: значение %v не входит в %v", at, value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object":
+		return d.validateObject(schema, value, at)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: ожидается массив", at)
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: ожидается строка", at)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: ожидается дата и время RFC 3339: %q", at, s)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: ожидается число", at)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: некорректное число %s", at, n)
+		}
+		if schema.Type == "integer" && f != math.Trunc(f) {
+			return fmt.Errorf("%s: ожидается целое число, получено %s", at, n)
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return fmt.Errorf("%s: значение %s меньше %v", at, n, *schema.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: ожидается логическое значение", at)
+		}
+	case "":
+		// Схема без типа допускает любое значение
+	default:
+		return fmt.Errorf("%s: неподдерживаемый тип схемы %q", at, schema.Type)
+	}
+	return nil
+}
+
+// validateObject проверяет обязательные, описанные и дополнительные свойства объекта
+func (d *Document) validateObject(schema *Schema, value any, at string) error {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: ожидается объект", at)
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s: отсутствует обязательное поле %q", at, name)
+		}
+	}
+
+	additional, allowed := schema.additional()
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldAt := at + "." + name
+		if prop, ok := schema.Properties[name]; ok {
+			if err := d.validate(prop, object[name], fieldAt); err != nil {
+				return err
+			}
+			continue
+		}
+		if !allowed {
+			return fmt.Errorf("%s: поле не описано", fieldAt)
+		}
+		if additional != nil {
+			if err := d.validate(additional, object[name], fieldAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// additional разбирает additionalProperties: возвращает схему дополнительных свойств (nil — любые)
+// и признак того, что дополнительные свойства допустимы
+func (s *Schema) additional() (*Schema, bool) {
+	raw := bytes.TrimSpace(s.AdditionalProperties)
+	switch {
+	case len(raw) == 0, bytes.Equal(raw, []byte("true")):
+		return nil, true
+	case bytes.Equal(raw, []byte("false")):
+		return nil, false
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, true
+	}
+	return &schema, true
+}
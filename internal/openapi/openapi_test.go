@@ -0,0 +1,92 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testDocument = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/items/{id}": {
+      "get": {
+        "responses": {
+          "200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+          "204": {"description": "Пусто"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["id", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer", "minimum": 1},
+          "status": {"type": "string", "enum": ["NEW", "DONE"]},
+          "created_at": {"type": "string", "format": "date-time"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "counts": {"type": "object", "additionalProperties": {"type": "integer"}}
+        }
+      }
+    }
+  }
+}`
+
+func TestDocument_ValidateResponse(t *testing.T) {
+	doc, err := Load([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"valid", "/items/1", 200, `{"id": 1, "status": "NEW", "created_at": "2025-01-02T03:04:05Z", "tags": ["a"], "counts": {"x": 2}}`, ""},
+		{"empty body", "/items/1", 204, ``, ""},
+		{"unknown path", "/other", 200, `{}`, "не описан"},
+		{"undocumented status", "/items/1", 500, `{}`, "код ответа 500 не описан"},
+		{"missing field", "/items/1", 200, `{"id": 1}`, `обязательное поле "created_at"`},
+		{"extra field", "/items/1", 200, `{"id": 1, "created_at": "2025-01-02T03:04:05Z", "name": "x"}`, "$.name: поле не описано"},
+		{"not integer", "/items/1", 200, `{"id": 1.5, "created_at": "2025-01-02T03:04:05Z"}`, "ожидается целое число"},
+		{"below minimum", "/items/1", 200, `{"id": 0, "created_at": "2025-01-02T03:04:05Z"}`, "меньше 1"},
+		{"not in enum", "/items/1", 200, `{"id": 1, "status": "LOST", "created_at": "2025-01-02T03:04:05Z"}`, "не входит"},
+		{"bad date", "/items/1", 200, `{"id": 1, "created_at": "yesterday"}`, "RFC 3339"},
+		{"null", "/items/1", 200, `{"id": 1, "created_at": "2025-01-02T03:04:05Z", "tags": null}`, "null не допускается"},
+		{"array item", "/items/1", 200, `{"id": 1, "created_at": "2025-01-02T03:04:05Z", "tags": [1]}`, "$.tags[0]: ожидается строка"},
+		{"additional properties", "/items/1", 200, `{"id": 1, "created_at": "2025-01-02T03:04:05Z", "counts": {"x": "2"}}`, "$.counts.x: ожидается число"},
+		{"unexpected body", "/items/1", 204, `{}`, "ожидался пустой ответ"},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		if tt.body != "" {
+			rr.Header().Set("Content-Type", "application/json")
+		}
+		rr.WriteHeader(tt.status)
+		rr.WriteString(tt.body)
+
+		_, err := doc.ValidateResponse(http.MethodGet, tt.path, rr.Result(), rr.Body.Bytes())
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoad_UnresolvedRef(t *testing.T) {
+	doc := strings.Replace(testDocument, "#/components/schemas/Item", "#/components/schemas/Missing", 1)
+	if _, err := Load([]byte(doc)); err == nil || !strings.Contains(err.Error(), `"Missing" не описана`) {
+		t.Errorf("expected unresolved reference error, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"time"
+)
+
+// Ошибки отмены выражений
+var (
+	ErrExpressionNotFound = errors.New("выражение не найдено")
+	ErrExpressionFinished = errors.New("выражение уже завершено")
+)
+
+// errCancelled — причина завершения отмененного выражения
+const errCancelled = "выражение отменено"
+
+// CancelExpression отменяет незавершенное выражение пользователя ownerID (0 — любого).
+// Выражение переходит в статус CANCELLED, его невыданные задачи больше не выдаются агентам,
+// а результаты уже выданных отклоняются как опоздавшие
+func (s *Storage) CancelExpression(id, ownerID int) (models.Expression, error) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	expr, exists := s.expressions[id]
+	if !exists || ownerID != 0 && expr.OwnerID != ownerID {
+		return models.Expression{}, ErrExpressionNotFound
+	}
+	if expr.Status.IsTerminal() {
+		return expr, ErrExpressionFinished
+	}
+
+	for _, taskID := range s.exprTasksMapping[id] {
+		task, exists := s.tasks[taskID]
+		if !exists || task.Result != nil {
+			continue
+		}
+		task.IsReady = false
+		s.tasks[taskID] = task
+
+		if span, exists := s.taskSpans[taskID]; exists {
+			span.SetError(errCancelled)
+			span.End()
+			delete(s.taskSpans, taskID)
+		}
+		delete(s.auditResults, taskID)
+	}
+
+	now := time.Now().UTC()
+	expr.Status = models.StatusCancelled
+	expr.ErrorMsg = errCancelled
+	expr.CompletedAt = &now
+	s.expressions[id] = expr
+	s.notifyCompletion(id)
+
+	s.logger.Info("Expression cancelled", logging.ExpressionID(id))
+	return expr, nil
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"testing"
+)
+
+func TestStorage_CancelExpression(t *testing.T) {
+	server, storage := newTestServer()
+
+	exprID, _ := storage.AddExpressionForOwner(1, "(1+2)*(3+4)")
+	tasks, _ := server.parser.ParseExpression("(1+2)*(3+4)")
+	storage.AddTasks(exprID, tasks)
+	issued, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Чужое выражение отменить нельзя
+	if _, err := storage.CancelExpression(exprID, 2); !errors.Is(err, ErrExpressionNotFound) {
+		t.Errorf("foreign cancel error = %v, want %v", err, ErrExpressionNotFound)
+	}
+
+	expr, err := storage.CancelExpression(exprID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Status != models.StatusCancelled || expr.ErrorMsg != errCancelled || expr.CompletedAt == nil {
+		t.Errorf("cancelled expression = %+v", expr)
+	}
+
+	// Невыданные задачи больше не выдаются, результат выданной отклоняется
+	if task, err := storage.GetReadyTask(); err == nil {
+		t.Errorf("task %+v issued after cancel", task)
+	}
+	if err := storage.UpdateTaskResult(issued.ID, 3, ""); !errors.Is(err, ErrLateResult) {
+		t.Errorf("late result error = %v, want %v", err, ErrLateResult)
+	}
+
+	if _, err := storage.CancelExpression(exprID, 1); !errors.Is(err, ErrExpressionFinished) {
+		t.Errorf("repeated cancel error = %v, want %v", err, ErrExpressionFinished)
+	}
+}
@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/openapi"
+	"github.com/mpkelevra23/arithmetic-web-service/web"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TestServer_OpenAPIContract выполняет запросы ко всем операциям из описания API
+// и проверяет реальные ответы обработчиков по схемам описания
+func TestServer_OpenAPIContract(t *testing.T) {
+	doc, err := openapi.Load(web.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, storage := newTestServer()
+	server.SetAuthenticator(auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret"))
+	server.SetMetrics(NewMetrics(metrics.NewRegistry(), storage))
+	server.SetNotifier(NewNotifier(storage, "secret"))
+	server.SetSweeper(NewSweeper(storage, RetentionPolicy{MaxAge: time.Hour}, 0))
+	server.SetLogLevel(zap.NewAtomicLevelAt(zap.InfoLevel))
+	handler := server.SetupRoutes()
+
+	covered := make(map[string]bool)
+	// call выполняет запрос, проверяет код и схему ответа и возвращает тело
+	call := func(method, path, authorization, body string, want int) []byte {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(models.AgentIDHeader, "agent-1")
+		if strings.HasPrefix(authorization, "key ") {
+			req.Header.Set(middleware.APIKeyHeader, strings.TrimPrefix(authorization, "key "))
+		} else if authorization != "" {
+			req.Header.Set("Authorization", "Bearer "+authorization)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != want {
+			t.Errorf("%s %s: status = %d, want %d: %s", method, path, rr.Code, want, rr.Body.String())
+		}
+		key, err := doc.ValidateResponse(method, req.URL.Path, rr.Result(), rr.Body.Bytes())
+		if err != nil {
+			t.Error(err)
+		}
+		covered[key] = true
+		return rr.Body.Bytes()
+	}
+	decode := func(data []byte, v any) {
+		t.Helper()
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("decode %s: %v", data, err)
+		}
+	}
+	const admin, agent = "admin-secret", "agent-secret"
+
+	// Учетные записи
+	var user auth.RegisterResponse
+	decode(call("POST", "/api/v1/register", "", `{"login": "alice", "password": "password123"}`, http.StatusCreated), &user)
+	call("POST", "/api/v1/register", "", `{"login": "alice", "password": "password123"}`, http.StatusConflict)
+	call("POST", "/api/v1/register", "", `{"login": "bob", "password": "short"}`, http.StatusUnprocessableEntity)
+	call("POST", "/api/v1/login", "", `{"login": "alice", "password": "wrong-password"}`, http.StatusUnauthorized)
+	var login auth.LoginResponse
+	decode(call("POST", "/api/v1/login", "", `{"login": "alice", "password": "password123"}`, http.StatusOK), &login)
+	token := login.Token
+
+	// Асинхронное вычисление
+	call("POST", "/api/v1/calculate", "", `{"expression": "2+2"}`, http.StatusUnauthorized)
+	call("POST", "/api/v1/calculate", token, `{"expression": ""}`, http.StatusUnprocessableEntity)
+	call("POST", "/api/v1/calculate?wait=never", token, `{"expression": "2+2"}`, http.StatusBadRequest)
+	call("POST", "/api/v1/calculate", token, `{"expression": "(2+3)*4"}`, http.StatusCreated)
+	call("POST", "/api/v1/calculate?wait=1ms", token, `{"expression": "1/0"}`, http.StatusAccepted)
+	call("POST", "/api/v1/calculate/batch", token, `[{"correlation_id": "a", "expression": "1+1"}, {"correlation_id": "b", "expression": "1+"}]`, http.StatusCreated)
+	call("POST", "/api/v1/calculate/batch", token, `{`, http.StatusUnprocessableEntity)
+
+	// Синхронное вычисление
+	call("POST", "/api/v1/evaluate", token, `{"expression": "2+2*2"}`, http.StatusOK)
+	call("POST", "/api/v1/evaluate", token, `{"expression": "1/0"}`, http.StatusUnprocessableEntity)
+	call("POST", "/api/v1/evaluate", token, `{`, http.StatusBadRequest)
+	call("POST", "/api/v1/evaluate/batch", token, `[{"correlation_id": "a", "expression": "2*3"}, {"correlation_id": "b", "expression": "x"}]`, http.StatusOK)
+
+	// Внутренний API агентов
+	call("GET", "/internal/task", "", "", http.StatusUnauthorized)
+	var task models.TaskResponse
+	decode(call("GET", "/internal/task", agent, "", http.StatusOK), &task)
+	result, _ := calcTask(task.Task)
+	call("POST", "/internal/task", agent, fmt.Sprintf(`{"id": %d, "result": %v}`, task.Task.ID, result), http.StatusOK)
+	call("POST", "/internal/task", agent, fmt.Sprintf(`{"id": %d, "result": %v}`, task.Task.ID, result), http.StatusOK)
+	call("POST", "/internal/task", agent, fmt.Sprintf(`{"id": %d, "result": %v}`, task.Task.ID, result+1), http.StatusConflict)
+	call("POST", "/internal/task", agent, `{"id": 999, "result": 1}`, http.StatusNotFound)
+	completeAllTasks(storage)
+	call("GET", "/internal/task", agent, "", http.StatusNotFound)
+
+	// Выражения и пакеты
+	call("GET", "/api/v1/expressions?status=completed,error&sort=duration&order=desc", token, "", http.StatusOK)
+	call("GET", "/api/v1/expressions?limit=0", token, "", http.StatusBadRequest)
+	call("GET", "/api/v1/expressions/1", token, "", http.StatusOK)
+	call("GET", "/api/v1/expressions/2", token, "", http.StatusOK)
+	call("GET", "/api/v1/expressions/x", token, "", http.StatusBadRequest)
+	call("GET", "/api/v1/expressions/999", token, "", http.StatusNotFound)
+	call("GET", "/api/v1/expressions/1/deliveries", token, "", http.StatusOK)
+	call("GET", "/api/v1/expressions/1/tasks", token, "", http.StatusOK)
+	call("GET", "/api/v1/batches/1", token, "", http.StatusOK)
+	call("GET", "/api/v1/batches/999", token, "", http.StatusNotFound)
+	var created models.ExpressionResponse
+	decode(call("POST", "/api/v1/calculate", token, `{"expression": "8/2"}`, http.StatusCreated), &created)
+	cancelPath := fmt.Sprintf("/api/v1/expressions/%d", created.ID)
+	call("DELETE", cancelPath, token, "", http.StatusOK)
+	call("DELETE", cancelPath, token, "", http.StatusConflict)
+	call("DELETE", "/api/v1/expressions/x", token, "", http.StatusBadRequest)
+	call("DELETE", "/api/v1/expressions/999", token, "", http.StatusNotFound)
+
+	// Административный API
+	call("GET", "/admin/v1/storage", "", "", http.StatusUnauthorized)
+	call("GET", "/admin/v1/storage", admin, "", http.StatusOK)
+	call("POST", "/admin/v1/retention/sweep", admin, "", http.StatusOK)
+	call("GET", "/admin/v1/operation-times", admin, "", http.StatusOK)
+	call("PUT", "/admin/v1/operation-times", admin, `{"addition_ms": 5}`, http.StatusOK)
+	call("PUT", "/admin/v1/operation-times", admin, `{"addition_ms": -5}`, http.StatusUnprocessableEntity)
+	call("GET", "/admin/v1/drain", admin, "", http.StatusOK)
+	call("PUT", "/admin/v1/drain", admin, `{"draining": false}`, http.StatusOK)
+	call("PUT", "/admin/v1/drain", admin, `{}`, http.StatusUnprocessableEntity)
+	call("GET", "/admin/v1/agents", admin, "", http.StatusOK)
+	call("GET", "/admin/v1/log-level", admin, "", http.StatusOK)
+	call("PUT", "/admin/v1/log-level", admin, `{"level": "debug"}`, http.StatusOK)
+	call("PUT", "/admin/v1/log-level", admin, `{"level": "loud"}`, http.StatusBadRequest)
+	call("POST", "/admin/v1/api-keys", admin, `{"name": "ci", "owner_id": 999}`, http.StatusUnprocessableEntity)
+	var key auth.IssueAPIKeyResponse
+	decode(call("POST", "/admin/v1/api-keys", admin, fmt.Sprintf(`{"name": "ci", "owner_id": %d}`, user.ID), http.StatusCreated), &key)
+	call("GET", "/admin/v1/api-keys", admin, "", http.StatusOK)
+	call("POST", "/api/v1/evaluate", "key "+key.Key, `{"expression": "7-2"}`, http.StatusOK)
+
+	// Проверки состояния, метрики и описание API
+	call("GET", "/healthz", "", "", http.StatusOK)
+	call("GET", "/readyz", "", "", http.StatusOK)
+	call("GET", "/version", "", "", http.StatusOK)
+	call("GET", "/metrics", "", "", http.StatusOK)
+	call("GET", "/api/openapi.json", "", "", http.StatusOK)
+
+	for _, op := range doc.Operations() {
+		if !covered[op] {
+			t.Errorf("operation %s is not covered by the contract test", op)
+		}
+	}
+}
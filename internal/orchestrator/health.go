@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/health"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// defaultAgentWindow — время, в течение которого агент считается активным после последнего запроса
+const defaultAgentWindow = 30 * time.Second
+
+// SetReadiness задает условие готовности: не меньше minAgents агентов обращались
+// к оркестратору за последние window. При minAgents = 0 агенты не проверяются
+func (s *Server) SetReadiness(minAgents int, window time.Duration) {
+	s.minAgents = minAgents
+	if window > 0 {
+		s.agentWindow = window
+	}
+}
+
+// recordAgent запоминает время обращения агента. Агент определяется по заголовку X-Agent-ID,
+// а без него — по IP-адресу
+func (s *Server) recordAgent(r *http.Request) {
+	id := r.Header.Get(models.AgentIDHeader)
+	if id == "" {
+		id, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+
+	s.agentsMutex.Lock()
+	defer s.agentsMutex.Unlock()
+
+	s.agents[id] = time.Now()
+}
+
+// ActiveAgents возвращает количество агентов, обращавшихся к оркестратору в пределах окна
+// активности. Давно не обращавшиеся агенты забываются
+func (s *Server) ActiveAgents() int {
+	s.agentsMutex.Lock()
+	defer s.agentsMutex.Unlock()
+
+	cutoff := time.Now().Add(-s.agentWindow)
+	for id, seen := range s.agents {
+		if seen.Before(cutoff) {
+			delete(s.agents, id)
+		}
+	}
+	return len(s.agents)
+}
+
+// Agents возвращает агентов, обращавшихся к оркестратору или выполняющих задачи, в порядке ID.
+// Встроенные агенты не обращаются к оркестратору по HTTP и видны, только пока выполняют задачи
+func (s *Server) Agents() []models.AgentInfo {
+	tasks := s.storage.AgentTasks()
+
+	s.agentsMutex.Lock()
+	byID := make(map[string]*models.AgentInfo, len(s.agents))
+	cutoff := time.Now().Add(-s.agentWindow)
+	for id, seen := range s.agents {
+		lastSeen := seen.UTC()
+		byID[id] = &models.AgentInfo{ID: id, LastSeen: &lastSeen, Active: seen.After(cutoff)}
+	}
+	s.agentsMutex.Unlock()
+
+	for id, count := range tasks {
+		info, exists := byID[id]
+		if !exists {
+			info = &models.AgentInfo{ID: id}
+			byID[id] = info
+		}
+		info.Tasks = count
+		info.Active = true
+	}
+
+	agents := make([]models.AgentInfo, 0, len(byID))
+	for _, info := range byID {
+		agents = append(agents, *info)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents
+}
+
+// readinessChecks возвращает проверки готовности оркестратора для /readyz
+func (s *Server) readinessChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "storage", Check: s.storage.Ping},
+		{Name: "draining", Check: func(context.Context) error {
+			if s.Draining() {
+				return fmt.Errorf("новые выражения не принимаются")
+			}
+			return nil
+		}},
+	}
+
+	if s.minAgents > 0 {
+		checks = append(checks, health.Check{Name: "agents", Check: func(context.Context) error {
+			if active := s.ActiveAgents(); active < s.minAgents {
+				return fmt.Errorf("активных агентов %d, требуется не меньше %d", active, s.minAgents)
+			}
+			return nil
+		}})
+	}
+
+	return checks
+}
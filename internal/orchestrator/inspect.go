@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExpressionTasks возвращает задачи выражения пользователя ownerID (0 — любого) в порядке ID
+// со стадиями выполнения
+func (s *Storage) ExpressionTasks(id, ownerID int) ([]models.TaskInfo, error) {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	expr, exists := s.expressions[id]
+	if !exists || ownerID != 0 && expr.OwnerID != ownerID {
+		return nil, ErrExpressionNotFound
+	}
+
+	infos := make([]models.TaskInfo, 0, len(s.exprTasksMapping[id]))
+	for _, taskID := range s.exprTasksMapping[id] {
+		task, exists := s.tasks[taskID]
+		if !exists {
+			continue
+		}
+		infos = append(infos, models.TaskInfo{
+			ID:           task.ID,
+			Arg1:         task.Arg1,
+			Arg2:         task.Arg2,
+			Operation:    task.Operation,
+			Dependencies: argDependencies(task),
+			State:        s.taskState(task, expr),
+			Result:       task.Result,
+			Agents:       slices.Clone(task.Leases),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
+}
+
+// argDependencies возвращает все задачи, на результаты которых ссылаются аргументы задачи.
+// В отличие от Dependencies, из которого удаляются выполненные зависимости, список не меняется
+// по ходу вычисления
+func argDependencies(task models.Task) []int {
+	deps := make([]int, 0, 2)
+	for _, arg := range []string{task.Arg1, task.Arg2} {
+		if ref, found := strings.CutPrefix(arg, "res:"); found {
+			if id, err := strconv.Atoi(ref); err == nil {
+				deps = append(deps, id)
+			}
+		}
+	}
+	return deps
+}
+
+// taskState определяет стадию выполнения задачи. Вызывается под блокировкой мьютекса
+func (s *Storage) taskState(task models.Task, expr models.Expression) models.TaskState {
+	switch {
+	case task.Result != nil:
+		return models.TaskDone
+	case expr.Status.IsTerminal():
+		return models.TaskSkipped
+	case task.IsReady:
+		return models.TaskReady
+	case !task.IssuedAt.IsZero():
+		return models.TaskRunning
+	default:
+		return models.TaskWaiting
+	}
+}
+
+// AgentTasks возвращает количество выполняемых задач каждого агента
+func (s *Storage) AgentTasks() map[string]int {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		if !s.isInFlight(task) {
+			continue
+		}
+		for _, agentID := range task.Leases {
+			counts[agentID]++
+		}
+	}
+	return counts
+}
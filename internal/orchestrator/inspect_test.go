@@ -0,0 +1,41 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"testing"
+)
+
+func TestStorage_ExpressionTasks(t *testing.T) {
+	server, storage := newTestServer()
+
+	exprID, _ := storage.AddExpressionForOwner(1, "(1+2)*(3+4)")
+	tasks, _ := server.parser.ParseExpression("(1+2)*(3+4)")
+	storage.AddTasks(exprID, tasks)
+
+	issued, _ := storage.GetReadyTaskForAgent("agent-1")
+	storage.AcceptTaskResult("agent-1", issued.ID, 0, "")
+	running, _ := storage.GetReadyTaskForAgent("agent-2")
+
+	infos, err := storage.ExpressionTasks(exprID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[int]models.TaskState)
+	for _, info := range infos {
+		states[info.ID] = info.State
+	}
+	if len(infos) != 3 || states[issued.ID] != models.TaskDone || states[running.ID] != models.TaskRunning {
+		t.Fatalf("tasks = %+v", infos)
+	}
+	if last := infos[2]; last.State != models.TaskWaiting || len(last.Dependencies) != 2 {
+		t.Errorf("root task = %+v, want WAITING with two dependencies", last)
+	}
+
+	if counts := storage.AgentTasks(); counts["agent-2"] != 1 || counts["agent-1"] != 0 {
+		t.Errorf("agent tasks = %v", counts)
+	}
+
+	if _, err := storage.ExpressionTasks(exprID, 2); err != ErrExpressionNotFound {
+		t.Errorf("foreign expression error = %v, want %v", err, ErrExpressionNotFound)
+	}
+}
@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"time"
+)
+
+// taskBuckets — границы гистограмм времени ожидания и выполнения задач (в секундах)
+var taskBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// lockBuckets — границы гистограммы ожидания мьютекса хранилища (в секундах)
+var lockBuckets = []float64{0.00001, 0.0001, 0.001, 0.01, 0.1, 1}
+
+// Metrics содержит метрики оркестратора
+type Metrics struct {
+	Registry *metrics.Registry
+
+	taskWait    *metrics.HistogramVec // Время от готовности задачи до выдачи агенту
+	taskExec    *metrics.HistogramVec // Время от выдачи задачи до получения результата
+	polls       *metrics.CounterVec   // Запросы задач агентами
+	cache       *metrics.CounterVec   // Обращения к кешу результатов
+	lockWait    *metrics.HistogramVec // Ожидание занятого мьютекса хранилища
+	taskResults *metrics.CounterVec   // Результаты задач, полученные от агентов
+	rejected    *metrics.CounterVec   // Отклоненные результаты задач
+	mismatches  *metrics.CounterVec   // Расхождения результатов агентов в режиме аудита
+}
+
+// NewMetrics регистрирует метрики оркестратора и датчики состояния хранилища
+func NewMetrics(registry *metrics.Registry, storage *Storage) *Metrics {
+	m := &Metrics{
+		Registry: registry,
+		taskWait: registry.NewHistogramVec("orchestrator_task_wait_seconds",
+			"Время ожидания готовой задачи в очереди до выдачи агенту.", taskBuckets, "operation"),
+		taskExec: registry.NewHistogramVec("orchestrator_task_execution_seconds",
+			"Время от выдачи задачи агенту до получения результата.", taskBuckets, "operation"),
+		polls: registry.NewCounterVec("orchestrator_agent_polls_total",
+			"Запросы задач агентами по результату (task или empty).", "result"),
+		cache: registry.NewCounterVec("orchestrator_result_cache_lookups_total",
+			"Обращения к кешу результатов задач по результату (hit или miss).", "result"),
+		lockWait: registry.NewHistogramVec("orchestrator_storage_lock_wait_seconds",
+			"Время ожидания занятого мьютекса хранилища (только при конкуренции).", lockBuckets, "mode"),
+		taskResults: registry.NewCounterVec("orchestrator_task_results_total",
+			"Результаты задач, полученные от агентов (ok или error).", "result"),
+		rejected: registry.NewCounterVec("orchestrator_task_results_rejected_total",
+			"Отклоненные результаты задач по причине (not_found, not_leased, duplicate, late или invalid).", "reason"),
+		mismatches: registry.NewCounterVec("orchestrator_audit_mismatches_total",
+			"Задачи, результаты которых у двух агентов разошлись в режиме аудита."),
+	}
+
+	registry.NewGaugeVecFunc("orchestrator_expressions",
+		"Количество выражений в хранилище по статусу.", "status", func() map[string]float64 {
+			stats := storage.Stats()
+			values := make(map[string]float64)
+			for _, status := range []models.Status{models.StatusPending, models.StatusProcessing, models.StatusCompleted, models.StatusError, models.StatusCancelled} {
+				values[string(status)] = float64(stats.ByStatus[status])
+			}
+			return values
+		})
+	registry.NewGaugeFunc("orchestrator_ready_queue_depth",
+		"Количество готовых задач, ожидающих выдачи агенту.", func() float64 {
+			return float64(storage.ReadyQueueDepth())
+		})
+
+	storage.SetMetrics(m)
+	return m
+}
+
+// observeTaskWait фиксирует время ожидания задачи в очереди
+func (m *Metrics) observeTaskWait(task models.Task, now time.Time) {
+	if m == nil || task.ReadyAt.IsZero() {
+		return
+	}
+	m.taskWait.WithLabelValues(string(task.Operation)).Observe(now.Sub(task.ReadyAt).Seconds())
+}
+
+// observeTaskExecution фиксирует время выполнения задачи агентом
+func (m *Metrics) observeTaskExecution(task models.Task, now time.Time, errorMsg string) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if errorMsg != "" {
+		result = "error"
+	}
+	m.taskResults.WithLabelValues(result).Inc()
+	if !task.IssuedAt.IsZero() {
+		m.taskExec.WithLabelValues(string(task.Operation)).Observe(now.Sub(task.IssuedAt).Seconds())
+	}
+}
+
+// rejectReasons — значения метки reason для ошибок приема результатов
+var rejectReasons = map[error]string{
+	ErrTaskNotFound:    "not_found",
+	ErrTaskNotLeased:   "not_leased",
+	ErrDuplicateResult: "duplicate",
+	ErrLateResult:      "late",
+	ErrInvalidResult:   "invalid",
+}
+
+// observeRejectedResult фиксирует отклоненный результат задачи
+func (m *Metrics) observeRejectedResult(err error) {
+	if m == nil {
+		return
+	}
+	m.rejected.WithLabelValues(rejectReasons[err]).Inc()
+}
+
+// observeAuditMismatch фиксирует расхождение результатов агентов
+func (m *Metrics) observeAuditMismatch() {
+	if m == nil {
+		return
+	}
+	m.mismatches.WithLabelValues().Inc()
+}
+
+// observePoll фиксирует запрос задачи агентом
+func (m *Metrics) observePoll(found bool) {
+	if m == nil {
+		return
+	}
+	if found {
+		m.polls.WithLabelValues("task").Inc()
+	} else {
+		m.polls.WithLabelValues("empty").Inc()
+	}
+}
+
+// observeCache фиксирует обращение к кешу результатов
+func (m *Metrics) observeCache(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		m.cache.WithLabelValues("hit").Inc()
+	} else {
+		m.cache.WithLabelValues("miss").Inc()
+	}
+}
+
+// observeLockWait фиксирует ожидание занятого мьютекса
+func (m *Metrics) observeLockWait(mode string, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.lockWait.WithLabelValues(mode).Observe(wait.Seconds())
+}
@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestServer_Metrics проверяет метрики оркестратора в формате Prometheus
+func TestServer_Metrics(t *testing.T) {
+	server, storage := newTestServer()
+	server.SetMetrics(NewMetrics(metrics.NewRegistry(), storage))
+	handler := server.SetupRoutes()
+
+	for _, body := range []string{`{"expression": "2+2*2"}`, `{"expression": "1+1"}`} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(body)))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Ожидался статус 201, получен %d", rr.Code)
+		}
+	}
+	completeAllTasks(storage)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/expressions/1", nil))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", rr.Code)
+	}
+	output := rr.Body.String()
+
+	for _, line := range []string{
+		`orchestrator_expressions{status="COMPLETED"} 2`,
+		`orchestrator_expressions{status="PENDING"} 0`,
+		`orchestrator_ready_queue_depth 0`,
+		`orchestrator_agent_polls_total{result="task"} 3`,
+		`orchestrator_agent_polls_total{result="empty"} 1`,
+		`orchestrator_task_results_total{result="ok"} 3`,
+		`orchestrator_task_wait_seconds_count{operation="ADD"} 2`,
+		`orchestrator_task_execution_seconds_count{operation="MULTIPLY"} 1`,
+		`orchestrator_http_request_duration_seconds_count{method="POST",route="/api/v1/calculate",status="201"} 2`,
+		`orchestrator_http_request_duration_seconds_count{method="GET",route="/api/v1/expressions/{id}",status="200"} 1`,
+		"# TYPE orchestrator_storage_lock_wait_seconds histogram",
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Строка %q не найдена в выводе метрик:\n%s", line, output)
+		}
+	}
+}
@@ -5,19 +5,29 @@ import (
 	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // OperationTimes хранит время выполнения операций в миллисекундах
 type OperationTimes struct {
-	Addition       int
-	Subtraction    int
-	Multiplication int
-	Division       int
+	Addition       int `json:"addition_ms"`
+	Subtraction    int `json:"subtraction_ms"`
+	Multiplication int `json:"multiplication_ms"`
+	Division       int `json:"division_ms"`
+}
+
+// Validate проверяет, что время выполнения операций не отрицательное
+func (t OperationTimes) Validate() error {
+	if t.Addition < 0 || t.Subtraction < 0 || t.Multiplication < 0 || t.Division < 0 {
+		return fmt.Errorf("время выполнения операции не может быть отрицательным")
+	}
+	return nil
 }
 
 // Parser представляет парсер арифметических выражений
 type Parser struct {
 	opTimes OperationTimes
+	mutex   sync.RWMutex // Защищает opTimes при изменении во время работы
 }
 
 // NewParser создает новый парсер
@@ -27,6 +37,23 @@ func NewParser(opTimes OperationTimes) *Parser {
 	}
 }
 
+// OperationTimes возвращает текущее время выполнения операций
+func (p *Parser) OperationTimes() OperationTimes {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.opTimes
+}
+
+// SetOperationTimes задает время выполнения операций для задач новых выражений.
+// Уже созданные задачи сохраняют прежнее время
+func (p *Parser) SetOperationTimes(opTimes OperationTimes) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.opTimes = opTimes
+}
+
 // Token представляет токен в выражении
 type Token struct {
 	Type  string // "NUMBER", "OPERATOR", "LPAREN", "RPAREN"
@@ -44,37 +71,46 @@ type Node struct {
 
 // ParseExpression разбирает выражение и создает задачи
 func (p *Parser) ParseExpression(expr string) ([]models.Task, error) {
+	tasks, _, err := p.Parse(expr)
+	return tasks, err
+}
+
+// Parse разбирает выражение и создает задачи. Вторым значением возвращается итог выражения:
+// ссылка на результат последней задачи вида "res:<id>" или само число, если в выражении
+// нет операций и задач не создано
+func (p *Parser) Parse(expr string) ([]models.Task, string, error) {
 	// Удаляем пробелы
 	expr = strings.ReplaceAll(expr, " ", "")
 
 	if expr == "" {
-		return nil, fmt.Errorf("пустое выражение")
+		return nil, "", fmt.Errorf("пустое выражение")
 	}
 
 	// Разбиваем на токены
 	tokens, err := p.tokenize(expr)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Строим дерево выражения
 	root, remainingTokens, err := p.parseExpression(tokens, 0)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if len(remainingTokens) > 0 {
-		return nil, fmt.Errorf("некорректное выражение: лишние символы")
+		return nil, "", fmt.Errorf("некорректное выражение: лишние символы")
 	}
 
 	// Преобразуем дерево в задачи
+	// Все задачи выражения получают время операций из одного снимка настроек
 	tasks := make([]models.Task, 0)
-	_, err = p.buildTasks(root, &tasks, 0)
+	value, err := p.buildTasks(root, p.OperationTimes(), &tasks, 0)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return tasks, nil
+	return tasks, value, nil
 }
 
 // Tokenize разбивает строку на токены
@@ -220,19 +256,19 @@ func (p *Parser) parseFactor(tokens []Token, pos int) (*Node, []Token, error) {
 }
 
 // buildTasks преобразует дерево выражения в список задач
-func (p *Parser) buildTasks(node *Node, tasks *[]models.Task, exprID int) (string, error) {
+func (p *Parser) buildTasks(node *Node, opTimes OperationTimes, tasks *[]models.Task, exprID int) (string, error) {
 	if node.Type == "NUMBER" {
 		// Для числа просто возвращаем его значение
 		return node.Value, nil
 	}
 
 	// Рекурсивно обрабатываем левое и правое поддерево
-	leftArg, err := p.buildTasks(node.Left, tasks, exprID)
+	leftArg, err := p.buildTasks(node.Left, opTimes, tasks, exprID)
 	if err != nil {
 		return "", err
 	}
 
-	rightArg, err := p.buildTasks(node.Right, tasks, exprID)
+	rightArg, err := p.buildTasks(node.Right, opTimes, tasks, exprID)
 	if err != nil {
 		return "", err
 	}
@@ -244,16 +280,16 @@ func (p *Parser) buildTasks(node *Node, tasks *[]models.Task, exprID int) (strin
 	switch node.Value {
 	case "+":
 		operation = models.OperationAdd
-		operationTime = p.opTimes.Addition
+		operationTime = opTimes.Addition
 	case "-":
 		operation = models.OperationSubtract
-		operationTime = p.opTimes.Subtraction
+		operationTime = opTimes.Subtraction
 	case "*":
 		operation = models.OperationMultiply
-		operationTime = p.opTimes.Multiplication
+		operationTime = opTimes.Multiplication
 	case "/":
 		operation = models.OperationDivide
-		operationTime = p.opTimes.Division
+		operationTime = opTimes.Division
 	default:
 		return "", fmt.Errorf("неизвестная операция: %s", node.Value)
 	}
@@ -46,6 +46,18 @@ func TestParser_ParseExpression(t *testing.T) {
 			wantErr:  false,
 			tasksLen: 4,
 		},
+		{
+			name:     "Число в скобках",
+			expr:     "((5))",
+			wantErr:  false,
+			tasksLen: 0,
+		},
+		{
+			name:     "Унарный минус",
+			expr:     "(-(5))",
+			wantErr:  true,
+			tasksLen: 0,
+		},
 		{
 			name:     "Пустое выражение",
 			expr:     "",
@@ -96,6 +108,21 @@ func TestParser_ParseExpression(t *testing.T) {
 	}
 }
 
+// TestParser_ParseValue проверяет итоговое значение разобранного выражения
+func TestParser_ParseValue(t *testing.T) {
+	parser := NewParser(OperationTimes{})
+
+	tasks, value, err := parser.Parse("((2.5))")
+	if err != nil || len(tasks) != 0 || value != "2.5" {
+		t.Errorf("Parse((2.5)) = %d tasks, %q, %v; want no tasks and 2.5", len(tasks), value, err)
+	}
+
+	tasks, value, err = parser.Parse("1+2*3")
+	if err != nil || len(tasks) != 2 || value != "res:2" {
+		t.Errorf("Parse(1+2*3) = %d tasks, %q, %v; want 2 tasks and res:2", len(tasks), value, err)
+	}
+}
+
 // TestStorage_AddExpression проверяет добавление выражения в хранилище
 func TestStorage_AddExpression(t *testing.T) {
 	storage := NewStorage()
@@ -0,0 +1,195 @@
+package orchestrator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultPageLimit — размер страницы списка выражений по умолчанию
+	defaultPageLimit = 100
+	// maxPageLimit — максимальный размер страницы списка выражений
+	maxPageLimit = 1000
+)
+
+// pageCursor указывает на последний элемент предыдущей страницы
+type pageCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Key        int64  `json:"k"`
+	ID         int    `json:"id"`
+}
+
+// encodeCursor кодирует курсор в непрозрачную строку
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor декодирует курсор и проверяет его соответствие параметрам сортировки
+func decodeCursor(raw string, query models.ExpressionQuery) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, fmt.Errorf("некорректный курсор")
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("некорректный курсор")
+	}
+	if c.SortBy != query.SortBy || c.Descending != query.Descending {
+		return c, fmt.Errorf("курсор не соответствует параметрам сортировки")
+	}
+	return c, nil
+}
+
+// sortKey возвращает значение поля сортировки выражения.
+// Незавершенные выражения при сортировке по длительности имеют ключ -1
+func sortKey(expr models.Expression, sortBy string) int64 {
+	switch sortBy {
+	case models.SortByCreatedAt:
+		return expr.CreatedAt.UnixNano()
+	case models.SortByDuration:
+		if duration, ok := expr.Duration(); ok {
+			return int64(duration)
+		}
+		return -1
+	default:
+		return int64(expr.ID)
+	}
+}
+
+// ValidateQuery проверяет параметры запроса и подставляет значения по умолчанию
+func ValidateQuery(query *models.ExpressionQuery) error {
+	switch query.SortBy {
+	case "":
+		query.SortBy = models.SortByID
+	case models.SortByID, models.SortByCreatedAt, models.SortByDuration:
+	default:
+		return fmt.Errorf("некорректное поле сортировки: %s", query.SortBy)
+	}
+
+	// Без limit и cursor список возвращается целиком, как до появления пагинации
+	switch {
+	case query.Limit == 0 && query.Cursor != "":
+		query.Limit = defaultPageLimit
+	case query.Limit < 0:
+		return fmt.Errorf("limit должен быть положительным")
+	case query.Limit > maxPageLimit:
+		query.Limit = maxPageLimit
+	}
+
+	for _, status := range query.Statuses {
+		switch status {
+		case models.StatusPending, models.StatusProcessing, models.StatusCompleted, models.StatusError, models.StatusCancelled:
+		default:
+			return fmt.Errorf("некорректный статус: %s", status)
+		}
+	}
+
+	return nil
+}
+
+// ListExpressions возвращает страницу выражений, удовлетворяющих запросу, и курсор следующей страницы
+func (s *Storage) ListExpressions(query models.ExpressionQuery) ([]models.Expression, string, error) {
+	if err := ValidateQuery(&query); err != nil {
+		return nil, "", err
+	}
+
+	var cursor *pageCursor
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor, query)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = &c
+	}
+
+	// Отбираем выражения под блокировкой чтения
+	s.rlock()
+	matched := make([]models.Expression, 0)
+	for _, expr := range s.expressions {
+		if matchesQuery(expr, query) {
+			matched = append(matched, expr)
+		}
+	}
+	s.mutex.RUnlock()
+
+	// less сравнивает пары (ключ, ID) с учетом направления сортировки
+	less := func(keyA int64, idA int, keyB int64, idB int) bool {
+		if query.Descending {
+			keyA, idA, keyB, idB = keyB, idB, keyA, idA
+		}
+		if keyA != keyB {
+			return keyA < keyB
+		}
+		return idA < idB
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return less(sortKey(matched[i], query.SortBy), matched[i].ID, sortKey(matched[j], query.SortBy), matched[j].ID)
+	})
+
+	// Пропускаем элементы до курсора включительно
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return less(cursor.Key, cursor.ID, sortKey(matched[i], query.SortBy), matched[i].ID)
+		})
+	}
+
+	end := len(matched)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+	page := matched[start:end]
+
+	nextCursor := ""
+	if end < len(matched) && len(page) > 0 {
+		last := page[len(page)-1]
+		nextCursor = encodeCursor(pageCursor{
+			SortBy:     query.SortBy,
+			Descending: query.Descending,
+			Key:        sortKey(last, query.SortBy),
+			ID:         last.ID,
+		})
+	}
+
+	return page, nextCursor, nil
+}
+
+// matchesQuery проверяет, удовлетворяет ли выражение фильтрам запроса
+func matchesQuery(expr models.Expression, query models.ExpressionQuery) bool {
+	if query.OwnerID != 0 && expr.OwnerID != query.OwnerID {
+		return false
+	}
+
+	if len(query.Statuses) > 0 {
+		found := false
+		for _, status := range query.Statuses {
+			if expr.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !query.CreatedAfter.IsZero() && expr.CreatedAt.Before(query.CreatedAfter) {
+		return false
+	}
+	if !query.CreatedBefore.IsZero() && !expr.CreatedAt.Before(query.CreatedBefore) {
+		return false
+	}
+
+	if query.Search != "" && !strings.Contains(expr.RawExpr, query.Search) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStorage_ListExpressionsUnpaginated проверяет, что без limit и cursor список возвращается целиком
+func TestStorage_ListExpressionsUnpaginated(t *testing.T) {
+	server, storage := newTestServer()
+	total := defaultPageLimit + 5
+	for i := 0; i < total; i++ {
+		if _, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "1+1"); err != nil {
+			t.Fatalf("submitExpression() error = %v", err)
+		}
+	}
+
+	exprs, next, err := storage.ListExpressions(models.ExpressionQuery{})
+	if err != nil {
+		t.Fatalf("ListExpressions() error = %v", err)
+	}
+	if len(exprs) != total || next != "" {
+		t.Errorf("len = %d, next = %q, want %d and no cursor", len(exprs), next, total)
+	}
+
+	// С курсором без limit используется размер страницы по умолчанию
+	first, next, err := storage.ListExpressions(models.ExpressionQuery{Limit: 1})
+	if err != nil || len(first) != 1 || next == "" {
+		t.Fatalf("ListExpressions(limit=1) = %d items, next %q, err %v", len(first), next, err)
+	}
+	exprs, next, err = storage.ListExpressions(models.ExpressionQuery{Cursor: next})
+	if err != nil {
+		t.Fatalf("ListExpressions(cursor) error = %v", err)
+	}
+	if len(exprs) != defaultPageLimit || next == "" {
+		t.Errorf("len = %d, next = %q, want %d and a cursor", len(exprs), next, defaultPageLimit)
+	}
+}
+
+// TestStorage_ListExpressions проверяет фильтрацию, сортировку и пагинацию выражений
+func TestStorage_ListExpressions(t *testing.T) {
+	server, storage := newTestServer()
+	for _, expr := range []string{"1+1", "2+2", "3+3", "4*4", "5-5"} {
+		if _, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), expr); err != nil {
+			t.Fatalf("submitExpression(%q) error = %v", expr, err)
+		}
+	}
+
+	// Завершаем выражения 1 и 2, остальные остаются в обработке
+	for _, id := range []int{1, 2} {
+		taskID := storage.exprTasksMapping[id][0]
+		if err := storage.UpdateTaskResult(taskID, float64(id*2), ""); err != nil {
+			t.Fatalf("UpdateTaskResult() error = %v", err)
+		}
+	}
+
+	ids := func(exprs []models.Expression) []int {
+		result := make([]int, 0, len(exprs))
+		for _, expr := range exprs {
+			result = append(result, expr.ID)
+		}
+		return result
+	}
+
+	equal := func(a, b []int) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Постраничный обход по убыванию ID
+	query := models.ExpressionQuery{Descending: true, Limit: 2}
+	var all []int
+	for page := 0; page < 5; page++ {
+		exprs, next, err := storage.ListExpressions(query)
+		if err != nil {
+			t.Fatalf("ListExpressions() error = %v", err)
+		}
+		all = append(all, ids(exprs)...)
+		if next == "" {
+			break
+		}
+		query.Cursor = next
+	}
+	if want := []int{5, 4, 3, 2, 1}; !equal(all, want) {
+		t.Errorf("pages = %v, want %v", all, want)
+	}
+
+	tests := []struct {
+		name  string
+		query models.ExpressionQuery
+		want  []int
+	}{
+		{
+			name:  "Фильтр по статусу",
+			query: models.ExpressionQuery{Statuses: []models.Status{models.StatusCompleted}},
+			want:  []int{1, 2},
+		},
+		{
+			name:  "Поиск по выражению",
+			query: models.ExpressionQuery{Search: "*"},
+			want:  []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exprs, _, err := storage.ListExpressions(tt.query)
+			if err != nil {
+				t.Fatalf("ListExpressions() error = %v", err)
+			}
+			if got := ids(exprs); !equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// При сортировке по убыванию длительности первыми идут завершенные выражения
+	exprs, _, err := storage.ListExpressions(models.ExpressionQuery{SortBy: models.SortByDuration, Descending: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListExpressions() error = %v", err)
+	}
+	for _, expr := range exprs {
+		if expr.CompletedAt == nil {
+			t.Errorf("expression %d is not completed", expr.ID)
+		}
+	}
+
+	// Курсор не подходит для другой сортировки
+	if _, _, err := storage.ListExpressions(models.ExpressionQuery{SortBy: models.SortByCreatedAt, Cursor: encodeCursor(pageCursor{SortBy: models.SortByID})}); err == nil {
+		t.Errorf("ожидалась ошибка для несовместимого курсора")
+	}
+}
@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"math"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ошибки приема результатов задач от агентов
+var (
+	ErrTaskNotFound    = errors.New("задача не найдена")
+	ErrTaskNotLeased   = errors.New("задача не выдана этому агенту")
+	ErrDuplicateResult = errors.New("результат задачи уже получен")
+	ErrLateResult      = errors.New("выражение уже завершено")
+	ErrInvalidResult   = errors.New("результат не является конечным числом")
+)
+
+// errAuditMismatch — сообщение об ошибке выражения, результаты задачи которого у агентов разошлись
+const errAuditMismatch = "результаты агентов расходятся"
+
+// auditResult — первый результат задачи в режиме аудита, ожидающий результата второго агента
+type auditResult struct {
+	agentID    string
+	result     float64
+	errorMsg   string
+	receivedAt time.Time
+}
+
+// matches сообщает, совпадает ли результат второго агента с первым
+func (r auditResult) matches(result float64, errorMsg string) bool {
+	if r.errorMsg != "" || errorMsg != "" {
+		return r.errorMsg == errorMsg
+	}
+	return r.result == result
+}
+
+// EnableAudit включает режим аудита: каждая задача выполняется двумя разными агентами,
+// и результат принимается, только если их ответы совпали
+func (s *Storage) EnableAudit(enabled bool) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.auditEnabled = enabled
+}
+
+// SetAuditTimeout задает, сколько результат задачи в режиме аудита ждет второго агента.
+// По истечении срока принимается единственный результат. 0 — ждать бессрочно
+func (s *Storage) SetAuditTimeout(timeout time.Duration) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.auditTimeout = timeout
+}
+
+// AcceptTaskResult принимает результат задачи от агента agentID. Результат отклоняется, если задача
+// не выдавалась этому агенту, другой результат уже получен, выражение завершено или значение не конечно.
+// Повторная отправка того же результата (например, из outbox агента после сбоя сети) подтверждается
+func (s *Storage) AcceptTaskResult(agentID string, id int, result float64, errorMsg string) error {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		s.metrics.observeRejectedResult(ErrTaskNotFound)
+		return fmt.Errorf("задача с ID %d: %w", id, ErrTaskNotFound)
+	}
+	if !slices.Contains(task.Leases, agentID) && !slices.Contains(task.Released, agentID) {
+		s.metrics.observeRejectedResult(ErrTaskNotLeased)
+		return fmt.Errorf("задача с ID %d: %w", id, ErrTaskNotLeased)
+	}
+	if s.isRepeatedResult(task, agentID, result, errorMsg) {
+		return nil
+	}
+
+	task, err := s.checkResult(id, result, errorMsg)
+	if err != nil {
+		return err
+	}
+
+	if !s.auditEnabled {
+		s.applyResult(task, result, errorMsg)
+		return nil
+	}
+
+	// Режим аудита: первый результат ждет второго, второй сверяется с первым
+	first, pending := s.auditResults[id]
+	if !pending {
+		s.auditResults[id] = auditResult{agentID: agentID, result: result, errorMsg: errorMsg, receivedAt: time.Now()}
+		return nil
+	}
+	if first.agentID == agentID {
+		s.metrics.observeRejectedResult(ErrDuplicateResult)
+		return fmt.Errorf("задача с ID %d: %w", id, ErrDuplicateResult)
+	}
+	delete(s.auditResults, id)
+
+	if first.matches(result, errorMsg) {
+		s.applyResult(task, result, errorMsg)
+		return nil
+	}
+
+	s.metrics.observeAuditMismatch()
+	s.logger.Warn("Task results mismatch",
+		logging.TaskID(id),
+		logging.ExpressionID(task.ExpressionID),
+		zap.Strings("agents", []string{first.agentID, agentID}),
+		zap.Float64s("results", []float64{first.result, result}),
+		zap.Strings("errors", []string{first.errorMsg, errorMsg}),
+	)
+	s.applyResult(task, 0, errAuditMismatch)
+	return nil
+}
+
+// isRepeatedResult сообщает, что агент agentID повторно прислал уже принятый от него результат.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) isRepeatedResult(task models.Task, agentID string, result float64, errorMsg string) bool {
+	if first, pending := s.auditResults[task.ID]; pending && first.agentID == agentID {
+		return first.matches(result, errorMsg)
+	}
+	if errorMsg == "" {
+		return task.Result != nil && *task.Result == result
+	}
+	expr, exists := s.expressions[task.ExpressionID]
+	return exists && expr.Status == models.StatusError && expr.ErrorMsg == errorMsg
+}
+
+// checkResult проверяет, что результат задачи id можно принять: задача существует и еще
+// не получила результат, ее выражение не завершено, а значение конечно.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) checkResult(id int, result float64, errorMsg string) (models.Task, error) {
+	task, exists := s.tasks[id]
+
+	var err error
+	switch expr, exprExists := s.expressions[task.ExpressionID]; {
+	case !exists:
+		err = ErrTaskNotFound
+	case task.Result != nil:
+		err = ErrDuplicateResult
+	case !exprExists || expr.Status.IsTerminal():
+		err = ErrLateResult
+	case errorMsg == "" && (math.IsNaN(result) || math.IsInf(result, 0)):
+		err = ErrInvalidResult
+	}
+	if err != nil {
+		s.metrics.observeRejectedResult(err)
+		return task, fmt.Errorf("задача с ID %d: %w", id, err)
+	}
+	return task, nil
+}
+
+// expireAudits принимает единственный результат задач, второй результат которых не пришел
+// за время auditTimeout, например, если работает только один агент. Вызывается под блокировкой мьютекса
+func (s *Storage) expireAudits(now time.Time) {
+	if s.auditTimeout <= 0 {
+		return
+	}
+	for id, first := range s.auditResults {
+		if now.Sub(first.receivedAt) < s.auditTimeout {
+			continue
+		}
+		delete(s.auditResults, id)
+
+		task, exists := s.tasks[id]
+		expr, exprExists := s.expressions[task.ExpressionID]
+		if !exists || task.Result != nil || !exprExists || expr.Status.IsTerminal() {
+			continue
+		}
+		s.logger.Warn("No second result for audited task, accepting single result",
+			logging.TaskID(id),
+			logging.ExpressionID(task.ExpressionID),
+			logging.AgentID(first.agentID),
+		)
+		s.applyResult(task, first.result, first.errorMsg)
+	}
+}
+
+// issueReplica выдает агенту agentID задачу, которую выполняет только один другой агент.
+// Возвращает nil, если таких задач нет. Вызывается под блокировкой мьютекса
+func (s *Storage) issueReplica(agentID string, now time.Time) *models.Task {
+	for id, task := range s.tasks {
+		if !s.isInFlight(task) || len(task.Leases) != 1 || task.Leases[0] == agentID {
+			continue
+		}
+
+		task.Leases = append(slices.Clone(task.Leases), agentID)
+		task.LeasedUntil = s.leaseDeadline(now)
+		s.tasks[id] = task
+
+		replica := task
+		replica.Arg1 = s.resolveArg(task.Arg1)
+		replica.Arg2 = s.resolveArg(task.Arg2)
+		if span, exists := s.taskSpans[id]; exists {
+			replica.TraceParent = span.SpanContext().Traceparent()
+		}
+		return &replica
+	}
+	return nil
+}
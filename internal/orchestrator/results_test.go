@@ -0,0 +1,192 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_TaskResultValidation(t *testing.T) {
+	server, storage := newTestServer()
+	handler := server.SetupRoutes()
+
+	exprID, _ := storage.AddExpression("2*3+1/0")
+	tasks, _ := server.parser.ParseExpression("2*3+1/0")
+	storage.AddTasks(exprID, tasks)
+	first, _ := storage.GetReadyTaskForAgent("agent-1")
+	second, _ := storage.GetReadyTaskForAgent("agent-1")
+	if first.Operation == models.OperationDivide {
+		first, second = second, first
+	}
+
+	send := func(agentID, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(body))
+		req.Header.Set(models.AgentIDHeader, agentID)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	result := func(id int, value string) string {
+		return fmt.Sprintf(`{"id": %d, "result": %s}`, id, value)
+	}
+
+	// Порядок важен: каждый шаг меняет состояние задач
+	tests := []struct {
+		name    string
+		agentID string
+		body    string
+		want    int
+	}{
+		{"unknown task", "agent-1", result(999, "1"), http.StatusNotFound},
+		{"task leased to another agent", "agent-2", result(first.ID, "6"), http.StatusForbidden},
+		{"task not issued yet", "agent-1", result(tasks[len(tasks)-1].ID, "1"), http.StatusForbidden},
+		{"overflow", "agent-1", result(first.ID, "1e309"), http.StatusUnprocessableEntity},
+		{"accepted", "agent-1", result(first.ID, "6"), http.StatusOK},
+		{"repeated result", "agent-1", result(first.ID, "6"), http.StatusOK},
+		{"different result", "agent-1", result(first.ID, "7"), http.StatusConflict},
+		{"error result", "agent-1", fmt.Sprintf(`{"id": %d, "error": "деление на ноль"}`, second.ID), http.StatusOK},
+		{"repeated error result", "agent-1", fmt.Sprintf(`{"id": %d, "error": "деление на ноль"}`, second.ID), http.StatusOK},
+		{"after expression failed", "agent-1", result(second.ID, "0"), http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		if code := send(tt.agentID, tt.body); code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+
+	// Значения, которые нельзя передать в JSON, отклоняются и при вызове хранилища напрямую
+	exprID, _ = storage.AddExpression("1+1")
+	tasks, _ = server.parser.ParseExpression("1+1")
+	storage.AddTasks(exprID, tasks)
+	task, _ := storage.GetReadyTask()
+	if err := storage.UpdateTaskResult(task.ID, math.NaN(), ""); !errors.Is(err, ErrInvalidResult) {
+		t.Errorf("UpdateTaskResult(NaN) error = %v, want %v", err, ErrInvalidResult)
+	}
+}
+
+func TestStorage_LeaseExpiry(t *testing.T) {
+	server, storage := newTestServer()
+	storage.SetLeaseTimeout(time.Minute)
+
+	exprID, _ := storage.AddExpression("2+2")
+	tasks, _ := server.parser.ParseExpression("2+2")
+	storage.AddTasks(exprID, tasks)
+	task, _ := storage.GetReadyTaskForAgent("agent-1")
+	if _, err := storage.GetReadyTaskForAgent("agent-2"); err == nil {
+		t.Fatal("task should not be issued while its lease is valid")
+	}
+
+	// После истечения срока аренды задача выдается другому агенту
+	leased := storage.tasks[task.ID]
+	leased.LeasedUntil = time.Now().Add(-time.Second)
+	storage.tasks[task.ID] = leased
+	reissued, err := storage.GetReadyTaskForAgent("agent-2")
+	if err != nil || reissued.ID != task.ID {
+		t.Fatalf("reissued = %+v, err = %v; want task %d", reissued, err, task.ID)
+	}
+
+	// Опоздавший результат первого агента принимается, повтор того же результата вторым — тоже
+	if err := storage.AcceptTaskResult("agent-1", task.ID, 4, ""); err != nil {
+		t.Errorf("late result error = %v", err)
+	}
+	if err := storage.AcceptTaskResult("agent-2", task.ID, 4, ""); err != nil {
+		t.Errorf("same result error = %v", err)
+	}
+	if err := storage.AcceptTaskResult("agent-3", task.ID, 4, ""); !errors.Is(err, ErrTaskNotLeased) {
+		t.Errorf("foreign result error = %v, want %v", err, ErrTaskNotLeased)
+	}
+	if expr, _ := storage.GetExpression(exprID); expr.Status != models.StatusCompleted {
+		t.Errorf("expression = %+v, want completed", expr)
+	}
+}
+
+func TestStorage_Audit(t *testing.T) {
+	server, storage := newTestServer()
+	storage.EnableAudit(true)
+
+	submit := func(expr string) int {
+		exprID, _ := storage.AddExpression(expr)
+		tasks, _ := server.parser.ParseExpression(expr)
+		storage.AddTasks(exprID, tasks)
+		return exprID
+	}
+
+	// Задача выдается второму агенту, но не тому же самому
+	matchID := submit("2+2")
+	task, _ := storage.GetReadyTaskForAgent("agent-1")
+	if _, err := storage.GetReadyTaskForAgent("agent-1"); err == nil {
+		t.Fatal("task should not be issued twice to the same agent")
+	}
+	replica, err := storage.GetReadyTaskForAgent("agent-2")
+	if err != nil || replica.ID != task.ID {
+		t.Fatalf("replica = %+v, err = %v; want task %d", replica, err, task.ID)
+	}
+
+	// Выражение завершается только после совпадения двух результатов
+	if err := storage.AcceptTaskResult("agent-1", task.ID, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.AcceptTaskResult("agent-1", task.ID, 4, ""); err != nil {
+		t.Errorf("repeated first result error = %v, want nil", err)
+	}
+	if err := storage.AcceptTaskResult("agent-1", task.ID, 5, ""); !errors.Is(err, ErrDuplicateResult) {
+		t.Errorf("changed first result error = %v, want %v", err, ErrDuplicateResult)
+	}
+	if expr, _ := storage.GetExpression(matchID); expr.Status.IsTerminal() {
+		t.Fatalf("expression finished after one result: %+v", expr)
+	}
+	if err := storage.AcceptTaskResult("agent-2", task.ID, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if expr, _ := storage.GetExpression(matchID); expr.Status != models.StatusCompleted || *expr.Result != "4" {
+		t.Errorf("matching results: expression = %+v", expr)
+	}
+
+	// Расхождение результатов завершает выражение ошибкой
+	mismatchID := submit("3*3")
+	task, _ = storage.GetReadyTaskForAgent("agent-1")
+	storage.GetReadyTaskForAgent("agent-2")
+	storage.AcceptTaskResult("agent-1", task.ID, 9, "")
+	storage.AcceptTaskResult("agent-2", task.ID, 10, "")
+	if expr, _ := storage.GetExpression(mismatchID); expr.Status != models.StatusError || expr.ErrorMsg != errAuditMismatch {
+		t.Errorf("mismatching results: expression = %+v", expr)
+	}
+}
+
+func TestStorage_AuditSingleAgent(t *testing.T) {
+	server, storage := newTestServer()
+	storage.EnableAudit(true)
+	storage.SetAuditTimeout(time.Minute)
+
+	exprID, _ := storage.AddExpression("2+2")
+	tasks, _ := server.parser.ParseExpression("2+2")
+	storage.AddTasks(exprID, tasks)
+
+	// Единственный агент не получает реплику своей же задачи
+	task, _ := storage.GetReadyTaskForAgent("embedded-1")
+	if err := storage.AcceptTaskResult("embedded-1", task.ID, 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.GetReadyTaskForAgent("embedded-1"); err == nil {
+		t.Fatal("task should not be issued twice to the same agent")
+	}
+	if expr, _ := storage.GetExpression(exprID); expr.Status.IsTerminal() {
+		t.Fatalf("expression finished before audit timeout: %+v", expr)
+	}
+
+	// Без второго результата по истечении срока принимается единственный
+	first := storage.auditResults[task.ID]
+	first.receivedAt = time.Now().Add(-time.Hour)
+	storage.auditResults[task.ID] = first
+	storage.GetReadyTaskForAgent("embedded-1")
+	if expr, _ := storage.GetExpression(exprID); expr.Status != models.StatusCompleted || *expr.Result != "4" {
+		t.Errorf("single result after timeout: expression = %+v", expr)
+	}
+}
@@ -0,0 +1,248 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetentionPolicy описывает, как долго хранятся завершенные выражения
+type RetentionPolicy struct {
+	MaxAge      time.Duration // Максимальный возраст завершенного выражения (0 — без ограничения)
+	ErrorMaxAge time.Duration // Максимальный возраст выражения с ошибкой (0 — как MaxAge)
+	MaxCount    int           // Максимальное количество выражений в хранилище (0 — без ограничения)
+}
+
+// Enabled сообщает, задано ли хотя бы одно ограничение
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.ErrorMaxAge > 0 || p.MaxCount > 0
+}
+
+// maxAgeFor возвращает максимальный возраст для выражения с заданным статусом
+func (p RetentionPolicy) maxAgeFor(status models.Status) time.Duration {
+	if status == models.StatusError && p.ErrorMaxAge > 0 {
+		return p.ErrorMaxAge
+	}
+	return p.MaxAge
+}
+
+// PurgeStats содержит результат очистки хранилища
+type PurgeStats struct {
+	Expressions int       `json:"expressions"` // Удалено выражений
+	Tasks       int       `json:"tasks"`       // Удалено задач
+	Batches     int       `json:"batches"`     // Удалено пакетов
+	Timestamp   time.Time `json:"timestamp"`   // Время очистки
+}
+
+// StorageStats содержит сведения о размере хранилища
+type StorageStats struct {
+	Expressions int                   `json:"expressions"`
+	Tasks       int                   `json:"tasks"`
+	Batches     int                   `json:"batches"`
+	ByStatus    map[models.Status]int `json:"by_status"`
+	LastPurge   *PurgeStats           `json:"last_purge,omitempty"`
+	TotalPurged PurgeStats            `json:"total_purged"`
+}
+
+// Purge удаляет завершенные выражения, их задачи и пакеты согласно политике хранения.
+// Незавершенные выражения никогда не удаляются
+func (s *Storage) Purge(policy RetentionPolicy, now time.Time) PurgeStats {
+	s.lock()
+
+	stats := PurgeStats{Timestamp: now.UTC()}
+	purged := make([]int, 0)
+
+	// Удаляем выражения старше допустимого возраста
+	terminal := make([]models.Expression, 0)
+	for id, expr := range s.expressions {
+		if !expr.Status.IsTerminal() || expr.CompletedAt == nil {
+			continue
+		}
+		if maxAge := policy.maxAgeFor(expr.Status); maxAge > 0 && now.Sub(*expr.CompletedAt) > maxAge {
+			stats.Tasks += s.deleteExpression(id)
+			purged = append(purged, id)
+			continue
+		}
+		terminal = append(terminal, expr)
+	}
+
+	// Ограничиваем количество выражений: сначала удаляются самые старые успешные,
+	// выражения с ошибками удаляются в последнюю очередь
+	if policy.MaxCount > 0 && len(s.expressions) > policy.MaxCount {
+		sort.Slice(terminal, func(i, j int) bool {
+			iErr := terminal[i].Status == models.StatusError
+			jErr := terminal[j].Status == models.StatusError
+			if iErr != jErr {
+				return !iErr
+			}
+			return terminal[i].CompletedAt.Before(*terminal[j].CompletedAt)
+		})
+
+		for _, expr := range terminal {
+			if len(s.expressions) <= policy.MaxCount {
+				break
+			}
+			stats.Tasks += s.deleteExpression(expr.ID)
+			purged = append(purged, expr.ID)
+		}
+	}
+
+	stats.Expressions = len(purged)
+	stats.Batches = s.deleteOrphanBatches(policy, now)
+
+	s.lastPurge = &stats
+	s.totalPurged.Expressions += stats.Expressions
+	s.totalPurged.Tasks += stats.Tasks
+	s.totalPurged.Batches += stats.Batches
+	s.totalPurged.Timestamp = stats.Timestamp
+
+	listeners := s.purgeListeners
+	s.mutex.Unlock()
+
+	if len(purged) > 0 {
+		for _, listener := range listeners {
+			listener(purged)
+		}
+	}
+
+	return stats
+}
+
+// AddPurgeListener регистрирует функцию, вызываемую со списком ID удаленных выражений
+func (s *Storage) AddPurgeListener(listener func(ids []int)) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.purgeListeners = append(s.purgeListeners, listener)
+}
+
+// Stats возвращает сведения о размере хранилища
+func (s *Storage) Stats() StorageStats {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	stats := StorageStats{
+		Expressions: len(s.expressions),
+		Tasks:       len(s.tasks),
+		Batches:     len(s.batches),
+		ByStatus:    make(map[models.Status]int),
+		TotalPurged: s.totalPurged,
+	}
+	for _, expr := range s.expressions {
+		stats.ByStatus[expr.Status]++
+	}
+	if s.lastPurge != nil {
+		lastPurge := *s.lastPurge
+		stats.LastPurge = &lastPurge
+	}
+
+	return stats
+}
+
+// deleteExpression удаляет выражение, его задачи и ожидающие сверки результаты задач,
+// возвращает количество удаленных задач. Вызывается под блокировкой мьютекса
+func (s *Storage) deleteExpression(id int) int {
+	taskIDs := s.exprTasksMapping[id]
+	for _, taskID := range taskIDs {
+		delete(s.tasks, taskID)
+		delete(s.taskSpans, taskID)
+		delete(s.auditResults, taskID)
+	}
+	delete(s.exprTasksMapping, id)
+	delete(s.expressions, id)
+	return len(taskIDs)
+}
+
+// deleteOrphanBatches удаляет пакеты, все принятые выражения которых уже удалены.
+// Пакеты, в которых все выражения были отклонены, удаляются по возрасту, как выражения с ошибкой.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) deleteOrphanBatches(policy RetentionPolicy, now time.Time) int {
+	deleted := 0
+	for id, batch := range s.batches {
+		accepted, alive := false, false
+		for _, item := range batch.Items {
+			if item.ExpressionID == 0 {
+				continue
+			}
+			accepted = true
+			if _, exists := s.expressions[item.ExpressionID]; exists {
+				alive = true
+				break
+			}
+		}
+
+		orphan := accepted && !alive
+		if !accepted {
+			maxAge := policy.maxAgeFor(models.StatusError)
+			orphan = maxAge > 0 && now.Sub(batch.CreatedAt) > maxAge
+		}
+		if orphan {
+			delete(s.batches, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Sweeper периодически очищает хранилище согласно политике хранения
+type Sweeper struct {
+	storage  *Storage
+	policy   RetentionPolicy
+	interval time.Duration
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewSweeper создает сборщик устаревших выражений
+func NewSweeper(storage *Storage, policy RetentionPolicy, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		storage:  storage,
+		policy:   policy,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start запускает периодическую очистку в отдельной горутине
+func (sw *Sweeper) Start() {
+	if !sw.policy.Enabled() || sw.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(sw.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sw.Sweep()
+			case <-sw.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop останавливает периодическую очистку
+func (sw *Sweeper) Stop() {
+	sw.once.Do(func() {
+		close(sw.stop)
+	})
+}
+
+// Sweep выполняет очистку немедленно
+func (sw *Sweeper) Sweep() PurgeStats {
+	stats := sw.storage.Purge(sw.policy, time.Now())
+	if stats.Expressions > 0 {
+		sw.storage.Logger().Info("Storage purged",
+			zap.Int("expressions", stats.Expressions),
+			zap.Int("tasks", stats.Tasks),
+			zap.Int("batches", stats.Batches),
+		)
+	}
+	return stats
+}
@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestStorage_Purge проверяет удаление выражений согласно политике хранения
+func TestStorage_Purge(t *testing.T) {
+	server, storage := newTestServer()
+	for _, expr := range []string{"1+1", "2+2", "1/0", "3*3"} {
+		if _, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), expr); err != nil {
+			t.Fatalf("submitExpression(%q) error = %v", expr, err)
+		}
+	}
+
+	// Выражения 1-3 завершаются (3 — с ошибкой), выражение 4 остается в обработке
+	for _, id := range []int{1, 2, 3} {
+		taskID := storage.exprTasksMapping[id][0]
+		errorMsg := ""
+		if id == 3 {
+			errorMsg = "деление на ноль"
+		}
+		storage.UpdateTaskResult(taskID, 1, errorMsg)
+	}
+
+	now := time.Now()
+
+	// Возрастное ограничение не затрагивает свежие выражения
+	if stats := storage.Purge(RetentionPolicy{MaxAge: time.Hour}, now); stats.Expressions != 0 {
+		t.Errorf("Purge() removed %d expressions, want 0", stats.Expressions)
+	}
+
+	// Ошибки хранятся дольше успешных выражений
+	stats := storage.Purge(RetentionPolicy{MaxAge: time.Minute, ErrorMaxAge: 2 * time.Hour}, now.Add(time.Hour))
+	if stats.Expressions != 2 || stats.Tasks != 2 {
+		t.Errorf("Purge() = %+v, want 2 expressions and 2 tasks", stats)
+	}
+	if _, err := storage.GetExpression(3); err != nil {
+		t.Errorf("выражение с ошибкой не должно быть удалено: %v", err)
+	}
+
+	// Ограничение количества не удаляет незавершенные выражения
+	storage.Purge(RetentionPolicy{MaxCount: 1}, now)
+	if _, err := storage.GetExpression(4); err != nil {
+		t.Errorf("незавершенное выражение не должно быть удалено: %v", err)
+	}
+
+	got := storage.Stats()
+	if got.Expressions != 1 || got.Tasks != 1 || got.ByStatus[models.StatusProcessing] != 1 {
+		t.Errorf("Stats() = %+v, want 1 processing expression with 1 task", got)
+	}
+	if got.TotalPurged.Expressions != 3 {
+		t.Errorf("TotalPurged.Expressions = %d, want 3", got.TotalPurged.Expressions)
+	}
+}
+
+// TestStorage_PurgeBatches проверяет удаление пакетов при очистке хранилища
+func TestStorage_PurgeBatches(t *testing.T) {
+	server, storage := newTestServer()
+	exprID, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "1+1")
+	if err != nil {
+		t.Fatalf("submitExpression() error = %v", err)
+	}
+	accepted := storage.AddBatch(0, []models.BatchItem{{CorrelationID: "a", ExpressionID: exprID}})
+	rejected := storage.AddBatch(0, []models.BatchItem{{CorrelationID: "b", Error: "некорректное выражение"}})
+
+	now := time.Now()
+	policy := RetentionPolicy{MaxAge: time.Minute}
+
+	// Пакет из отклоненных выражений не удаляется при первой же очистке
+	if stats := storage.Purge(policy, now); stats.Batches != 0 {
+		t.Errorf("Purge() removed %d batches, want 0", stats.Batches)
+	}
+	if _, err := storage.GetBatchStatus(rejected, 0); err != nil {
+		t.Errorf("пакет с отклоненными выражениями не должен быть удален: %v", err)
+	}
+
+	// Пакет с принятыми выражениями живет, пока живут его выражения
+	storage.UpdateTaskResult(storage.exprTasksMapping[exprID][0], 2, "")
+	stats := storage.Purge(policy, now.Add(time.Hour))
+	if stats.Expressions != 1 || stats.Batches != 2 {
+		t.Errorf("Purge() = %+v, want 1 expression and 2 batches", stats)
+	}
+	for _, id := range []int{accepted, rejected} {
+		if _, err := storage.GetBatchStatus(id, 0); err == nil {
+			t.Errorf("пакет %d должен быть удален", id)
+		}
+	}
+}
+
+// TestStorage_PurgeAuditResults проверяет, что очистка удаляет ожидающие сверки результаты
+// задач удаляемого выражения
+func TestStorage_PurgeAuditResults(t *testing.T) {
+	server, storage := newTestServer()
+	storage.EnableAudit(true)
+
+	exprID, _ := storage.AddExpression("1/0+2*3")
+	tasks, _ := server.parser.ParseExpression("1/0+2*3")
+	storage.AddTasks(exprID, tasks)
+
+	// Первый агент получает обе независимые задачи и сообщает результат умножения,
+	// который ждет сверки, а деление на ноль подтверждают оба агента
+	division, _ := storage.GetReadyTaskForAgent("agent-1")
+	multiplication, _ := storage.GetReadyTaskForAgent("agent-1")
+	if division.Operation != models.OperationDivide {
+		division, multiplication = multiplication, division
+	}
+	if err := storage.AcceptTaskResult("agent-1", multiplication.ID, 6, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Второй агент получает реплики обеих задач в произвольном порядке
+	for i := 0; i < 2; i++ {
+		if _, err := storage.GetReadyTaskForAgent("agent-2"); err != nil {
+			t.Fatalf("replica %d: %v", i+1, err)
+		}
+	}
+	for _, agentID := range []string{"agent-1", "agent-2"} {
+		if err := storage.AcceptTaskResult(agentID, division.ID, 0, "деление на ноль"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expr, _ := storage.GetExpression(exprID); expr.Status != models.StatusError {
+		t.Fatalf("expression = %+v, want error", expr)
+	}
+
+	storage.Purge(RetentionPolicy{MaxAge: time.Minute}, time.Now().Add(time.Hour))
+	if _, err := storage.GetExpression(exprID); err == nil {
+		t.Fatal("expression should be purged")
+	}
+	if n := len(storage.auditResults); n != 0 {
+		t.Errorf("auditResults has %d entries after purge, want 0", n)
+	}
+}
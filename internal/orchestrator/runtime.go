@@ -0,0 +1,135 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DrainStatus описывает состояние режима вывода из эксплуатации
+type DrainStatus struct {
+	Draining          bool `json:"draining"`           // Новые выражения не принимаются
+	ActiveExpressions int  `json:"active_expressions"` // Выражения, вычисление которых еще не завершено
+}
+
+// SetLogLevel подключает изменяемый уровень логирования к административному API
+func (s *Server) SetLogLevel(level zap.AtomicLevel) {
+	s.logLevel = &level
+}
+
+// SetDraining включает или выключает режим вывода из эксплуатации. В этом режиме новые
+// выражения отклоняются, а агенты продолжают получать задачи уже принятых выражений
+func (s *Server) SetDraining(draining bool) {
+	if s.draining.Swap(draining) != draining {
+		s.logger.Info("Drain mode changed", zap.Bool("draining", draining))
+	}
+}
+
+// Draining сообщает, включен ли режим вывода из эксплуатации
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// shutdownPollInterval — период проверки возврата выданных задач при остановке
+const shutdownPollInterval = 50 * time.Millisecond
+
+// Shutdown готовит оркестратор к остановке: прекращает прием новых выражений и выдачу задач,
+// затем ждет, пока агенты вернут результаты уже выданных задач. HTTP-сервер должен работать
+// до возврата из Shutdown, чтобы агенты могли отправить результаты. Хранилище находится
+// в памяти, поэтому задачи, не вернувшиеся до отмены ctx, теряются вместе с остальным
+// состоянием при завершении процесса
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.SetDraining(true)
+	s.stopping.Store(true)
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for s.storage.InFlightTasks() > 0 {
+		select {
+		case <-ctx.Done():
+			s.logger.Warn("Shutdown deadline exceeded, unfinished tasks are lost",
+				zap.Int("tasks", s.storage.InFlightTasks()),
+				zap.Int("active_expressions", s.storage.ActiveExpressions()),
+			)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	s.logger.Info("All issued tasks finished", zap.Int("active_expressions", s.storage.ActiveExpressions()))
+	return nil
+}
+
+// rejectIfDraining отвечает 503, если сервер не принимает новые выражения
+func (s *Server) rejectIfDraining(w http.ResponseWriter) bool {
+	if !s.Draining() {
+		return false
+	}
+	w.Header().Set("Retry-After", "30")
+	writeError(w, "Сервер не принимает новые выражения", http.StatusServiceUnavailable)
+	return true
+}
+
+// handleOperationTimes возвращает или изменяет время выполнения операций.
+// PUT принимает частичное обновление: не указанные поля сохраняют текущие значения
+func (s *Server) handleOperationTimes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		opTimes := s.parser.OperationTimes()
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&opTimes); err != nil {
+			writeError(w, fmt.Sprintf("Некорректный JSON: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+		if err := opTimes.Validate(); err != nil {
+			writeError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		s.parser.SetOperationTimes(opTimes)
+		s.logger.Info("Operation times updated",
+			zap.Int("time_addition_ms", opTimes.Addition),
+			zap.Int("time_subtraction_ms", opTimes.Subtraction),
+			zap.Int("time_multiplication_ms", opTimes.Multiplication),
+			zap.Int("time_division_ms", opTimes.Division),
+		)
+	default:
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.parser.OperationTimes())
+}
+
+// handleDrain возвращает или изменяет режим вывода из эксплуатации
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Draining *bool `json:"draining"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Draining == nil {
+			writeError(w, "Ожидается JSON с полем draining", http.StatusUnprocessableEntity)
+			return
+		}
+		s.SetDraining(*req.Draining)
+	default:
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DrainStatus{
+		Draining:          s.Draining(),
+		ActiveExpressions: s.storage.ActiveExpressions(),
+	})
+}
@@ -1,78 +1,366 @@
 package orchestrator
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	apierrors "github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/handler"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/health"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
 	"net/http"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxWaitTimeout ограничивает время синхронного ожидания результата
+	maxWaitTimeout = 60 * time.Second
+	// maxBatchSize ограничивает количество выражений в одном пакете
+	maxBatchSize = 1000
+	// maxBatchBodySize ограничивает размер тела пакетного запроса
+	maxBatchBodySize = 10 << 20
 )
 
 // Server представляет HTTP-сервер оркестратора
 type Server struct {
-	storage *Storage
-	parser  *Parser
+	storage     *Storage
+	parser      *Parser
+	notifier    *Notifier
+	sweeper     *Sweeper
+	auth        *auth.Authenticator
+	ipLimit     *ratelimit.Limiter
+	keyLimit    *ratelimit.Limiter
+	idempotency *middleware.IdempotencyStore
+	metrics     *Metrics
+	tracer      *tracing.Tracer
+	logger      *zap.Logger
+	logLevel    *zap.AtomicLevel
+	draining    atomic.Bool
+	stopping    atomic.Bool // Сервер останавливается и не выдает новые задачи
+
+	agents      map[string]time.Time // Время последнего обращения каждого агента
+	agentsMutex sync.Mutex
+	agentWindow time.Duration // Окно активности агента
+	minAgents   int           // Минимум активных агентов для готовности
 }
 
 // NewServer создает новый сервер оркестратора
 func NewServer(storage *Storage, parser *Parser) *Server {
 	return &Server{
-		storage: storage,
-		parser:  parser,
+		storage:     storage,
+		parser:      parser,
+		logger:      zap.NewNop(),
+		agents:      make(map[string]time.Time),
+		agentWindow: defaultAgentWindow,
 	}
 }
 
+// SetNotifier включает доставку webhook-уведомлений о завершении выражений
+func (s *Server) SetNotifier(notifier *Notifier) {
+	s.notifier = notifier
+}
+
+// SetIdempotencyStore включает повторную обработку отправки выражений с ключом Idempotency-Key
+func (s *Server) SetIdempotencyStore(store *middleware.IdempotencyStore) {
+	s.idempotency = store
+}
+
+// SetSweeper подключает сборщик устаревших выражений для административного API
+func (s *Server) SetSweeper(sweeper *Sweeper) {
+	s.sweeper = sweeper
+}
+
+// SetAuthenticator включает аутентификацию пользователей, агентов и администратора
+func (s *Server) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.auth = authenticator
+}
+
+// SetRateLimiters включает ограничение частоты запросов к пользовательскому API
+// по IP-адресу и по API-ключу. Любой из ограничителей может быть nil
+func (s *Server) SetRateLimiters(perIP, perKey *ratelimit.Limiter) {
+	s.ipLimit = perIP
+	s.keyLimit = perKey
+}
+
+// SetMetrics включает эндпоинт /metrics и измерение длительности HTTP-запросов
+func (s *Server) SetMetrics(metrics *Metrics) {
+	s.metrics = metrics
+}
+
+// SetLogger задает логгер сервера и хранилища
+func (s *Server) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+	s.storage.SetLogger(logger)
+}
+
+// SetTracer включает трассировку отправки выражений и выполнения задач
+func (s *Server) SetTracer(tracer *tracing.Tracer) {
+	s.tracer = tracer
+	s.storage.SetTracer(tracer)
+}
+
 // SetupRoutes настраивает маршруты HTTP-сервера
 func (s *Server) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
-	// API для пользователей
-	mux.HandleFunc("/api/v1/calculate", s.handleCalculate)
-	mux.HandleFunc("/api/v1/expressions", s.handleGetExpressions)
-	mux.HandleFunc("/api/v1/expressions/", s.handleGetExpression)
+	// API для пользователей: асинхронное вычисление агентами и синхронное — прямо в запросе
+	user := s.userAuth()
+	submit := func(next http.Handler) http.Handler {
+		return user(s.idempotent()(next))
+	}
+	mux.Handle("/api/v1/calculate", tracing.Middleware(s.tracer, "/api/v1/calculate")(submit(http.HandlerFunc(s.handleCalculate))))
+	mux.Handle("/api/v1/calculate/batch", tracing.Middleware(s.tracer, "/api/v1/calculate/batch")(submit(http.HandlerFunc(s.handleCalculateBatch))))
+	mux.Handle("/api/v1/evaluate", tracing.Middleware(s.tracer, "/api/v1/evaluate")(submit(handler.EvaluateHandler(s.logger, evaluateQuota{s}))))
+	mux.Handle("/api/v1/evaluate/batch", tracing.Middleware(s.tracer, "/api/v1/evaluate/batch")(submit(handler.BatchEvaluateHandler(s.logger, runtime.NumCPU(), evaluateQuota{s}))))
+	mux.Handle("/api/v1/batches/", user(http.HandlerFunc(s.handleGetBatch)))
+	mux.Handle("/api/v1/expressions", user(http.HandlerFunc(s.handleGetExpressions)))
+	mux.Handle("/api/v1/expressions/", user(http.HandlerFunc(s.handleExpression)))
+
+	// Регистрация и вход пользователей. Хеширование пароля дорогое, поэтому запросы
+	// ограничиваются по IP-адресу клиента
+	if s.auth != nil && s.auth.Tokens != nil {
+		account := s.ipRateLimit()
+		mux.Handle("/api/v1/register", account(auth.RegisterHandler(s.auth)))
+		mux.Handle("/api/v1/login", account(auth.LoginHandler(s.auth)))
+	}
 
 	// API для агентов
-	mux.HandleFunc("/internal/task", s.handleTask)
+	mux.Handle("/internal/task", s.agentAuth()(http.HandlerFunc(s.handleTask)))
+
+	// Административное API
+	admin := s.adminAuth()
+	mux.Handle("/admin/v1/storage", admin(http.HandlerFunc(s.handleStorageStats)))
+	mux.Handle("/admin/v1/retention/sweep", admin(http.HandlerFunc(s.handleRetentionSweep)))
+	mux.Handle("/admin/v1/operation-times", admin(http.HandlerFunc(s.handleOperationTimes)))
+	mux.Handle("/admin/v1/drain", admin(http.HandlerFunc(s.handleDrain)))
+	mux.Handle("/admin/v1/agents", admin(http.HandlerFunc(s.handleAgents)))
+	if s.logLevel != nil {
+		mux.Handle("/admin/v1/log-level", admin(jsonContent(s.logLevel)))
+	}
+	if s.auth != nil {
+		mux.Handle("/admin/v1/api-keys", admin(auth.APIKeysHandler(s.auth)))
+	}
+
+	// Описание API и веб-интерфейс со страницей документации /static/docs.html
+	mux.Handle("/api/openapi.json", handler.OpenAPIHandler())
+	mux.Handle("/static/", http.StripPrefix("/static/", handler.StaticHandler(s.logger)))
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/static/index.html", http.StatusSeeOther)
+			return
+		}
+		writeError(w, "Не найдено", http.StatusNotFound)
+	}))
 
-	return mux
+	// Проверки состояния и сведения о сборке
+	health.Register(mux, "orchestrator", s.readinessChecks()...)
+
+	// Метрики в формате Prometheus
+	var h http.Handler = mux
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics.Registry.Handler())
+		h = middleware.MetricsMiddleware(s.metrics.Registry, "orchestrator", mux)(h)
+	}
+
+	// Логирование запросов с идентификатором запроса; опросы агентов и проверки состояния — на уровне debug
+	h = middleware.LoggingMiddleware(s.logger, "/internal/task", "/healthz", "/readyz")(h)
+	return middleware.RequestIDMiddleware(h)
+}
+
+// userAuth возвращает цепочку middleware пользовательского API: аутентификация по API-ключу,
+// ограничение частоты запросов и проверка JWT (каждое звено — если включено)
+func (s *Server) userAuth() func(http.Handler) http.Handler {
+	apiKey, rateLimit, requireUser := passThrough, passThrough, passThrough
+	if s.auth != nil {
+		apiKey = middleware.AuthenticateAPIKey(s.auth.APIKeys)
+		if s.auth.Tokens != nil {
+			requireUser = middleware.RequireUser(s.auth.Tokens, s.auth.Users)
+		}
+	}
+	if s.ipLimit != nil || s.keyLimit != nil {
+		rateLimit = middleware.RateLimitMiddleware(s.ipLimit, s.keyLimit)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return apiKey(rateLimit(requireUser(next)))
+	}
+}
+
+// ipRateLimit возвращает middleware ограничения частоты запросов по IP-адресу, если оно включено
+func (s *Server) ipRateLimit() func(http.Handler) http.Handler {
+	if s.ipLimit == nil {
+		return passThrough
+	}
+	return middleware.RateLimitMiddleware(s.ipLimit, nil)
+}
+
+// idempotent возвращает middleware повторной обработки запросов с ключом идемпотентности,
+// если задано хранилище ключей. Подключается внутри цепочки userAuth
+func (s *Server) idempotent() func(http.Handler) http.Handler {
+	if s.idempotency == nil {
+		return passThrough
+	}
+	return middleware.IdempotencyMiddleware(s.idempotency)
+}
+
+// agentAuth возвращает middleware аутентификации агентов, если задан общий секрет.
+// Если включена аутентификация пользователей, а секрет агентов не задан, внутреннее API
+// недоступно: иначе любой клиент мог бы получать задачи и подменять их результаты
+func (s *Server) agentAuth() func(http.Handler) http.Handler {
+	switch {
+	case s.auth == nil:
+		return passThrough
+	case s.auth.AgentSecret != "":
+		return middleware.RequireSecret(s.auth.AgentSecret)
+	case s.auth.Tokens != nil:
+		return agentsDisabled
+	default:
+		return passThrough
+	}
+}
+
+// adminAuth возвращает middleware аутентификации администратора.
+// Без заданного секрета администратора административное API недоступно
+func (s *Server) adminAuth() func(http.Handler) http.Handler {
+	if s.auth == nil || s.auth.AdminSecret == "" {
+		return adminDisabled
+	}
+	return middleware.RequireSecret(s.auth.AdminSecret)
+}
+
+// adminDisabled отклоняет запросы к административному API, если секрет администратора не задан
+func adminDisabled(http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, "Административное API отключено: не задан ADMIN_SECRET", http.StatusForbidden)
+	})
+}
+
+// agentsDisabled отклоняет запросы агентов, если аутентификация пользователей включена без секрета агентов
+func agentsDisabled(http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, "Внутреннее API отключено: при заданном JWT_SECRET требуется AGENT_SECRET", http.StatusForbidden)
+	})
+}
+
+// writeError отправляет ошибку в едином для всего API формате {"error": "..."}.
+// Порядок аргументов совпадает с http.Error
+func writeError(w http.ResponseWriter, message string, status int) {
+	apierrors.WriteErrorResponse(w, status, message)
+}
+
+// jsonContent задает тип содержимого application/json для обработчиков, которые пишут JSON,
+// но не указывают его тип (например, уровень логирования zap)
+func jsonContent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// passThrough — middleware, не выполняющее никаких проверок
+func passThrough(next http.Handler) http.Handler {
+	return next
+}
+
+// ownerID возвращает ID аутентифицированного пользователя или 0, если аутентификация отключена
+func ownerID(r *http.Request) int {
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return user.ID
+	}
+	return 0
 }
 
 // handleCalculate обрабатывает запрос на добавление выражения
 func (s *Server) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.rejectIfDraining(w) {
 		return
 	}
 
 	var req models.ExpressionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Некорректный JSON", http.StatusUnprocessableEntity)
+		writeError(w, "Некорректный JSON", http.StatusUnprocessableEntity)
 		return
 	}
 
 	if req.Expression == "" {
-		http.Error(w, "Выражение не может быть пустым", http.StatusUnprocessableEntity)
+		writeError(w, "Выражение не может быть пустым", http.StatusUnprocessableEntity)
 		return
 	}
 
-	// Добавляем выражение в хранилище
-	exprID, err := s.storage.AddExpression(req.Expression)
+	wait, waitRequested, err := parseWait(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Ошибка добавления выражения: %v", err), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if req.CallbackURL != "" {
+		if s.notifier == nil {
+			writeError(w, "Уведомления не поддерживаются", http.StatusUnprocessableEntity)
+			return
+		}
+		if err := s.notifier.ValidateCallbackURL(req.CallbackURL); err != nil {
+			writeError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
 	// Разбираем выражение на задачи
-	tasks, err := s.parser.ParseExpression(req.Expression)
+	tasks, value, err := s.parseExpression(r.Context(), req.Expression)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Ошибка разбора выражения: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Списываем квоту API-ключа
+	if err := s.chargeQuota(r, req.Expression, len(tasks)); err != nil {
+		writeQuotaError(w, err)
+		return
+	}
+
+	// Добавляем выражение в хранилище
+	exprID, err := s.storage.AddExpressionForOwner(ownerID(r), req.Expression)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Ошибка разбора выражения: %v", err), http.StatusUnprocessableEntity)
+		writeError(w, fmt.Sprintf("Ошибка добавления выражения: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// Регистрируем адрес уведомления до запуска задач
+	if req.CallbackURL != "" {
+		s.notifier.Register(exprID, req.CallbackURL)
+	}
+
 	// Добавляем задачи для выражения
-	if err := s.storage.AddTasks(exprID, tasks); err != nil {
-		http.Error(w, fmt.Sprintf("Ошибка добавления задач: %v", err), http.StatusInternalServerError)
+	if err := s.addTasks(exprID, tasks, value); err != nil {
+		logging.FromContext(r.Context(), s.logger).Error("Adding tasks failed", logging.ExpressionID(exprID), zap.Error(err))
+		writeError(w, fmt.Sprintf("Ошибка добавления задач: %v", err), http.StatusInternalServerError)
+		return
+	}
+	logging.FromContext(r.Context(), s.logger).Info("Expression accepted",
+		logging.ExpressionID(exprID),
+		zap.Int("tasks", len(tasks)),
+	)
+
+	// Синхронный режим: ждем завершения выражения
+	if waitRequested {
+		s.waitAndRespond(w, r, exprID, wait)
 		return
 	}
 
@@ -83,38 +371,364 @@ func (s *Server) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleCalculateBatch обрабатывает пакетную отправку выражений (JSON-массив или NDJSON)
+func (s *Server) handleCalculateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.rejectIfDraining(w) {
+		return
+	}
+
+	reqItems, err := models.DecodeBatchItems(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	if err != nil {
+		writeError(w, fmt.Sprintf("Некорректный пакет: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if len(reqItems) > maxBatchSize {
+		writeError(w, fmt.Sprintf("Пакет не может содержать больше %d выражений", maxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	items := make([]models.BatchItem, 0, len(reqItems))
+	for _, reqItem := range reqItems {
+		item := models.BatchItem{CorrelationID: reqItem.CorrelationID}
+
+		exprID, err := s.submitExpression(r, reqItem.Expression)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.ExpressionID = exprID
+		}
+
+		items = append(items, item)
+	}
+
+	batchID := s.storage.AddBatch(ownerID(r), items)
+
+	resp := models.BatchResponse{BatchID: batchID, Items: items}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/batches/%d", batchID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// submitExpression разбирает выражение и добавляет его вместе с задачами в хранилище.
+// Некорректные выражения в хранилище не попадают
+func (s *Server) submitExpression(r *http.Request, expression string) (int, error) {
+	if strings.TrimSpace(expression) == "" {
+		return 0, fmt.Errorf("выражение не может быть пустым")
+	}
+
+	tasks, value, err := s.parseExpression(r.Context(), expression)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка разбора выражения: %v", err)
+	}
+
+	if err := s.chargeQuota(r, expression, len(tasks)); err != nil {
+		return 0, err
+	}
+
+	exprID, err := s.storage.AddExpressionForOwner(ownerID(r), expression)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.addTasks(exprID, tasks, value); err != nil {
+		return 0, err
+	}
+
+	return exprID, nil
+}
+
+// addTasks добавляет задачи выражения в хранилище. Выражение без операций задач не имеет
+// и завершается сразу значением, полученным при разборе
+func (s *Server) addTasks(exprID int, tasks []models.Task, value string) error {
+	if len(tasks) > 0 {
+		return s.storage.AddTasks(exprID, tasks)
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("некорректное число %q: %w", value, err)
+	}
+	return s.storage.CompleteConstant(exprID, result)
+}
+
+// parseExpression разбирает выражение в дочернем спане запроса и передает контекст трассировки
+// в задачи, чтобы ожидание в очереди и выполнение агентом попали в ту же трассу
+func (s *Server) parseExpression(ctx context.Context, expression string) ([]models.Task, string, error) {
+	_, span := s.tracer.Start(ctx, "parse", tracing.SpanKindInternal)
+	defer span.End()
+
+	tasks, value, err := s.parser.Parse(expression)
+	if err != nil {
+		span.SetError(err.Error())
+		return nil, "", err
+	}
+	span.SetAttribute("tasks", strconv.Itoa(len(tasks)))
+
+	if sc, ok := tracing.SpanContextFromContext(ctx); ok {
+		for i := range tasks {
+			tasks[i].TraceParent = sc.Traceparent()
+		}
+	}
+
+	return tasks, value, nil
+}
+
+// chargeQuota списывает выражение с квоты API-ключа, которым аутентифицирован запрос
+func (s *Server) chargeQuota(r *http.Request, expression string, tasks int) error {
+	key, ok := auth.APIKeyFromContext(r.Context())
+	if !ok || s.auth == nil {
+		return nil
+	}
+	return s.auth.APIKeys.Charge(key.ID, len(expression), tasks)
+}
+
+// evaluateQuota списывает с квоты API-ключа выражения синхронного вычисления так же,
+// как выражения, отправленные агентам
+type evaluateQuota struct {
+	s *Server
+}
+
+// Charge списывает выражение вместе с количеством его операций. Некорректное выражение
+// не списывается: его отклонит вычислитель
+func (q evaluateQuota) Charge(r *http.Request, expression string) error {
+	tasks, _, err := q.s.parser.Parse(expression)
+	if err != nil {
+		return nil
+	}
+	return q.s.chargeQuota(r, expression, len(tasks))
+}
+
+// WriteError отправляет ответ о превышении квоты
+func (evaluateQuota) WriteError(w http.ResponseWriter, err error) {
+	writeQuotaError(w, err)
+}
+
+// writeQuotaError отправляет ответ о превышении квоты: 413 для слишком длинного выражения,
+// 429 с заголовками Retry-After и X-RateLimit-* для остальных квот
+func writeQuotaError(w http.ResponseWriter, err error) {
+	var quotaErr *auth.QuotaError
+	if !errors.As(err, &quotaErr) {
+		writeError(w, fmt.Sprintf("Ошибка: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if quotaErr.RetryAfter == 0 {
+		writeError(w, quotaErr.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	middleware.WriteRateLimitHeaders(w, quotaErr.Limit, quotaErr.Remaining, quotaErr.Reset, quotaErr.RetryAfter)
+	writeError(w, quotaErr.Error(), http.StatusTooManyRequests)
+}
+
+// handleGetBatch обрабатывает запрос на получение агрегированного статуса пакета
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/v1/batches/"))
+	if err != nil {
+		writeError(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.storage.GetBatchStatus(id, ownerID(r))
+	if err != nil {
+		writeError(w, "Пакет не найден", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// waitAndRespond ожидает завершения выражения и отправляет полный результат.
+// Если время ожидания истекло, возвращает 202 Accepted с заголовком Location
+func (s *Server) waitAndRespond(w http.ResponseWriter, r *http.Request, exprID int, wait time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	expr, err := s.storage.WaitExpression(ctx, exprID)
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/expressions/%d", exprID))
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(models.ExpressionResponse{ID: exprID})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.ExpressionDetailResponse{Expression: expr})
+}
+
+// parseWait извлекает время ожидания из параметра ?wait=10s или заголовка Prefer: wait=10.
+// Возвращает false, если синхронный режим не запрошен
+func parseWait(r *http.Request) (time.Duration, bool, error) {
+	if value := r.URL.Query().Get("wait"); value != "" {
+		wait, err := time.ParseDuration(value)
+		if err != nil {
+			// Допускаем значение в секундах без единиц измерения
+			seconds, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return 0, false, fmt.Errorf("некорректное значение wait: %s", value)
+			}
+			wait = time.Duration(seconds) * time.Second
+		}
+		return clampWait(wait)
+	}
+
+	for _, prefer := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(prefer, ",") {
+			name, value, found := strings.Cut(strings.TrimSpace(pref), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "wait") {
+				continue
+			}
+			seconds, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return 0, false, fmt.Errorf("некорректное значение Prefer: wait=%s", value)
+			}
+			return clampWait(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	return 0, false, nil
+}
+
+// clampWait проверяет время ожидания и ограничивает его сверху
+func clampWait(wait time.Duration) (time.Duration, bool, error) {
+	if wait <= 0 {
+		return 0, false, fmt.Errorf("время ожидания должно быть положительным")
+	}
+	if wait > maxWaitTimeout {
+		wait = maxWaitTimeout
+	}
+	return wait, true, nil
+}
+
 // handleGetExpressions обрабатывает запрос на получение всех выражений
 func (s *Server) handleGetExpressions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
-	expressions := s.storage.GetAllExpressions()
+	query, err := parseExpressionQuery(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.OwnerID = ownerID(r)
 
-	resp := models.ExpressionsResponse{Expressions: expressions}
+	expressions, nextCursor, err := s.storage.ListExpressions(query)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := models.ExpressionsResponse{Expressions: expressions, NextCursor: nextCursor}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
-// handleGetExpression обрабатывает запрос на получение выражения по ID
-func (s *Server) handleGetExpression(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+// parseExpressionQuery извлекает параметры фильтрации, сортировки и пагинации из URL:
+// status, created_after, created_before, q, sort, order, limit, cursor
+func parseExpressionQuery(r *http.Request) (models.ExpressionQuery, error) {
+	params := r.URL.Query()
+	query := models.ExpressionQuery{
+		Search: params.Get("q"),
+		SortBy: params.Get("sort"),
+		Cursor: params.Get("cursor"),
+	}
+
+	if value := params.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			query.Statuses = append(query.Statuses, models.Status(strings.ToUpper(strings.TrimSpace(status))))
+		}
+	}
+
+	for name, target := range map[string]*time.Time{
+		"created_after":  &query.CreatedAfter,
+		"created_before": &query.CreatedBefore,
+	} {
+		if value := params.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("некорректное значение %s: ожидается RFC 3339", name)
+			}
+			*target = t
+		}
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("некорректное направление сортировки: %s", params.Get("order"))
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("некорректное значение limit: %s", value)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// handleExpression обрабатывает запросы на получение и отмену выражения по ID
+func (s *Server) handleExpression(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Извлекаем ID из URL
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/expressions/")
+	path, sub, _ := strings.Cut(path, "/")
 	id, err := strconv.Atoi(path)
 	if err != nil {
-		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		writeError(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if sub != "" {
+			writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleCancelExpression(w, r, id)
 		return
 	}
 
+	// Чужие выражения недоступны так же, как несуществующие
 	expr, err := s.storage.GetExpression(id)
-	if err != nil {
-		http.Error(w, "Выражение не найдено", http.StatusNotFound)
+	if err != nil || ownerID(r) != 0 && expr.OwnerID != ownerID(r) {
+		writeError(w, "Выражение не найдено", http.StatusNotFound)
+		return
+	}
+
+	switch sub {
+	case "":
+	case "deliveries":
+		s.handleGetDeliveries(w, expr.ID)
+		return
+	case "tasks":
+		s.handleGetTasks(w, expr.ID)
+		return
+	default:
+		writeError(w, "Не найдено", http.StatusNotFound)
 		return
 	}
 
@@ -123,16 +737,116 @@ func (s *Server) handleGetExpression(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleCancelExpression отменяет незавершенное выражение и возвращает его итоговое состояние
+func (s *Server) handleCancelExpression(w http.ResponseWriter, r *http.Request, id int) {
+	expr, err := s.storage.CancelExpression(id, ownerID(r))
+	switch {
+	case errors.Is(err, ErrExpressionNotFound):
+		writeError(w, "Выражение не найдено", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrExpressionFinished):
+		writeError(w, "Выражение уже завершено", http.StatusConflict)
+		return
+	}
+
+	resp := models.ExpressionDetailResponse{Expression: expr}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGetDeliveries возвращает журнал доставки webhook-уведомлений для выражения
+func (s *Server) handleGetDeliveries(w http.ResponseWriter, exprID int) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	if s.notifier != nil {
+		deliveries = s.notifier.Deliveries(exprID)
+	}
+
+	resp := models.WebhookDeliveriesResponse{Deliveries: deliveries}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleGetTasks возвращает задачи выражения со стадиями выполнения
+func (s *Server) handleGetTasks(w http.ResponseWriter, exprID int) {
+	tasks, err := s.storage.ExpressionTasks(exprID, 0)
+	if err != nil {
+		writeError(w, "Выражение не найдено", http.StatusNotFound)
+		return
+	}
+
+	resp := models.ExpressionTasksResponse{Tasks: tasks}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleAgents возвращает агентов, обращавшихся к оркестратору или выполняющих задачи
+func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := models.AgentsResponse{Agents: s.Agents()}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleStorageStats возвращает сведения о размере хранилища
+func (s *Server) handleStorageStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.storage.Stats())
+}
+
+// handleRetentionSweep немедленно запускает очистку хранилища
+func (s *Server) handleRetentionSweep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.sweeper == nil {
+		writeError(w, "Политика хранения не настроена", http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.sweeper.Sweep())
+}
+
 // handleTask обрабатывает запросы агентов
 func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	s.recordAgent(r)
+
 	switch r.Method {
 	case http.MethodGet:
-		// Получение задачи
-		task, err := s.storage.GetReadyTask()
+		// При остановке новые задачи не выдаются, результаты выданных принимаются
+		if s.stopping.Load() {
+			w.Header().Set("Retry-After", "30")
+			writeError(w, "Оркестратор останавливается", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Получение задачи: она выдается агенту, указанному в заголовке
+		agentID := r.Header.Get(models.AgentIDHeader)
+		task, err := s.storage.GetReadyTaskForAgent(agentID)
+
+		// Размер оставшейся очереди помогает агенту подобрать количество воркеров
+		w.Header().Set(models.QueueDepthHeader, strconv.Itoa(s.storage.ReadyQueueDepth()))
 		if err != nil {
-			http.Error(w, "Нет доступных задач", http.StatusNotFound)
+			writeError(w, "Нет доступных задач", http.StatusNotFound)
 			return
 		}
+		s.logger.Debug("Task issued",
+			logging.TaskID(task.ID),
+			logging.ExpressionID(task.ExpressionID),
+			logging.AgentID(agentID),
+			zap.String("operation", string(task.Operation)),
+		)
 
 		resp := models.TaskResponse{Task: task}
 		w.Header().Set("Content-Type", "application/json")
@@ -142,22 +856,43 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 		// Получение результата задачи
 		var req models.TaskResultRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Некорректный JSON", http.StatusUnprocessableEntity)
+			writeError(w, "Некорректный JSON", http.StatusUnprocessableEntity)
 			return
 		}
 
-		if err := s.storage.UpdateTaskResult(req.ID, req.Result, req.Error); err != nil {
-			if strings.Contains(err.Error(), "не найдена") {
-				http.Error(w, "Задача не найдена", http.StatusNotFound)
-			} else {
-				http.Error(w, fmt.Sprintf("Ошибка: %v", err), http.StatusInternalServerError)
-			}
+		agentID := r.Header.Get(models.AgentIDHeader)
+		fields := []zap.Field{logging.TaskID(req.ID), logging.AgentID(agentID)}
+		if err := s.storage.AcceptTaskResult(agentID, req.ID, req.Result, req.Error); err != nil {
+			s.logger.Warn("Task result rejected", append(fields, zap.Error(err))...)
+			writeError(w, err.Error(), resultErrorStatus(err))
 			return
 		}
+		if req.Error != "" {
+			fields = append(fields, zap.String("error", req.Error))
+		}
+		s.logger.Debug("Task result received", fields...)
 
 		w.WriteHeader(http.StatusOK)
 
 	default:
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		writeError(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// resultErrorStatus возвращает код ответа для отклоненного результата задачи
+func resultErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrTaskNotLeased):
+		return http.StatusForbidden
+	case errors.Is(err, ErrDuplicateResult):
+		return http.StatusConflict
+	case errors.Is(err, ErrLateResult):
+		return http.StatusGone
+	case errors.Is(err, ErrInvalidResult):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
 	}
 }
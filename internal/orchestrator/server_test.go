@@ -0,0 +1,615 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestServer создает сервер оркестратора с минимальными временами операций
+func newTestServer() (*Server, *Storage) {
+	storage := NewStorage()
+	parser := NewParser(OperationTimes{Addition: 1, Subtraction: 1, Multiplication: 1, Division: 1})
+	return NewServer(storage, parser), storage
+}
+
+// completeAllTasks выполняет все готовые задачи, имитируя работу агента
+func completeAllTasks(storage *Storage) {
+	for {
+		task, err := storage.GetReadyTask()
+		if err != nil {
+			return
+		}
+		result, err := calcTask(task)
+		if err != nil {
+			storage.UpdateTaskResult(task.ID, 0, err.Error())
+			continue
+		}
+		storage.UpdateTaskResult(task.ID, result, "")
+	}
+}
+
+// calcTask вычисляет результат задачи без задержек
+func calcTask(task *models.Task) (float64, error) {
+	arg1, err := strconv.ParseFloat(task.Arg1, 64)
+	if err != nil {
+		return 0, err
+	}
+	arg2, err := strconv.ParseFloat(task.Arg2, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch task.Operation {
+	case models.OperationAdd:
+		return arg1 + arg2, nil
+	case models.OperationSubtract:
+		return arg1 - arg2, nil
+	case models.OperationMultiply:
+		return arg1 * arg2, nil
+	default:
+		if arg2 == 0 {
+			return 0, fmt.Errorf("деление на ноль")
+		}
+		return arg1 / arg2, nil
+	}
+}
+
+// TestServer_CalculateWait проверяет синхронное ожидание результата
+func TestServer_CalculateWait(t *testing.T) {
+	server, storage := newTestServer()
+	handler := server.SetupRoutes()
+
+	// Имитируем агента, выполняющего задачи в фоне
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				completeAllTasks(storage)
+			}
+		}
+	}()
+
+	body, _ := json.Marshal(models.ExpressionRequest{Expression: "2+2*2"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate?wait=5s", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var resp models.ExpressionDetailResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if resp.Expression.Status != models.StatusCompleted {
+		t.Errorf("Status = %v, want %v", resp.Expression.Status, models.StatusCompleted)
+	}
+	if resp.Expression.Result == nil || *resp.Expression.Result != "6" {
+		t.Errorf("Result = %v, want 6", resp.Expression.Result)
+	}
+}
+
+// TestServer_CalculateWaitTimeout проверяет ответ 202 при истечении времени ожидания
+func TestServer_CalculateWaitTimeout(t *testing.T) {
+	server, _ := newTestServer()
+	handler := server.SetupRoutes()
+
+	body, _ := json.Marshal(models.ExpressionRequest{Expression: "1+1"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+	req.Header.Set("Prefer", "respond-async, wait=1")
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("ответ получен через %v, ожидалось не менее 1s", elapsed)
+	}
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+	if loc := rr.Header().Get("Location"); loc != "/api/v1/expressions/1" {
+		t.Errorf("Location = %q, want %q", loc, "/api/v1/expressions/1")
+	}
+}
+
+// TestServer_CalculateBatch проверяет пакетную отправку и агрегированный статус
+func TestServer_CalculateBatch(t *testing.T) {
+	server, storage := newTestServer()
+	handler := server.SetupRoutes()
+
+	body := `[{"correlation_id":"a","expression":"2+2"},{"correlation_id":"b","expression":"2++2"},{"correlation_id":"c","expression":"3*3"}]`
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate/batch", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	var resp models.BatchResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Items) != 3 {
+		t.Fatalf("len(Items) = %d, want 3", len(resp.Items))
+	}
+	if resp.Items[0].ExpressionID == 0 || resp.Items[2].ExpressionID == 0 {
+		t.Errorf("ожидались ID для корректных выражений: %+v", resp.Items)
+	}
+	if resp.Items[1].Error == "" || resp.Items[1].ExpressionID != 0 {
+		t.Errorf("ожидалась ошибка разбора: %+v", resp.Items[1])
+	}
+
+	getStatus := func() models.BatchStatusResponse {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/batches/%d", resp.BatchID), nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+		}
+		var status models.BatchStatusResponse
+		json.Unmarshal(rr.Body.Bytes(), &status)
+		return status
+	}
+
+	if status := getStatus(); status.Status != models.StatusProcessing || status.Rejected != 1 {
+		t.Errorf("status = %+v, want PROCESSING with 1 rejected", status)
+	}
+
+	completeAllTasks(storage)
+
+	status := getStatus()
+	if status.Status != models.StatusCompleted || status.Counts[models.StatusCompleted] != 2 {
+		t.Errorf("status = %+v, want COMPLETED with 2 completed", status)
+	}
+	if status.Items[2].Result == nil || *status.Items[2].Result != "9" {
+		t.Errorf("Items[2].Result = %v, want 9", status.Items[2].Result)
+	}
+}
+
+// TestServer_ConstantExpression проверяет, что выражение без операций завершается сразу,
+// а выражение с унарным минусом отклоняется при разборе
+func TestServer_ConstantExpression(t *testing.T) {
+	server, storage := newTestServer()
+	handler := server.SetupRoutes()
+
+	submit := func(expr string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExpressionRequest{Expression: expr})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := submit("((5))")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+	var resp models.ExpressionResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if expr, _ := storage.GetExpression(resp.ID); expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != "5" {
+		t.Errorf("expression = %+v, want completed with 5", expr)
+	}
+
+	if rr := submit("(-(5))"); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unary minus: status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+// TestServer_Auth проверяет аутентификацию и разграничение выражений между пользователями
+func TestServer_Auth(t *testing.T) {
+	server, _ := newTestServer()
+	server.SetAuthenticator(auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret"))
+	handler := server.SetupRoutes()
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	login := func(name string) string {
+		creds := fmt.Sprintf(`{"login":%q,"password":"password123"}`, name)
+		if rr := do(http.MethodPost, "/api/v1/register", "", creds); rr.Code != http.StatusCreated {
+			t.Fatalf("register status = %d, want %d", rr.Code, http.StatusCreated)
+		}
+		rr := do(http.MethodPost, "/api/v1/login", "", creds)
+		var resp auth.LoginResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.Token
+	}
+
+	alice, bob := login("alice"), login("bob")
+
+	// Без токена API недоступно
+	if rr := do(http.MethodPost, "/api/v1/calculate", "", `{"expression":"1+1"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	if rr := do(http.MethodPost, "/api/v1/calculate", alice, `{"expression":"1+1"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	// Выражение видно только владельцу
+	if rr := do(http.MethodGet, "/api/v1/expressions/1", bob, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("чужое выражение: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := do(http.MethodGet, "/api/v1/expressions/1", alice, ""); rr.Code != http.StatusOK {
+		t.Errorf("свое выражение: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var list models.ExpressionsResponse
+	json.Unmarshal(do(http.MethodGet, "/api/v1/expressions", bob, "").Body.Bytes(), &list)
+	if len(list.Expressions) != 0 {
+		t.Errorf("bob видит %d выражений, ожидалось 0", len(list.Expressions))
+	}
+
+	// Внутреннее API требует секрет агента
+	if rr := do(http.MethodGet, "/internal/task", alice, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("internal с JWT: status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := do(http.MethodGet, "/internal/task", "agent-secret", ""); rr.Code != http.StatusOK {
+		t.Errorf("internal с секретом: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	// Административное API требует секрет администратора
+	if rr := do(http.MethodGet, "/admin/v1/storage", "agent-secret", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("admin с секретом агента: status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := do(http.MethodGet, "/admin/v1/storage", "admin-secret", ""); rr.Code != http.StatusOK {
+		t.Errorf("admin с секретом: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+// TestServer_AuthHardening проверяет ограничение частоты входа и отказ без секрета при TLS
+func TestServer_AuthHardening(t *testing.T) {
+	server, _ := newTestServer()
+	server.SetAuthenticator(auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret"))
+	server.SetRateLimiters(ratelimit.NewLimiter(1, 2), nil)
+	handler := server.SetupRoutes()
+
+	// Попытки входа с одного IP-адреса ограничиваются
+	creds := `{"login":"alice","password":"wrong-password"}`
+	var codes []int
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(creds)))
+		codes = append(codes, rr.Code)
+	}
+	if codes[0] != http.StatusUnauthorized || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("login statuses = %v, want 401 first and 429 last", codes)
+	}
+
+	// Проверенный клиентский сертификат не заменяет секрет агента
+	req := httptest.NewRequest(http.MethodGet, "/internal/task", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("internal с сертификатом: status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	// Токен, выпущенный до перезапуска, не открывает доступ к пользователю с тем же ID
+	before := auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret")
+	victim, _ := before.Users.Register("victim", "password123")
+	staleToken, _, _ := before.Tokens.Issue(victim)
+	after, _ := newTestServer()
+	restarted := auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret")
+	if user, _ := restarted.Users.Register("mallory", "password123"); user.ID != victim.ID {
+		t.Fatalf("IDs should repeat after restart: %d != %d", user.ID, victim.ID)
+	}
+	after.SetAuthenticator(restarted)
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/expressions", nil)
+	req.Header.Set("Authorization", "Bearer "+staleToken)
+	rr = httptest.NewRecorder()
+	after.SetupRoutes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("токен до перезапуска: status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	// При включенной аутентификации пользователей без секрета агентов внутреннее API закрыто
+	noAgentSecret, _ := newTestServer()
+	noAgentSecret.SetAuthenticator(auth.NewAuthenticator("jwt-secret", time.Hour, "", "admin-secret"))
+	rr = httptest.NewRecorder()
+	noAgentSecret.SetupRoutes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/internal/task", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("internal без AGENT_SECRET: status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+// TestServer_APIKeyQuota проверяет выпуск API-ключей и соблюдение квот
+func TestServer_APIKeyQuota(t *testing.T) {
+	server, _ := newTestServer()
+	server.SetAuthenticator(auth.NewAuthenticator("", time.Hour, "", "admin-secret"))
+	handler := server.SetupRoutes()
+
+	// Выпускаем ключ с квотой в 2 выражения в минуту и 3 задачи в сутки
+	body := `{"name":"batch-jobs","quota":{"expressions_per_minute":2,"tasks_per_day":3,"max_expression_length":10}}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/v1/api-keys", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var issued auth.IssueAPIKeyResponse
+	json.Unmarshal(rr.Body.Bytes(), &issued)
+
+	submit := func(key, expr string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExpressionRequest{Expression: expr})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := submit("ak_unknown", "1+1"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("неизвестный ключ: status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := submit(issued.Key, "1+1+1+1+1+1"); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("длинное выражение: status = %d, want %d", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+	if rr := submit(issued.Key, "1+1"); rr.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	// Выражение из трех задач превышает суточную квоту задач
+	rr = submit(issued.Key, "1+1+1+1")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("квота задач: status = %d, Retry-After = %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	if rr := submit(issued.Key, "2+2"); rr.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	// Третье выражение за минуту превышает квоту выражений
+	rr = submit(issued.Key, "3")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("квота выражений: status = %d, X-RateLimit-Limit = %q", rr.Code, rr.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+// TestServer_EvaluateQuota проверяет, что синхронное вычисление расходует квоту API-ключа
+func TestServer_EvaluateQuota(t *testing.T) {
+	server, _ := newTestServer()
+	authenticator := auth.NewAuthenticator("", time.Hour, "", "admin-secret")
+	server.SetAuthenticator(authenticator)
+	handler := server.SetupRoutes()
+	_, key, err := authenticator.APIKeys.Issue("sync", 0, auth.Quota{ExpressionsPerMinute: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := post("/api/v1/evaluate", `{"expression":"1+1"}`); rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// Второе выражение пакета превышает квоту и отклоняется без вычисления
+	rr := post("/api/v1/evaluate/batch", `[{"correlation_id":"a","expression":"2+2"},{"correlation_id":"b","expression":"3+3"}]`)
+	var batch struct {
+		Results []struct {
+			Result string `json:"result"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &batch)
+	if rr.Code != http.StatusOK || len(batch.Results) != 2 || batch.Results[0].Result != "4" || batch.Results[1].Error == "" {
+		t.Errorf("batch: status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+
+	rr = post("/api/v1/evaluate", `{"expression":"4+4"}`)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("квота исчерпана: status = %d, Retry-After = %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestServer_RuntimeAdmin(t *testing.T) {
+	server, storage := newTestServer()
+	server.SetLogLevel(zap.NewAtomicLevelAt(zap.InfoLevel))
+
+	// Без секрета администратора административное API недоступно
+	rr := httptest.NewRecorder()
+	server.SetupRoutes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/v1/storage", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("admin без секрета: status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	server.SetAuthenticator(auth.NewAuthenticator("", time.Hour, "", "admin-secret"))
+	handler := server.SetupRoutes()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Частичное обновление времени операций применяется к новым задачам
+	rr = do(http.MethodPut, "/admin/v1/operation-times", `{"multiplication_ms": 750}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("operation-times: status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	want := OperationTimes{Addition: 1, Subtraction: 1, Multiplication: 750, Division: 1}
+	if got := server.parser.OperationTimes(); got != want {
+		t.Errorf("operation times = %+v, want %+v", got, want)
+	}
+	if rr := do(http.MethodPost, "/api/v1/calculate", `{"expression": "2*3"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("calculate: status = %d", rr.Code)
+	}
+	if task, err := storage.GetReadyTask(); err != nil || task.OperationTime != 750 {
+		t.Errorf("task = %+v, err = %v; want operation time 750", task, err)
+	}
+
+	if rr := do(http.MethodPut, "/admin/v1/operation-times", `{"division_ms": -1}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("negative time: status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	// Уровень логирования
+	if rr := do(http.MethodPut, "/admin/v1/log-level", `{"level": "debug"}`); rr.Code != http.StatusOK {
+		t.Errorf("log-level: status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if !server.logLevel.Enabled(zap.DebugLevel) {
+		t.Error("debug level should be enabled")
+	}
+
+	// В режиме вывода из эксплуатации новые выражения отклоняются, а задачи выдаются
+	rr = do(http.MethodPut, "/admin/v1/drain", `{"draining": true}`)
+	var status DrainStatus
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil || !status.Draining || status.ActiveExpressions != 1 {
+		t.Fatalf("drain: status = %+v, err = %v", status, err)
+	}
+	if rr := do(http.MethodPost, "/api/v1/calculate", `{"expression": "1+1"}`); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("calculate while draining: status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr := do(http.MethodPost, "/internal/task", `{"id": 1, "result": 6}`); rr.Code != http.StatusOK {
+		t.Errorf("task result while draining: status = %d", rr.Code)
+	}
+
+	rr = do(http.MethodGet, "/admin/v1/drain", "")
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil || status.ActiveExpressions != 0 {
+		t.Errorf("drain after completion: status = %+v, err = %v", status, err)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server, storage := newTestServer()
+	handler := server.SetupRoutes()
+
+	exprID, _ := storage.AddExpression("1+2")
+	tasks, _ := server.parser.ParseExpression("1+2")
+	storage.AddTasks(exprID, tasks)
+	task, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Если агент не успел до истечения срока, остановка сообщает об этом, а задача остается выданной
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		t.Error("Shutdown should report the deadline")
+	}
+	if storage.InFlightTasks() != 1 || storage.ReadyQueueDepth() != 0 {
+		t.Errorf("in flight = %d, ready = %d; want 1 and 0", storage.InFlightTasks(), storage.ReadyQueueDepth())
+	}
+
+	// Новые задачи и выражения не выдаются и не принимаются, результаты принимаются
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/internal/task", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("task poll: status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(`{"expression": "1+1"}`)))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("calculate: status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/internal/task", strings.NewReader(fmt.Sprintf(`{"id": %d, "result": 3}`, task.ID))))
+	if rr.Code != http.StatusOK {
+		t.Errorf("task result: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	// Без выданных задач остановка завершается сразу
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
+
+func TestServer_Readiness(t *testing.T) {
+	server, _ := newTestServer()
+	server.SetReadiness(1, time.Minute)
+	handler := server.SetupRoutes()
+
+	ready := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rr.Code
+	}
+
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("without agents: status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// Опрос задач отмечает агента активным
+	req := httptest.NewRequest(http.MethodGet, "/internal/task", nil)
+	req.Header.Set(models.AgentIDHeader, "agent-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if code := ready(); code != http.StatusOK {
+		t.Errorf("with agent: status = %d, want %d", code, http.StatusOK)
+	}
+
+	server.SetDraining(true)
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("draining: status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+// TestServer_UnifiedAPI проверяет синхронный и асинхронный режимы, единый формат ошибок
+// и веб-интерфейс на одном сервере
+func TestServer_UnifiedAPI(t *testing.T) {
+	server, _ := newTestServer()
+	handler := server.SetupRoutes()
+
+	tests := []struct {
+		name, method, path, body string
+		wantStatus               int
+		wantBody                 string
+	}{
+		{"async", http.MethodPost, "/api/v1/calculate", `{"expression": "2+2*2"}`, http.StatusCreated, `"id":1`},
+		{"sync", http.MethodPost, "/api/v1/evaluate", `{"expression": "2+2*2"}`, http.StatusOK, `"result":"6"`},
+		{"sync batch", http.MethodPost, "/api/v1/evaluate/batch", `[{"correlation_id": "a", "expression": "1/0"}]`, http.StatusOK, `"error":"Division by zero"`},
+		{"async error", http.MethodPost, "/api/v1/calculate", `{"expression": ""}`, http.StatusUnprocessableEntity, `"error":"Выражение не может быть пустым"`},
+		{"sync error", http.MethodGet, "/api/v1/evaluate", "", http.StatusMethodNotAllowed, `"error":"Unsupported HTTP method"`},
+		{"not found", http.MethodGet, "/api/v1/expressions/42", "", http.StatusNotFound, `"error":`},
+		{"web interface", http.MethodGet, "/static/", "", http.StatusOK, "<html"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.wantStatus || !strings.Contains(rr.Body.String(), tt.wantBody) {
+			t.Errorf("%s: %d %s, want %d with %s", tt.name, rr.Code, rr.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+		if rr.Code >= 400 && rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: error Content-Type = %q", tt.name, rr.Header().Get("Content-Type"))
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/static/index.html" {
+		t.Errorf("root: %d, Location = %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
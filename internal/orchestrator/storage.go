@@ -1,10 +1,17 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"slices"
 	"strconv"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 // Storage представляет хранилище выражений и задач
@@ -16,8 +23,28 @@ type Storage struct {
 	mutex            sync.RWMutex              // Мьютекс для защиты данных
 	exprTasksMapping map[int][]int             // Связь выражений с задачами
 	resultCache      map[string]float64        // Кеш результатов задач
+	waiters          map[int][]chan struct{}   // Ожидающие завершения выражений
+	listeners        []func(models.Expression) // Подписчики на завершение выражений
+	batches          map[int]models.Batch      // Хранилище пакетов выражений
+	batchCounter     int                       // Счетчик для ID пакетов
+	purgeListeners   []func(ids []int)         // Подписчики на удаление выражений
+	lastPurge        *PurgeStats               // Результат последней очистки
+	totalPurged      PurgeStats                // Суммарно удалено с момента запуска
+	cacheEnabled     bool                      // Повторное использование результатов одинаковых задач
+	auditEnabled     bool                      // Выполнение каждой задачи двумя агентами со сверкой результатов
+	auditResults     map[int]auditResult       // Первые результаты задач, ожидающие сверки
+	auditTimeout     time.Duration             // Время ожидания второго результата (0 — бессрочно)
+	leaseTimeout     time.Duration             // Срок аренды задачи агентом (0 — бессрочно)
+	metrics          *Metrics                  // Метрики хранилища (может быть nil)
+	tracer           *tracing.Tracer           // Трассировщик задач (может быть nil)
+	taskSpans        map[int]*tracing.Span     // Незавершенные спаны выполнения задач
+	logger           *zap.Logger               // Логгер хранилища и связанных компонентов
 }
 
+// maxResultCacheSize — максимальное число записей в кеше результатов;
+// при превышении кеш очищается целиком
+const maxResultCacheSize = 10000
+
 // NewStorage создает новое хранилище
 func NewStorage() *Storage {
 	return &Storage{
@@ -27,29 +54,116 @@ func NewStorage() *Storage {
 		taskCounter:      0,
 		exprTasksMapping: make(map[int][]int),
 		resultCache:      make(map[string]float64),
+		waiters:          make(map[int][]chan struct{}),
+		batches:          make(map[int]models.Batch),
+		taskSpans:        make(map[int]*tracing.Span),
+		auditResults:     make(map[int]auditResult),
+		logger:           zap.NewNop(),
 	}
 }
 
-// AddExpression добавляет новое выражение в хранилище
-func (s *Storage) AddExpression(expr string) (int, error) {
+// SetMetrics подключает сбор метрик хранилища
+func (s *Storage) SetMetrics(metrics *Metrics) {
+	s.metrics = metrics
+}
+
+// SetLogger задает логгер хранилища. Нотификатор и сборщик используют логгер хранилища
+func (s *Storage) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
+// Logger возвращает логгер хранилища
+func (s *Storage) Logger() *zap.Logger {
+	return s.logger
+}
+
+// SetTracer включает запись спанов ожидания задач в очереди и их выполнения
+func (s *Storage) SetTracer(tracer *tracing.Tracer) {
+	s.tracer = tracer
+}
+
+// EnableResultCache включает повторное использование результатов задач
+// с одинаковыми операцией и аргументами
+func (s *Storage) EnableResultCache(enabled bool) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.cacheEnabled = enabled
+}
+
+// SetLeaseTimeout задает срок, после которого задача, не вернувшая результат, возвращается
+// в очередь и может быть выдана другому агенту. 0 отключает ограничение
+func (s *Storage) SetLeaseTimeout(timeout time.Duration) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.leaseTimeout = timeout
+}
+
+// lock захватывает мьютекс на запись и фиксирует время ожидания при конкуренции
+func (s *Storage) lock() {
+	if s.mutex.TryLock() {
+		return
+	}
+	start := time.Now()
 	s.mutex.Lock()
+	s.metrics.observeLockWait("write", time.Since(start))
+}
+
+// rlock захватывает мьютекс на чтение и фиксирует время ожидания при конкуренции
+func (s *Storage) rlock() {
+	if s.mutex.TryRLock() {
+		return
+	}
+	start := time.Now()
+	s.mutex.RLock()
+	s.metrics.observeLockWait("read", time.Since(start))
+}
+
+// AddExpression добавляет новое выражение без владельца в хранилище
+func (s *Storage) AddExpression(expr string) (int, error) {
+	return s.AddExpressionForOwner(0, expr)
+}
+
+// AddExpressionForOwner добавляет новое выражение пользователя в хранилище
+func (s *Storage) AddExpressionForOwner(ownerID int, expr string) (int, error) {
+	s.lock()
 	defer s.mutex.Unlock()
 
 	s.exprCounter++
 	id := s.exprCounter
 
 	s.expressions[id] = models.Expression{
-		ID:      id,
-		RawExpr: expr,
-		Status:  models.StatusPending,
+		ID:        id,
+		RawExpr:   expr,
+		Status:    models.StatusPending,
+		CreatedAt: time.Now().UTC(),
+		OwnerID:   ownerID,
 	}
 
 	return id, nil
 }
 
+// Ping проверяет, что хранилище отвечает: блокировку удается получить до отмены ctx
+func (s *Storage) Ping(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.rlock()
+		s.mutex.RUnlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("хранилище не отвечает: %w", ctx.Err())
+	}
+}
+
 // GetExpression возвращает выражение по ID
 func (s *Storage) GetExpression(id int) (models.Expression, error) {
-	s.mutex.RLock()
+	s.rlock()
 	defer s.mutex.RUnlock()
 
 	expr, exists := s.expressions[id]
@@ -60,9 +174,23 @@ func (s *Storage) GetExpression(id int) (models.Expression, error) {
 	return expr, nil
 }
 
+// ActiveExpressions возвращает количество выражений, вычисление которых еще не завершено
+func (s *Storage) ActiveExpressions() int {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	active := 0
+	for _, expr := range s.expressions {
+		if !expr.Status.IsTerminal() {
+			active++
+		}
+	}
+	return active
+}
+
 // GetAllExpressions возвращает все выражения
 func (s *Storage) GetAllExpressions() []models.Expression {
-	s.mutex.RLock()
+	s.rlock()
 	defer s.mutex.RUnlock()
 
 	result := make([]models.Expression, 0, len(s.expressions))
@@ -73,9 +201,154 @@ func (s *Storage) GetAllExpressions() []models.Expression {
 	return result
 }
 
+// WaitExpression блокируется до перехода выражения в окончательный статус
+// или до отмены контекста и возвращает текущее состояние выражения
+func (s *Storage) WaitExpression(ctx context.Context, id int) (models.Expression, error) {
+	s.lock()
+	expr, exists := s.expressions[id]
+	if !exists {
+		s.mutex.Unlock()
+		return models.Expression{}, fmt.Errorf("выражение с ID %d не найдено", id)
+	}
+	if expr.Status.IsTerminal() {
+		s.mutex.Unlock()
+		return expr, nil
+	}
+
+	ch := make(chan struct{})
+	s.waiters[id] = append(s.waiters[id], ch)
+	s.mutex.Unlock()
+
+	select {
+	case <-ch:
+		return s.GetExpression(id)
+	case <-ctx.Done():
+		s.removeWaiter(id, ch)
+		expr, err := s.GetExpression(id)
+		if err != nil {
+			return expr, err
+		}
+		if expr.Status.IsTerminal() {
+			return expr, nil
+		}
+		return expr, ctx.Err()
+	}
+}
+
+// removeWaiter удаляет канал ожидания, если выражение еще не завершено
+func (s *Storage) removeWaiter(id int, ch chan struct{}) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	chans := s.waiters[id]
+	for i, c := range chans {
+		if c == ch {
+			s.waiters[id] = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(s.waiters[id]) == 0 {
+		delete(s.waiters, id)
+	}
+}
+
+// AddCompletionListener регистрирует функцию, вызываемую при переходе
+// выражения в окончательный статус. Функция вызывается в отдельной горутине
+func (s *Storage) AddCompletionListener(listener func(models.Expression)) {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// notifyCompletion оповещает ожидающих и подписчиков о завершении выражения.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) notifyCompletion(exprID int) {
+	for _, ch := range s.waiters[exprID] {
+		close(ch)
+	}
+	delete(s.waiters, exprID)
+
+	expr := s.expressions[exprID]
+	fields := []zap.Field{logging.ExpressionID(exprID), zap.String("status", string(expr.Status))}
+	if duration, ok := expr.Duration(); ok {
+		fields = append(fields, zap.Duration("duration", duration))
+	}
+	if expr.ErrorMsg != "" {
+		fields = append(fields, zap.String("error", expr.ErrorMsg))
+	}
+	s.logger.Info("Expression finished", fields...)
+
+	for _, listener := range s.listeners {
+		go listener(expr)
+	}
+}
+
+// AddBatch сохраняет пакет выражений пользователя и возвращает его ID
+func (s *Storage) AddBatch(ownerID int, items []models.BatchItem) int {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	s.batchCounter++
+	id := s.batchCounter
+
+	s.batches[id] = models.Batch{
+		ID:        id,
+		Items:     items,
+		OwnerID:   ownerID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	return id
+}
+
+// GetBatchStatus возвращает агрегированный статус пакета.
+// Если ownerID не равен 0, пакет должен принадлежать этому пользователю
+func (s *Storage) GetBatchStatus(id, ownerID int) (models.BatchStatusResponse, error) {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	batch, exists := s.batches[id]
+	if !exists || ownerID != 0 && batch.OwnerID != ownerID {
+		return models.BatchStatusResponse{}, fmt.Errorf("пакет с ID %d не найден", id)
+	}
+
+	resp := models.BatchStatusResponse{
+		ID:     id,
+		Status: models.StatusCompleted,
+		Total:  len(batch.Items),
+		Counts: make(map[models.Status]int),
+		Items:  make([]models.BatchItemStatus, 0, len(batch.Items)),
+	}
+
+	for _, item := range batch.Items {
+		itemStatus := models.BatchItemStatus{
+			CorrelationID: item.CorrelationID,
+			ExpressionID:  item.ExpressionID,
+			Error:         item.Error,
+		}
+
+		if item.Error != "" {
+			resp.Rejected++
+		} else if expr, exists := s.expressions[item.ExpressionID]; exists {
+			itemStatus.Status = expr.Status
+			itemStatus.Result = expr.Result
+			itemStatus.Error = expr.ErrorMsg
+			resp.Counts[expr.Status]++
+			if !expr.Status.IsTerminal() {
+				resp.Status = models.StatusProcessing
+			}
+		}
+
+		resp.Items = append(resp.Items, itemStatus)
+	}
+
+	return resp, nil
+}
+
 // AddTasks добавляет задачи для выражения
 func (s *Storage) AddTasks(exprID int, tasks []models.Task) error {
-	s.mutex.Lock()
+	s.lock()
 	defer s.mutex.Unlock()
 
 	_, exists := s.expressions[exprID]
@@ -131,6 +404,9 @@ func (s *Storage) AddTasks(exprID int, tasks []models.Task) error {
 		}
 
 		task.IsReady = len(task.Dependencies) == 0
+		if task.IsReady {
+			task.ReadyAt = time.Now()
+		}
 		s.tasks[taskID] = task
 	}
 
@@ -144,71 +420,287 @@ func (s *Storage) AddTasks(exprID int, tasks []models.Task) error {
 	return nil
 }
 
-// GetReadyTask возвращает задачу, готовую к выполнению
+// CompleteConstant завершает выражение без операций: его значение известно уже при разборе,
+// и агентам выдавать нечего
+func (s *Storage) CompleteConstant(exprID int, value float64) error {
+	s.lock()
+	defer s.mutex.Unlock()
+
+	expr, exists := s.expressions[exprID]
+	if !exists {
+		return fmt.Errorf("выражение с ID %d не найдено", exprID)
+	}
+
+	now := time.Now().UTC()
+	result := fmt.Sprintf("%g", value)
+	expr.Status = models.StatusCompleted
+	expr.Result = &result
+	expr.StartedAt = &now
+	expr.CompletedAt = &now
+	s.expressions[exprID] = expr
+	s.exprTasksMapping[exprID] = []int{}
+	s.notifyCompletion(exprID)
+
+	return nil
+}
+
+// GetReadyTask возвращает задачу, готовую к выполнению, агенту без идентификатора
 func (s *Storage) GetReadyTask() (*models.Task, error) {
-	s.mutex.Lock()
+	return s.GetReadyTaskForAgent("")
+}
+
+// GetReadyTaskForAgent возвращает задачу, готовую к выполнению, и выдает ее агенту agentID.
+// Результат задачи принимается только от агента, которому она выдана
+func (s *Storage) GetReadyTaskForAgent(agentID string) (*models.Task, error) {
+	s.lock()
 	defer s.mutex.Unlock()
 
-	for id, task := range s.tasks {
-		if task.IsReady && task.Result == nil {
+	now := time.Now()
+	s.expireLeases(now)
+	s.expireAudits(now)
+
+	// В режиме аудита сначала выдаем второму агенту задачи, которые выполняет только один
+	if s.auditEnabled {
+		if task := s.issueReplica(agentID, now); task != nil {
+			s.metrics.observePoll(true)
+			return task, nil
+		}
+	}
+
+	// После завершения задачи из кеша просмотр начинается заново,
+	// так как могли стать готовыми зависящие от нее задачи
+scan:
+	for {
+		for id, task := range s.tasks {
+			if !task.IsReady || task.Result != nil {
+				continue
+			}
+
 			// Помечаем задачу как "в процессе"
 			task.IsReady = false
+			task.IssuedAt = now
+			task.LeasedUntil = s.leaseDeadline(now)
+			task.Leases = []string{agentID}
 			s.tasks[id] = task
 
-			// Копируем задачу для возврата
-			taskToReturn := task
-
-			// Заменяем ссылки на результаты на их значения
-			if len(taskToReturn.Arg1) > 4 && taskToReturn.Arg1[:4] == "res:" {
-				taskIDStr := taskToReturn.Arg1[4:]
-				taskID, err := strconv.Atoi(taskIDStr)
-				if err == nil {
-					// Ищем задачу с этим ID, проверка на ExpressionID убрана
-					depTask, exists := s.tasks[taskID]
-					if exists && depTask.Result != nil {
-						taskToReturn.Arg1 = fmt.Sprintf("%f", *depTask.Result)
-					}
-				}
+			// Отмечаем время начала вычисления выражения
+			if expr, exists := s.expressions[task.ExpressionID]; exists && expr.StartedAt == nil {
+				startedAt := now.UTC()
+				expr.StartedAt = &startedAt
+				s.expressions[task.ExpressionID] = expr
 			}
 
-			if len(taskToReturn.Arg2) > 4 && taskToReturn.Arg2[:4] == "res:" {
-				taskIDStr := taskToReturn.Arg2[4:]
-				taskID, err := strconv.Atoi(taskIDStr)
-				if err == nil {
-					// Ищем задачу с этим ID, проверка на ExpressionID убрана
-					depTask, exists := s.tasks[taskID]
-					if exists && depTask.Result != nil {
-						taskToReturn.Arg2 = fmt.Sprintf("%f", *depTask.Result)
-					}
+			// Копируем задачу для возврата и заменяем ссылки на результаты на их значения
+			taskToReturn := task
+			taskToReturn.Arg1 = s.resolveArg(task.Arg1)
+			taskToReturn.Arg2 = s.resolveArg(task.Arg2)
+
+			// Задачу с уже известным результатом завершаем без агента
+			if s.cacheEnabled {
+				if result, hit := s.resultCache[cacheKey(taskToReturn)]; hit {
+					s.metrics.observeCache(true)
+					task.Result = &result
+					s.tasks[id] = task
+					s.updateDependencies(id)
+					s.checkExpressionCompletion(task.ExpressionID)
+					continue scan
 				}
+				s.metrics.observeCache(false)
 			}
 
+			s.metrics.observePoll(true)
+			s.metrics.observeTaskWait(task, now)
+			taskToReturn.TraceParent = s.traceIssue(task, now)
 			return &taskToReturn, nil
 		}
+		break
 	}
 
+	s.metrics.observePoll(false)
 	return nil, fmt.Errorf("нет готовых задач")
 }
 
-// UpdateTaskResult обновляет результат выполненной задачи
+// traceIssue записывает спан ожидания задачи в очереди и начинает спан ее выполнения.
+// Возвращает traceparent спана выполнения, который агент продолжает.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) traceIssue(task models.Task, now time.Time) string {
+	if s.tracer == nil || task.TraceParent == "" {
+		return task.TraceParent
+	}
+	parent, err := tracing.ParseTraceparent(task.TraceParent)
+	if err != nil {
+		return task.TraceParent
+	}
+
+	taskID := strconv.Itoa(task.ID)
+	if !task.ReadyAt.IsZero() {
+		wait := s.tracer.StartAt(parent, "task.queue_wait", tracing.SpanKindInternal, task.ReadyAt)
+		wait.SetAttribute("task.id", taskID)
+		wait.SetAttribute("task.operation", string(task.Operation))
+		wait.EndAt(now)
+	}
+
+	exec := s.tracer.StartAt(parent, "task.execute", tracing.SpanKindInternal, now)
+	exec.SetAttribute("task.id", taskID)
+	exec.SetAttribute("task.operation", string(task.Operation))
+	s.taskSpans[task.ID] = exec
+
+	return exec.SpanContext().Traceparent()
+}
+
+// InFlightTasks возвращает количество задач, выданных агентам и еще не вернувших результат
+func (s *Storage) InFlightTasks() int {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	inFlight := 0
+	for _, task := range s.tasks {
+		if s.isInFlight(task) {
+			inFlight++
+		}
+	}
+	return inFlight
+}
+
+// expireLeases возвращает в очередь выданные задачи, срок аренды которых истек.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) expireLeases(now time.Time) {
+	if s.leaseTimeout <= 0 {
+		return
+	}
+	for id, task := range s.tasks {
+		if !s.isInFlight(task) || task.LeasedUntil.IsZero() || now.Before(task.LeasedUntil) {
+			continue
+		}
+		s.logger.Warn("Task lease expired, task re-queued",
+			logging.TaskID(id),
+			logging.ExpressionID(task.ExpressionID),
+			zap.Strings("agents", task.Leases),
+		)
+		s.requeue(task, now, "срок аренды задачи истек")
+	}
+}
+
+// requeue возвращает выданную задачу в очередь. Агенты, которым она была выдана, остаются
+// в списке Released, и их результат принимается, пока задачу не завершил кто-то другой.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) requeue(task models.Task, now time.Time, reason string) {
+	for _, agentID := range task.Leases {
+		if !slices.Contains(task.Released, agentID) {
+			task.Released = append(task.Released, agentID)
+		}
+	}
+	task.IsReady = true
+	task.ReadyAt = now
+	task.IssuedAt = time.Time{}
+	task.LeasedUntil = time.Time{}
+	task.Leases = nil
+	s.tasks[task.ID] = task
+	delete(s.auditResults, task.ID)
+
+	if span, exists := s.taskSpans[task.ID]; exists {
+		span.SetError(reason)
+		span.End()
+		delete(s.taskSpans, task.ID)
+	}
+}
+
+// leaseDeadline возвращает срок аренды задачи, выданной в момент now.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) leaseDeadline(now time.Time) time.Time {
+	if s.leaseTimeout <= 0 {
+		return time.Time{}
+	}
+	return now.Add(s.leaseTimeout)
+}
+
+// isInFlight сообщает, выполняется ли задача агентом. Вызывается под блокировкой мьютекса
+func (s *Storage) isInFlight(task models.Task) bool {
+	if task.IsReady || task.Result != nil || task.IssuedAt.IsZero() {
+		return false
+	}
+	expr, exists := s.expressions[task.ExpressionID]
+	return exists && !expr.Status.IsTerminal()
+}
+
+// ReadyQueueDepth возвращает количество готовых задач, ожидающих выдачи агенту
+func (s *Storage) ReadyQueueDepth() int {
+	s.rlock()
+	defer s.mutex.RUnlock()
+
+	depth := 0
+	for _, task := range s.tasks {
+		if task.IsReady && task.Result == nil {
+			depth++
+		}
+	}
+	return depth
+}
+
+// resolveArg заменяет ссылку вида "res:<id>" на результат задачи, если он уже известен.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) resolveArg(arg string) string {
+	if len(arg) <= 4 || arg[:4] != "res:" {
+		return arg
+	}
+
+	taskID, err := strconv.Atoi(arg[4:])
+	if err != nil {
+		return arg
+	}
+
+	// Ищем задачу с этим ID, проверка на ExpressionID убрана
+	depTask, exists := s.tasks[taskID]
+	if exists && depTask.Result != nil {
+		return fmt.Sprintf("%f", *depTask.Result)
+	}
+	return arg
+}
+
+// cacheKey возвращает ключ кеша результатов для задачи с подставленными аргументами
+func cacheKey(task models.Task) string {
+	return fmt.Sprintf("%s|%s|%s", task.Operation, task.Arg1, task.Arg2)
+}
+
+// UpdateTaskResult обновляет результат выполненной задачи без проверки, кому она выдана
 func (s *Storage) UpdateTaskResult(id int, result float64, errorMsg string) error {
-	s.mutex.Lock()
+	s.lock()
 	defer s.mutex.Unlock()
 
-	task, exists := s.tasks[id]
-	if !exists {
-		return fmt.Errorf("задача с ID %d не найдена", id)
+	task, err := s.checkResult(id, result, errorMsg)
+	if err != nil {
+		return err
 	}
+	s.applyResult(task, result, errorMsg)
+	return nil
+}
+
+// applyResult сохраняет проверенный результат задачи и продвигает вычисление выражения.
+// Вызывается под блокировкой мьютекса
+func (s *Storage) applyResult(task models.Task, result float64, errorMsg string) {
+	id := task.ID
+	if span, exists := s.taskSpans[id]; exists {
+		if errorMsg != "" {
+			span.SetError(errorMsg)
+		}
+		span.End()
+		delete(s.taskSpans, id)
+	}
+
+	s.metrics.observeTaskExecution(task, time.Now(), errorMsg)
 
 	if errorMsg != "" {
 		// Задача завершилась с ошибкой
 		expr, exists := s.expressions[task.ExpressionID]
-		if exists {
+		if exists && !expr.Status.IsTerminal() {
+			now := time.Now().UTC()
 			expr.Status = models.StatusError
 			expr.ErrorMsg = errorMsg
+			expr.CompletedAt = &now
 			s.expressions[task.ExpressionID] = expr
+			s.notifyCompletion(task.ExpressionID)
 		}
-		return nil
+		return
 	}
 
 	// Обновляем результат задачи
@@ -216,13 +708,22 @@ func (s *Storage) UpdateTaskResult(id int, result float64, errorMsg string) erro
 	task.Result = &resultValue
 	s.tasks[id] = task
 
+	// Запоминаем результат для одинаковых задач
+	if s.cacheEnabled {
+		if len(s.resultCache) >= maxResultCacheSize {
+			s.resultCache = make(map[string]float64)
+		}
+		resolved := task
+		resolved.Arg1 = s.resolveArg(task.Arg1)
+		resolved.Arg2 = s.resolveArg(task.Arg2)
+		s.resultCache[cacheKey(resolved)] = resultValue
+	}
+
 	// Обновляем зависимости других задач
 	s.updateDependencies(id)
 
 	// Проверяем завершение выражения
 	s.checkExpressionCompletion(task.ExpressionID)
-
-	return nil
 }
 
 // updateDependencies обновляет зависимости задач
@@ -235,6 +736,7 @@ func (s *Storage) updateDependencies(completedTaskID int) {
 	expressionID := completedTask.ExpressionID
 
 	// Обновляем только задачи, относящиеся к тому же выражению
+	now := time.Now()
 	for id, task := range s.tasks {
 		// Проверяем, что задача относится к тому же выражению
 		if task.ExpressionID != expressionID || task.Result != nil {
@@ -242,17 +744,21 @@ func (s *Storage) updateDependencies(completedTaskID int) {
 		}
 
 		// Проверяем зависимости
+		removed := false
 		for i, depID := range task.Dependencies {
 			if depID == completedTaskID {
 				// Удаляем выполненную зависимость
 				task.Dependencies = append(task.Dependencies[:i], task.Dependencies[i+1:]...)
+				removed = true
 				break
 			}
 		}
 
-		// Если зависимостей нет, задача готова
-		if len(task.Dependencies) == 0 {
+		// Если удалена последняя зависимость, задача готова. Задачи без зависимостей
+		// уже выданы агентам или ждут в очереди, их готовность не меняется
+		if removed && len(task.Dependencies) == 0 {
 			task.IsReady = true
+			task.ReadyAt = now
 		}
 
 		s.tasks[id] = task
@@ -283,10 +789,13 @@ func (s *Storage) checkExpressionCompletion(exprID int) {
 	if allCompleted && finalResult != nil {
 		expr, exists := s.expressions[exprID]
 		if exists {
+			now := time.Now().UTC()
 			expr.Status = models.StatusCompleted
 			resultStr := fmt.Sprintf("%g", *finalResult)
 			expr.Result = &resultStr
+			expr.CompletedAt = &now
 			s.expressions[exprID] = expr
+			s.notifyCompletion(exprID)
 		}
 	}
 }
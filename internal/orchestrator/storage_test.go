@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStorage_UpdateDependencies проверяет, что завершение задачи не возвращает в очередь
+// уже выданные агентам задачи того же выражения
+func TestStorage_UpdateDependencies(t *testing.T) {
+	server, storage := newTestServer()
+	if _, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "(1+2)*(3+4)"); err != nil {
+		t.Fatalf("Ошибка добавления выражения: %v", err)
+	}
+
+	first, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatalf("Ожидалась готовая задача: %v", err)
+	}
+	second, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatalf("Ожидалась вторая готовая задача: %v", err)
+	}
+
+	storage.UpdateTaskResult(first.ID, 3, "")
+	if task, err := storage.GetReadyTask(); err == nil {
+		t.Fatalf("Задача %d выдана повторно, пока выполняется", task.ID)
+	}
+
+	storage.UpdateTaskResult(second.ID, 7, "")
+	task, err := storage.GetReadyTask()
+	if err != nil || task.Operation != models.OperationMultiply {
+		t.Fatalf("Ожидалась задача умножения, получено %+v, %v", task, err)
+	}
+}
+
+// TestStorage_ResultCache проверяет повторное использование результатов одинаковых задач
+func TestStorage_ResultCache(t *testing.T) {
+	server, storage := newTestServer()
+	m := NewMetrics(metrics.NewRegistry(), storage)
+	storage.EnableResultCache(true)
+
+	first, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "3*4")
+	if err != nil {
+		t.Fatalf("Ошибка добавления выражения: %v", err)
+	}
+	completeAllTasks(storage)
+
+	second, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "3*4+1")
+	if err != nil {
+		t.Fatalf("Ошибка добавления выражения: %v", err)
+	}
+
+	// Умножение берется из кеша, агенту выдается только сложение
+	task, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatalf("Ожидалась готовая задача: %v", err)
+	}
+	if task.Operation != models.OperationAdd || task.Arg1 != "12.000000" {
+		t.Fatalf("Ожидалась задача сложения с результатом из кеша, получено %+v", task)
+	}
+	storage.UpdateTaskResult(task.ID, 13, "")
+
+	for id, want := range map[int]string{first: "12", second: "13"} {
+		expr, _ := storage.GetExpression(id)
+		if expr.Result == nil || *expr.Result != want {
+			t.Errorf("Выражение %d: ожидался результат %s, получено %+v", id, want, expr)
+		}
+	}
+
+	if hits := m.cache.WithLabelValues("hit").Value(); hits != 1 {
+		t.Errorf("Ожидалось 1 попадание в кеш, получено %v", hits)
+	}
+}
+
+// TestStorage_ResultCacheChain проверяет, что выражение, все задачи которого есть в кеше,
+// вычисляется без агента: после попадания в кеш готовыми становятся зависящие задачи
+func TestStorage_ResultCacheChain(t *testing.T) {
+	server, storage := newTestServer()
+	storage.EnableResultCache(true)
+
+	for _, expr := range []string{"2*3+1", "2*3+1"} {
+		if _, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), expr); err != nil {
+			t.Fatalf("Ошибка добавления выражения: %v", err)
+		}
+		completeAllTasks(storage)
+	}
+
+	if task, err := storage.GetReadyTask(); err == nil {
+		t.Fatalf("Ожидалось, что задачи не останется, выдана %+v", task)
+	}
+	expr, _ := storage.GetExpression(2)
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != "7" {
+		t.Errorf("Ожидалось выражение COMPLETED с результатом 7, получено %+v", expr)
+	}
+}
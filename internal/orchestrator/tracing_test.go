@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer — буфер, безопасный для конкурентной записи
+type syncBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+// Write записывает данные в буфер
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+// exportedSpan — поля спана OTLP/JSON, проверяемые в тестах
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+}
+
+// spans разбирает спаны, записанные файловым экспортером
+func (b *syncBuffer) spans(t *testing.T) []exportedSpan {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var result []exportedSpan
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			t.Fatalf("Некорректная строка трассировки: %v", err)
+		}
+		result = append(result, req.ResourceSpans[0].ScopeSpans[0].Spans...)
+	}
+	return result
+}
+
+// TestServer_Tracing проверяет спаны отправки выражения, разбора, ожидания и выполнения задач
+func TestServer_Tracing(t *testing.T) {
+	var buf syncBuffer
+	server, storage := newTestServer()
+	server.SetTracer(tracing.NewTracer("orchestrator", tracing.NewWriterExporter(&buf)))
+	handler := server.SetupRoutes()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(`{"expression": "1+2*3"}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", rr.Code)
+	}
+
+	// Агент получает traceparent спана выполнения задачи
+	task, err := storage.GetReadyTask()
+	if err != nil {
+		t.Fatalf("Ожидалась готовая задача: %v", err)
+	}
+	issued, err := tracing.ParseTraceparent(task.TraceParent)
+	if err != nil {
+		t.Fatalf("Задача не содержит traceparent: %v", err)
+	}
+	result, _ := calcTask(task)
+	storage.UpdateTaskResult(task.ID, result, "")
+	completeAllTasks(storage)
+
+	byName := make(map[string][]exportedSpan)
+	for _, span := range buf.spans(t) {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+
+	root := byName["POST /api/v1/calculate"]
+	if len(root) != 1 {
+		t.Fatalf("Ожидался один спан запроса, получено: %+v", byName)
+	}
+	expected := map[string]int{"parse": 1, "task.queue_wait": 2, "task.execute": 2}
+	for name, count := range expected {
+		if len(byName[name]) != count {
+			t.Errorf("Ожидалось %d спанов %s, получено %d", count, name, len(byName[name]))
+		}
+		for _, span := range byName[name] {
+			if span.TraceID != root[0].TraceID || span.ParentSpanID != root[0].SpanID {
+				t.Errorf("Спан %s не является дочерним к спану запроса: %+v", name, span)
+			}
+		}
+	}
+
+	if issued.TraceID.String() != root[0].TraceID || issued.SpanID.String() != byName["task.execute"][0].SpanID {
+		t.Errorf("traceparent задачи %s не указывает на спан выполнения", task.TraceParent)
+	}
+}
@@ -0,0 +1,431 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Заголовки webhook-уведомлений
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+)
+
+// Параметры очереди доставки
+const (
+	webhookWorkers   = 4    // Горутины, доставляющие уведомления
+	webhookQueueSize = 1000 // Уведомления, ожидающие доставки
+)
+
+// Ошибки проверки адреса уведомления
+var (
+	ErrCallbackNotAllowed = errors.New("адрес уведомления указывает на локальную или частную сеть")
+	ErrWebhooksDisabled   = errors.New("уведомления отключены: не задан WEBHOOK_SECRET")
+)
+
+// webhookJob — уведомление, ожидающее доставки
+type webhookJob struct {
+	url   string
+	event models.WebhookEvent
+}
+
+// Notifier доставляет webhook-уведомления о завершении выражений
+type Notifier struct {
+	secret       []byte                           // Ключ для подписи HMAC-SHA256
+	client       *http.Client                     // HTTP-клиент для доставки
+	maxAttempts  int                              // Максимальное количество попыток
+	baseDelay    time.Duration                    // Начальная задержка между попытками
+	callbacks    map[int]string                   // Адреса уведомлений по ID выражения
+	deliveries   map[int][]models.WebhookDelivery // Журнал доставок по ID выражения
+	allowedHosts map[string]bool                  // Имена и адреса закрытой сети, разрешенные для уведомлений
+	allowedNets  []*net.IPNet                     // Подсети закрытой сети, разрешенные для уведомлений
+	queue        chan webhookJob                  // Очередь доставки
+	stop         chan struct{}                    // Закрывается при остановке
+	stopOnce     sync.Once
+	workers      sync.WaitGroup
+	storage      *Storage     // Хранилище выражений (источник логгера)
+	mutex        sync.RWMutex // Мьютекс для защиты данных
+}
+
+// NewNotifier создает нотификатор, запускает воркеров доставки и подписывает
+// нотификатор на завершение выражений в хранилище
+func NewNotifier(storage *Storage, secret string) *Notifier {
+	n := &Notifier{
+		storage:      storage,
+		secret:       []byte(secret),
+		maxAttempts:  5,
+		baseDelay:    time.Second,
+		callbacks:    make(map[int]string),
+		deliveries:   make(map[int][]models.WebhookDelivery),
+		allowedHosts: make(map[string]bool),
+		queue:        make(chan webhookJob, webhookQueueSize),
+		stop:         make(chan struct{}),
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: n.checkDialAddress}
+	n.client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				// Разрешенные по имени хосты не проверяются по адресу
+				if host, _, err := net.SplitHostPort(addr); err == nil && n.hostAllowed(host) {
+					return (&net.Dialer{Timeout: dialer.Timeout}).DialContext(ctx, network, addr)
+				}
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+
+	for i := 0; i < webhookWorkers; i++ {
+		n.workers.Add(1)
+		go n.work()
+	}
+	storage.AddCompletionListener(n.handleCompletion)
+	storage.AddPurgeListener(n.forget)
+	return n
+}
+
+// SetAllowedHosts разрешает уведомления на адреса закрытой сети. list — список
+// через запятую из имен хостов, IP-адресов и подсетей в нотации CIDR
+func (n *Notifier) SetAllowedHosts(list string) error {
+	hosts := make(map[string]bool)
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return fmt.Errorf("некорректная подсеть %q: %v", entry, err)
+			}
+			nets = append(nets, ipNet)
+		default:
+			hosts[entry] = true
+		}
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.allowedHosts = hosts
+	n.allowedNets = nets
+	return nil
+}
+
+// Shutdown прекращает прием уведомлений и доставляет уже поставленные в очередь,
+// без повторных попыток. Ожидает завершения доставки или отмены ctx
+func (n *Notifier) Shutdown(ctx context.Context) error {
+	n.stopOnce.Do(func() { close(n.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		n.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// forget удаляет адреса и журналы доставок удаленных из хранилища выражений
+func (n *Notifier) forget(ids []int) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(n.callbacks, id)
+		delete(n.deliveries, id)
+	}
+}
+
+// ValidateCallbackURL проверяет, что адрес уведомления абсолютный, использует HTTP(S)
+// и не указывает на локальную или частную сеть, если она не разрешена SetAllowedHosts.
+// Адреса, в которые разрешается имя хоста, проверяются при каждом подключении.
+// Без ключа подписи получатель не отличит уведомление от подделки, поэтому адреса не принимаются
+func (n *Notifier) ValidateCallbackURL(rawURL string) error {
+	if len(n.secret) == 0 {
+		return ErrWebhooksDisabled
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("некорректный callback_url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("callback_url должен быть абсолютным http(s) адресом")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if n.hostAllowed(host) {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && !n.ipAllowed(ip) {
+		return ErrCallbackNotAllowed
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrCallbackNotAllowed
+	}
+	return nil
+}
+
+// hostAllowed сообщает, разрешен ли хост явно
+func (n *Notifier) hostAllowed(host string) bool {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.allowedHosts[strings.ToLower(host)]
+}
+
+// ipAllowed сообщает, можно ли отправлять уведомления на адрес: публичные адреса
+// разрешены всегда, адреса закрытой сети — только из списка разрешенных
+func (n *Notifier) ipAllowed(ip net.IP) bool {
+	if !isPrivateIP(ip) {
+		return true
+	}
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if n.allowedHosts[ip.String()] {
+		return true
+	}
+	for _, ipNet := range n.allowedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDialAddress запрещает подключение к закрытой сети после разрешения имени хоста,
+// в том числе при перенаправлениях
+func (n *Notifier) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !n.ipAllowed(ip) {
+		return ErrCallbackNotAllowed
+	}
+	return nil
+}
+
+// sharedAddressSpace — подсеть 100.64.0.0/10 операторского NAT
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateIP сообщает, относится ли адрес к локальной, частной или служебной сети,
+// включая адрес метаданных облака 169.254.169.254
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// Register связывает выражение с адресом уведомления
+func (n *Notifier) Register(exprID int, callbackURL string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.callbacks[exprID] = callbackURL
+}
+
+// Deliveries возвращает журнал доставок для выражения
+func (n *Notifier) Deliveries(exprID int) []models.WebhookDelivery {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	result := make([]models.WebhookDelivery, len(n.deliveries[exprID]))
+	copy(result, n.deliveries[exprID])
+	return result
+}
+
+// Sign вычисляет подпись тела уведомления в формате "sha256=<hex>"
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// handleCompletion вызывается хранилищем при завершении выражения
+func (n *Notifier) handleCompletion(expr models.Expression) {
+	n.mutex.RLock()
+	callbackURL, exists := n.callbacks[expr.ID]
+	n.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	job := webhookJob{
+		url: callbackURL,
+		event: models.WebhookEvent{
+			Event:      eventForStatus(expr.Status),
+			Expression: expr,
+			Timestamp:  time.Now().UTC(),
+		},
+	}
+
+	reason := ""
+	select {
+	case <-n.stop:
+		reason = "нотификатор остановлен"
+	default:
+		select {
+		case n.queue <- job:
+			return
+		default:
+			reason = "очередь доставки переполнена"
+		}
+	}
+
+	n.storage.Logger().Error("Webhook dropped", logging.ExpressionID(expr.ID), zap.String("reason", reason))
+	n.record(expr.ID, models.WebhookDelivery{
+		Attempt:   1,
+		URL:       callbackURL,
+		Event:     job.event.Event,
+		Error:     reason,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// work доставляет уведомления из очереди. После остановки доставляет оставшиеся
+// в очереди уведомления и завершается
+func (n *Notifier) work() {
+	defer n.workers.Done()
+
+	for {
+		select {
+		case job := <-n.queue:
+			n.deliver(job.url, job.event)
+		case <-n.stop:
+			for {
+				select {
+				case job := <-n.queue:
+					n.deliver(job.url, job.event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver отправляет уведомление с повторными попытками и экспоненциальной задержкой.
+// После остановки нотификатора повторные попытки не выполняются
+func (n *Notifier) deliver(callbackURL string, event models.WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.storage.Logger().Error("Webhook event encoding error", logging.ExpressionID(event.Expression.ID), zap.Error(err))
+		return
+	}
+	signature := Sign(n.secret, body)
+
+	delay := n.baseDelay
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		delivery := models.WebhookDelivery{
+			Attempt:   attempt,
+			URL:       callbackURL,
+			Event:     event.Event,
+			Timestamp: time.Now().UTC(),
+		}
+
+		statusCode, err := n.send(callbackURL, event.Event, signature, body)
+		delivery.StatusCode = statusCode
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Success = true
+		}
+		n.record(event.Expression.ID, delivery)
+
+		if delivery.Success {
+			return
+		}
+
+		n.storage.Logger().Warn("Webhook delivery failed",
+			logging.ExpressionID(event.Expression.ID),
+			zap.String("url", callbackURL),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		if attempt < n.maxAttempts {
+			select {
+			case <-time.After(delay):
+			case <-n.stop:
+				return
+			}
+			delay *= 2
+		}
+	}
+}
+
+// send выполняет одну попытку доставки и возвращает код ответа
+func (n *Notifier) send(callbackURL, event, signature string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			n.storage.Logger().Warn("Response body close error", zap.Error(err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("неожиданный код ответа: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// record добавляет попытку доставки в журнал
+func (n *Notifier) record(exprID int, delivery models.WebhookDelivery) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.deliveries[exprID] = append(n.deliveries[exprID], delivery)
+}
+
+// eventForStatus возвращает тип события для окончательного статуса выражения
+func eventForStatus(status models.Status) string {
+	switch status {
+	case models.StatusError:
+		return models.EventExpressionFailed
+	case models.StatusCancelled:
+		return models.EventExpressionCancelled
+	default:
+		return models.EventExpressionCompleted
+	}
+}
@@ -0,0 +1,246 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver собирает уведомления, полученные тестовым сервером
+type webhookReceiver struct {
+	mutex    sync.Mutex
+	events   []models.WebhookEvent
+	failures int // Количество первых запросов, на которые отвечаем ошибкой
+	secret   []byte
+	badSigs  int
+}
+
+// ServeHTTP проверяет подпись и сохраняет событие
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mutex.Lock()
+	defer rcv.mutex.Unlock()
+
+	if rcv.failures > 0 {
+		rcv.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if r.Header.Get(SignatureHeader) != Sign(rcv.secret, body) {
+		rcv.badSigs++
+	}
+
+	var event models.WebhookEvent
+	json.Unmarshal(body, &event)
+	rcv.events = append(rcv.events, event)
+}
+
+// received возвращает копию полученных событий
+func (rcv *webhookReceiver) received() []models.WebhookEvent {
+	rcv.mutex.Lock()
+	defer rcv.mutex.Unlock()
+	return append([]models.WebhookEvent(nil), rcv.events...)
+}
+
+// submitWithCallback отправляет выражение с адресом уведомления
+func submitWithCallback(t *testing.T, handler http.Handler, expr, callbackURL string) int {
+	t.Helper()
+	body, _ := json.Marshal(models.ExpressionRequest{Expression: expr, CallbackURL: callbackURL})
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/calculate", bytes.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var resp models.ExpressionResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	return resp.ID
+}
+
+// waitForEvents ожидает получения заданного количества событий
+func waitForEvents(t *testing.T, rcv *webhookReceiver, n int) []models.WebhookEvent {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := rcv.received(); len(events) >= n {
+			return events
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("получено %d событий, ожидалось %d", len(rcv.received()), n)
+	return nil
+}
+
+// TestNotifier_Delivery проверяет доставку подписанных уведомлений
+func TestNotifier_Delivery(t *testing.T) {
+	rcv := &webhookReceiver{secret: []byte("secret")}
+	receiver := httptest.NewServer(rcv)
+	defer receiver.Close()
+
+	server, storage := newTestServer()
+	notifier := NewNotifier(storage, "secret")
+	notifier.SetAllowedHosts("127.0.0.1")
+	server.SetNotifier(notifier)
+	handler := server.SetupRoutes()
+
+	okID := submitWithCallback(t, handler, "2*3", receiver.URL)
+	failID := submitWithCallback(t, handler, "1/0", receiver.URL)
+	cancelID := submitWithCallback(t, handler, "5-1", receiver.URL)
+	if _, err := storage.CancelExpression(cancelID, 0); err != nil {
+		t.Fatal(err)
+	}
+	completeAllTasks(storage)
+
+	events := waitForEvents(t, rcv, 3)
+	byID := make(map[int]models.WebhookEvent)
+	for _, event := range events {
+		byID[event.Expression.ID] = event
+	}
+
+	if got := byID[okID].Event; got != models.EventExpressionCompleted {
+		t.Errorf("Event = %q, want %q", got, models.EventExpressionCompleted)
+	}
+	if got := byID[failID].Event; got != models.EventExpressionFailed {
+		t.Errorf("Event = %q, want %q", got, models.EventExpressionFailed)
+	}
+	if got := byID[cancelID].Event; got != models.EventExpressionCancelled {
+		t.Errorf("Event = %q, want %q", got, models.EventExpressionCancelled)
+	}
+	if rcv.badSigs != 0 {
+		t.Errorf("получено %d уведомлений с неверной подписью", rcv.badSigs)
+	}
+}
+
+// TestNotifier_Retry проверяет повторную доставку и журнал попыток
+func TestNotifier_Retry(t *testing.T) {
+	rcv := &webhookReceiver{secret: []byte("secret"), failures: 2}
+	receiver := httptest.NewServer(rcv)
+	defer receiver.Close()
+
+	server, storage := newTestServer()
+	notifier := NewNotifier(storage, "secret")
+	notifier.baseDelay = time.Millisecond
+	notifier.SetAllowedHosts("127.0.0.0/8")
+	server.SetNotifier(notifier)
+	handler := server.SetupRoutes()
+
+	id := submitWithCallback(t, handler, "1+1", receiver.URL)
+	completeAllTasks(storage)
+	waitForEvents(t, rcv, 1)
+
+	// Попытка записывается в журнал после получения ответа, поэтому ждем ее появления
+	var resp models.WebhookDeliveriesResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/expressions/1/deliveries", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		if len(resp.Deliveries) >= 3 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(resp.Deliveries) != 3 {
+		t.Fatalf("len(Deliveries) = %d, want 3", len(resp.Deliveries))
+	}
+	for i, delivery := range resp.Deliveries {
+		wantSuccess := i == 2
+		if delivery.Success != wantSuccess || delivery.Attempt != i+1 {
+			t.Errorf("Deliveries[%d] = %+v", i, delivery)
+		}
+	}
+	if id != 1 {
+		t.Errorf("id = %d, want 1", id)
+	}
+}
+
+// TestValidateCallbackURL проверяет валидацию адреса уведомления
+func TestValidateCallbackURL(t *testing.T) {
+	_, storage := newTestServer()
+	notifier := NewNotifier(storage, "secret")
+	if err := notifier.SetAllowedHosts("hooks.internal, 10.1.0.0/16"); err != nil {
+		t.Fatalf("SetAllowedHosts: %v", err)
+	}
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/hook", false},
+		{"http://203.0.113.10:9000/hook", false},
+		{"http://hooks.internal/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://localhost:9000", true},
+		{"http://127.0.0.1:9000", true},
+		{"http://[::1]/hook", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://10.2.0.1/hook", true},
+		{"http://192.168.1.1/hook", true},
+		{"ftp://example.com", true},
+		{"/relative/path", true},
+	}
+
+	for _, tt := range tests {
+		if err := notifier.ValidateCallbackURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateCallbackURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+
+	if err := notifier.SetAllowedHosts("10.0.0.0/33"); err == nil {
+		t.Error("SetAllowedHosts accepted an invalid CIDR range")
+	}
+
+	// Без ключа подписи уведомления отключены
+	unsigned := NewNotifier(storage, "")
+	if err := unsigned.ValidateCallbackURL("https://example.com/hook"); !errors.Is(err, ErrWebhooksDisabled) {
+		t.Errorf("ValidateCallbackURL without secret error = %v, want %v", err, ErrWebhooksDisabled)
+	}
+}
+
+// TestNotifier_BlockedAddress проверяет, что адрес закрытой сети запрещен и при подключении,
+// например после разрешения имени хоста, а очередь доставляется при остановке
+func TestNotifier_BlockedAddress(t *testing.T) {
+	rcv := &webhookReceiver{secret: []byte("secret")}
+	receiver := httptest.NewServer(rcv)
+	defer receiver.Close()
+
+	server, storage := newTestServer()
+	notifier := NewNotifier(storage, "secret")
+	notifier.maxAttempts = 1
+	id, err := server.submitExpression(httptest.NewRequest(http.MethodPost, "/", nil), "1+1")
+	if err != nil {
+		t.Fatalf("submitExpression: %v", err)
+	}
+	notifier.Register(id, receiver.URL)
+	completeAllTasks(storage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var deliveries []models.WebhookDelivery
+	for len(deliveries) == 0 && ctx.Err() == nil {
+		deliveries = notifier.Deliveries(id)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := notifier.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if len(deliveries) != 1 || deliveries[0].Success || !strings.Contains(deliveries[0].Error, ErrCallbackNotAllowed.Error()) {
+		t.Errorf("deliveries = %+v, want one blocked attempt", deliveries)
+	}
+	if len(rcv.received()) != 0 {
+		t.Error("уведомление доставлено на адрес закрытой сети")
+	}
+}
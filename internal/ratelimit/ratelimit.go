@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket реализует алгоритм маркерной корзины
+type TokenBucket struct {
+	rate     float64   // Скорость пополнения, маркеров в секунду
+	burst    float64   // Емкость корзины
+	tokens   float64   // Текущее количество маркеров
+	lastSeen time.Time // Время последнего пополнения
+}
+
+// NewTokenBucket создает заполненную корзину
+func NewTokenBucket(rate float64, burst int, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastSeen: now,
+	}
+}
+
+// Result описывает решение ограничителя и состояние корзины
+type Result struct {
+	Allowed    bool          // Разрешен ли запрос
+	Limit      int           // Емкость корзины
+	Remaining  int           // Оставшееся количество маркеров
+	RetryAfter time.Duration // Через сколько появятся нужные маркеры (если запрещено)
+	Reset      time.Duration // Через сколько корзина заполнится полностью
+}
+
+// Take пытается забрать n маркеров из корзины
+func (b *TokenBucket) Take(n int, now time.Time) Result {
+	b.refill(now)
+
+	result := Result{Limit: int(b.burst)}
+	if float64(n) <= b.tokens {
+		b.tokens -= float64(n)
+		result.Allowed = true
+	} else if b.rate > 0 {
+		result.RetryAfter = time.Duration((float64(n) - b.tokens) / b.rate * float64(time.Second))
+	} else {
+		result.RetryAfter = time.Duration(math.MaxInt64)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	if b.rate > 0 {
+		result.Reset = time.Duration((b.burst - b.tokens) / b.rate * float64(time.Second))
+	}
+	return result
+}
+
+// refill пополняет корзину пропорционально прошедшему времени
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.lastSeen).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.lastSeen = now
+	}
+}
+
+// full сообщает, заполнена ли корзина полностью
+func (b *TokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// Limiter хранит отдельную маркерную корзину для каждого ключа
+type Limiter struct {
+	rate      float64
+	burst     int
+	buckets   map[string]*TokenBucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+	now       func() time.Time
+}
+
+// sweepInterval — период удаления заполненных корзин неактивных ключей
+const sweepInterval = time.Minute
+
+// NewLimiter создает ограничитель со скоростью rate запросов в секунду и емкостью burst
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*TokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow пытается забрать один маркер из корзины ключа
+func (l *Limiter) Allow(key string) Result {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = NewTokenBucket(l.rate, l.burst, now)
+		l.buckets[key] = bucket
+	}
+
+	return bucket.Take(1, now)
+}
+
+// sweep удаляет заполненные корзины: их состояние не отличается от новых.
+// Вызывается под блокировкой мьютекса
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	for key, bucket := range l.buckets {
+		if bucket.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokenBucket проверяет расход и пополнение маркерной корзины
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := NewTokenBucket(1, 2, now)
+
+	for i := 0; i < 2; i++ {
+		if result := bucket.Take(1, now); !result.Allowed {
+			t.Fatalf("Take() #%d denied, want allowed", i+1)
+		}
+	}
+
+	result := bucket.Take(1, now)
+	if result.Allowed {
+		t.Fatalf("Take() allowed on empty bucket")
+	}
+	if result.RetryAfter != time.Second || result.Remaining != 0 || result.Limit != 2 {
+		t.Errorf("Take() = %+v, want RetryAfter=1s Remaining=0 Limit=2", result)
+	}
+
+	// Через секунду появляется один маркер
+	if result := bucket.Take(1, now.Add(time.Second)); !result.Allowed {
+		t.Errorf("Take() after refill denied, want allowed")
+	}
+}
+
+// TestLimiter проверяет, что ключи ограничиваются независимо
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(1, 1)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	if !limiter.Allow("a").Allowed {
+		t.Fatalf("Allow(a) denied")
+	}
+	if limiter.Allow("a").Allowed {
+		t.Errorf("second Allow(a) allowed")
+	}
+	if !limiter.Allow("b").Allowed {
+		t.Errorf("Allow(b) denied")
+	}
+}
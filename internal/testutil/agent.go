@@ -0,0 +1,30 @@
+// Package testutil содержит вспомогательные функции для тестов, которым нужен работающий оркестратор.
+package testutil
+
+import (
+	"context"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/agent"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+	"testing"
+)
+
+// StartAgent запускает в процессе теста агента, который берет задачи прямо из хранилища
+// и выполняет их без задержек. Агент останавливается по завершении теста
+func StartAgent(t testing.TB, storage *orchestrator.Storage) {
+	t.Helper()
+	a := agent.NewAgent("", 2)
+	a.SetID("embedded-1")
+	a.SetTransport(agent.NewLocalTransport(storage))
+	a.SetExecutors(agent.NewBuiltinExecutors(agent.NoDelay))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
@@ -0,0 +1,283 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter принимает завершенные спаны
+type Exporter interface {
+	Export(service string, span SpanData)
+	Shutdown() error
+}
+
+// FileExporter записывает каждый спан отдельной строкой в формате OTLP/JSON
+// (ExportTraceServiceRequest), который читает, например, otlpjsonfile receiver коллектора
+type FileExporter struct {
+	writer io.Writer
+	closer io.Closer
+	mutex  sync.Mutex
+}
+
+// NewFileExporter создает экспортер, дописывающий спаны в файл
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл трассировки: %w", err)
+	}
+	return &FileExporter{writer: file, closer: file}, nil
+}
+
+// NewWriterExporter создает экспортер, записывающий спаны в произвольный поток
+func NewWriterExporter(w io.Writer) *FileExporter {
+	return &FileExporter{writer: w}
+}
+
+// Export записывает спан
+func (e *FileExporter) Export(service string, span SpanData) {
+	data, err := json.Marshal(encodeRequest(service, []SpanData{span}))
+	if err != nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.writer.Write(append(data, '\n'))
+}
+
+// Shutdown закрывает файл
+func (e *FileExporter) Shutdown() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// OTLPExporter отправляет спаны пакетами на коллектор по протоколу OTLP/HTTP с кодировкой JSON
+type OTLPExporter struct {
+	endpoint string
+	client   *http.Client
+	spans    chan exportedSpan
+	done     chan struct{}
+	closed   bool         // Экспортер остановлен, новые спаны отбрасываются
+	mutex    sync.RWMutex // Защищает closed и закрытие spans от одновременного Export
+}
+
+// exportedSpan — спан вместе с именем сервиса
+type exportedSpan struct {
+	service string
+	span    SpanData
+}
+
+const (
+	// otlpBatchSize — максимальное число спанов в одном запросе
+	otlpBatchSize = 100
+	// otlpFlushInterval — максимальная задержка отправки спана
+	otlpFlushInterval = 2 * time.Second
+)
+
+// NewOTLPExporter создает экспортер для коллектора по адресу endpoint (например, http://localhost:4318)
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: endpoint + "/v1/traces",
+		client:   &http.Client{Timeout: 10 * time.Second},
+		spans:    make(chan exportedSpan, 10*otlpBatchSize),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export ставит спан в очередь на отправку. При переполнении очереди или после
+// Shutdown спан отбрасывается
+func (e *OTLPExporter) Export(service string, span SpanData) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.closed {
+		return
+	}
+	select {
+	case e.spans <- exportedSpan{service: service, span: span}:
+	default:
+	}
+}
+
+// Shutdown отправляет оставшиеся спаны и останавливает экспортер.
+// Спаны, завершенные после остановки, отбрасываются
+func (e *OTLPExporter) Shutdown() error {
+	e.mutex.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.spans)
+	}
+	e.mutex.Unlock()
+
+	<-e.done
+	return nil
+}
+
+// run накапливает спаны и отправляет их по размеру пакета или по таймеру
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]exportedSpan, 0, otlpBatchSize)
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				e.send(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// send отправляет пакет спанов, сгруппированный по сервисам
+func (e *OTLPExporter) send(batch []exportedSpan) {
+	if len(batch) == 0 {
+		return
+	}
+
+	byService := make(map[string][]SpanData)
+	for _, s := range batch {
+		byService[s.service] = append(byService[s.service], s.span)
+	}
+	for service, spans := range byService {
+		data, err := json.Marshal(encodeRequest(service, spans))
+		if err != nil {
+			continue
+		}
+		resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
+// Типы OTLP/JSON (ExportTraceServiceRequest)
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// otlpStatusError — код статуса STATUS_CODE_ERROR
+const otlpStatusError = 2
+
+// encodeRequest преобразует спаны сервиса в запрос OTLP/JSON
+func encodeRequest(service string, spans []SpanData) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.Attributes),
+		}
+		if span.Parent != (SpanID{}) {
+			s.ParentSpanID = span.Parent.String()
+		}
+		if span.Error != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		encoded = append(encoded, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes(map[string]string{"service.name": service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "arithmetic-web-service"}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes преобразует атрибуты в отсортированный список OTLP
+func encodeAttributes(attributes map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return result
+}
+
+// NewExporter выбирает экспортер по настройкам: OTLP при заданном endpoint,
+// иначе файл при заданном пути. Если не задано ничего, возвращает nil
+func NewExporter(otlpEndpoint, filePath string) (Exporter, error) {
+	switch {
+	case otlpEndpoint != "":
+		return NewOTLPExporter(otlpEndpoint), nil
+	case filePath != "":
+		exporter, err := NewFileExporter(filePath)
+		if err != nil {
+			return nil, err
+		}
+		return exporter, nil
+	default:
+		return nil, nil
+	}
+}
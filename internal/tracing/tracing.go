@@ -0,0 +1,304 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader — заголовок W3C Trace Context
+const TraceparentHeader = "traceparent"
+
+// TraceID — идентификатор трассы (16 байт)
+type TraceID [16]byte
+
+// SpanID — идентификатор спана (8 байт)
+type SpanID [8]byte
+
+// String возвращает идентификатор трассы в шестнадцатеричном виде
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String возвращает идентификатор спана в шестнадцатеричном виде
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext — распространяемая между сервисами часть спана
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid сообщает, заданы ли идентификаторы трассы и спана
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent возвращает значение заголовка traceparent версии 00
+func (sc SpanContext) Traceparent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent разбирает значение заголовка traceparent
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("некорректный traceparent: %q", value)
+	}
+	// Версия ff запрещена, версия 00 не допускает дополнительных полей
+	if parts[0] == "ff" || parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, fmt.Errorf("неподдерживаемая версия traceparent: %q", value)
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, fmt.Errorf("некорректный trace-id: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, fmt.Errorf("некорректный parent-id: %w", err)
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, fmt.Errorf("некорректные флаги: %w", err)
+	}
+	if strings.ToLower(value) != value || !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("некорректный traceparent: %q", value)
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	return sc, nil
+}
+
+// contextKey — ключ контекста для текущего SpanContext
+type contextKey struct{}
+
+// ContextWithSpanContext сохраняет SpanContext в контексте
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, sc)
+}
+
+// SpanContextFromContext возвращает SpanContext из контекста
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(contextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Inject записывает traceparent текущего спана в заголовки запроса
+func Inject(ctx context.Context, header http.Header) {
+	if sc, ok := SpanContextFromContext(ctx); ok {
+		header.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
+
+// Extract извлекает traceparent из заголовков и сохраняет его в контексте
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// SpanKind — тип спана в терминах OTLP
+type SpanKind int
+
+// Типы спанов
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanData — завершенный спан, передаваемый экспортеру
+type SpanData struct {
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+}
+
+// Span — незавершенный спан. Методы nil-спана ничего не делают,
+// поэтому код можно трассировать без проверки, включена ли трассировка
+type Span struct {
+	tracer *Tracer
+	data   SpanData
+	ended  bool
+	mutex  sync.Mutex
+}
+
+// SpanContext возвращает распространяемый контекст спана
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttribute задает атрибут спана
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// SetError отмечает спан как завершившийся с ошибкой
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Error = message
+}
+
+// End завершает спан текущим временем
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt завершает спан заданным временем и передает его экспортеру.
+// Повторные вызовы игнорируются
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = end
+	data := s.data
+	s.mutex.Unlock()
+
+	s.tracer.export(data)
+}
+
+// Tracer создает спаны и передает завершенные спаны экспортеру.
+// Методы nil-трассировщика возвращают nil-спаны
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+// NewTracer создает трассировщик сервиса с заданным экспортером
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Start начинает спан, дочерний к SpanContext из контекста, и возвращает контекст с новым спаном
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	parent, _ := SpanContextFromContext(ctx)
+	span := t.StartAt(parent, name, kind, time.Now())
+	return ContextWithSpanContext(ctx, span.SpanContext()), span
+}
+
+// StartAt начинает спан с явным родителем и временем начала.
+// Если родитель не задан, начинается новая трасса
+func (t *Tracer) StartAt(parent SpanContext, name string, kind SpanKind, start time.Time) *Span {
+	if t == nil {
+		return nil
+	}
+
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if !parent.IsValid() {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	return &Span{
+		tracer: t,
+		data: SpanData{
+			Name:    name,
+			Kind:    kind,
+			Context: sc,
+			Parent:  parent.SpanID,
+			Start:   start,
+		},
+	}
+}
+
+// Shutdown отправляет накопленные спаны и освобождает ресурсы экспортера
+func (t *Tracer) Shutdown() error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.Shutdown()
+}
+
+// export передает завершенный спан экспортеру
+func (t *Tracer) export(data SpanData) {
+	if !data.Context.Sampled {
+		return
+	}
+	t.exporter.Export(t.service, data)
+}
+
+// Middleware создает серверный спан для каждого запроса, продолжая трассу из заголовка traceparent.
+// Имя спана — метод и шаблон маршрута
+func Middleware(tracer *Tracer, route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if tracer == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(Extract(r.Context(), r.Header), r.Method+" "+route, SpanKindServer)
+			defer span.End()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			w.Header().Set(TraceparentHeader, span.SpanContext().Traceparent())
+
+			srw := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(srw, r.WithContext(ctx))
+
+			span.SetAttribute("http.response.status_code", fmt.Sprintf("%d", srw.statusCode))
+			if srw.statusCode >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(srw.statusCode))
+			}
+		})
+	}
+}
+
+// statusResponseWriter запоминает статус ответа
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader сохраняет статус ответа и передает его далее
+func (w *statusResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,158 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestParseTraceparent проверяет разбор и формирование заголовка traceparent
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceparent(valid)
+	if err != nil {
+		t.Fatalf("Ошибка разбора корректного traceparent: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("Неожиданный SpanContext: %+v", sc)
+	}
+	if sc.Traceparent() != valid {
+		t.Errorf("Ожидался %s, получен %s", valid, sc.Traceparent())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("Ожидалась ошибка для %q", invalid)
+		}
+	}
+}
+
+// TestMiddleware проверяет продолжение трассы из заголовка и экспорт в формате OTLP/JSON
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("test", NewWriterExporter(&buf))
+
+	var child SpanContext
+	handler := Middleware(tracer, "/api/v1/calculate")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracer.Start(r.Context(), "parse", SpanKindInternal)
+		child = span.SpanContext()
+		span.End()
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	server, err := ParseTraceparent(rr.Header().Get(TraceparentHeader))
+	if err != nil {
+		t.Fatalf("Ответ не содержит traceparent: %v", err)
+	}
+
+	// Первой строкой экспортируется дочерний спан, второй — серверный
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Ожидалось 2 спана, получено %d:\n%s", len(lines), buf.String())
+	}
+	spans := make([]otlpSpan, 0, 2)
+	for _, line := range lines {
+		var req otlpRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			t.Fatalf("Некорректный OTLP/JSON: %v", err)
+		}
+		if service := req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue; service != "test" {
+			t.Errorf("Ожидался сервис test, получен %s", service)
+		}
+		spans = append(spans, req.ResourceSpans[0].ScopeSpans[0].Spans[0])
+	}
+
+	parse, root := spans[0], spans[1]
+	if root.Name != "POST /api/v1/calculate" || root.Kind != SpanKindServer || root.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Неожиданный серверный спан: %+v", root)
+	}
+	if root.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || root.SpanID != server.SpanID.String() {
+		t.Errorf("Серверный спан не продолжает входящую трассу: %+v", root)
+	}
+	if parse.Name != "parse" || parse.ParentSpanID != root.SpanID || parse.SpanID != child.SpanID.String() {
+		t.Errorf("Неожиданный дочерний спан: %+v", parse)
+	}
+}
+
+// TestNilTracer проверяет, что отключенная трассировка не влияет на код
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", SpanKindInternal)
+	span.SetAttribute("key", "value")
+	span.SetError("ошибка")
+	span.End()
+
+	if _, ok := SpanContextFromContext(ctx); ok {
+		t.Error("Отключенный трассировщик не должен добавлять SpanContext в контекст")
+	}
+}
+
+// TestOTLPExporter_ExportAfterShutdown проверяет, что спаны, завершенные после остановки
+// экспортера, отбрасываются без паники, а накопленные до нее отправляются
+func TestOTLPExporter_ExportAfterShutdown(t *testing.T) {
+	var received atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				received.Add(int32(len(ss.Spans)))
+			}
+		}
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL)
+	tracer := NewTracer("test", exporter)
+	_, span := tracer.Start(context.Background(), "before", SpanKindInternal)
+	span.End()
+
+	// Спаны, завершающиеся одновременно с остановкой, не должны приводить к панике
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, span := tracer.Start(context.Background(), "late", SpanKindInternal)
+				span.End()
+			}
+		}()
+	}
+	if err := exporter.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	wg.Wait()
+
+	sent := received.Load()
+	if sent < 1 {
+		t.Errorf("Спан до остановки не отправлен")
+	}
+	exporter.Export("test", SpanData{Name: "after"})
+	if err := exporter.Shutdown(); err != nil {
+		t.Errorf("Повторный Shutdown: %v", err)
+	}
+	if received.Load() != sent {
+		t.Errorf("Спаны после остановки отправлены: %d, до остановки %d", received.Load(), sent)
+	}
+}
@@ -0,0 +1,352 @@
+// Package client — Go-клиент API сервиса вычисления арифметических выражений.
+//
+//	c := client.New("http://localhost:8080", client.WithToken(token))
+//	id, err := c.Submit(ctx, "(2+3)*4")
+//	expr, err := c.Wait(ctx, id)
+//
+// Временные сбои (сетевые ошибки, 429, 502, 503, 504) повторяются с экспоненциальной задержкой
+// и учетом заголовка Retry-After. Ошибки API сопоставляются с ErrNotFound, ErrConflict и другими
+// ошибками пакета и проверяются через errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки аутентификации и идемпотентности
+const (
+	apiKeyHeader         = "X-API-Key"
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// maxRetryDelay ограничивает задержку между повторными попытками
+const maxRetryDelay = 30 * time.Second
+
+// Client обращается к пользовательскому API сервиса. Безопасен для использования
+// из нескольких горутин
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	token        string        // JWT пользователя
+	apiKey       string        // API-ключ сервисной учетной записи
+	maxRetries   int           // Количество повторных попыток после временного сбоя
+	retryBase    time.Duration // Задержка перед первой повторной попыткой
+	pollInterval time.Duration // Интервал опроса статуса в Wait
+}
+
+// Option настраивает клиента
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP-клиент для запросов
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken задает JWT, полученный при входе пользователя
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey задает API-ключ, выданный администратором
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithRetries задает количество повторных попыток после временного сбоя и задержку
+// перед первой из них; каждая следующая задержка вдвое больше. 0 отключает повторы
+func WithRetries(maxRetries int, base time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBase = base
+	}
+}
+
+// WithPollInterval задает интервал опроса статуса выражения в Wait
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+// New создает клиента API по адресу baseURL, например http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		maxRetries:   3,
+		retryBase:    200 * time.Millisecond,
+		pollInterval: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Submit отправляет выражение на асинхронное вычисление и возвращает его ID.
+// Запрос снабжается ключом идемпотентности, поэтому повторная попытка после сбоя
+// не создает второе выражение
+func (c *Client) Submit(ctx context.Context, expression string) (int, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		ID int `json:"id"`
+	}
+	header := http.Header{idempotencyKeyHeader: {key}}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/calculate", header, expressionRequest{Expression: expression}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+// Get возвращает выражение по ID
+func (c *Client) Get(ctx context.Context, id int) (Expression, error) {
+	var resp expressionResponse
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d", id), nil, nil, &resp)
+	return resp.Expression, err
+}
+
+// List возвращает страницу выражений пользователя. Следующая страница запрашивается
+// с ListOptions.Cursor, равным Page.NextCursor
+func (c *Client) List(ctx context.Context, opts ListOptions) (Page, error) {
+	path := "/api/v1/expressions"
+	if query := opts.query().Encode(); query != "" {
+		path += "?" + query
+	}
+
+	var page Page
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &page)
+	return page, err
+}
+
+// Wait опрашивает выражение, пока оно не перейдет в окончательный статус COMPLETED, ERROR или CANCELLED,
+// и возвращает его. Ошибка вычисления не считается ошибкой Wait: ее причина — в Expression.Error
+func (c *Client) Wait(ctx context.Context, id int) (Expression, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		expr, err := c.Get(ctx, id)
+		if err != nil || expr.Status.IsTerminal() {
+			return expr, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return expr, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tasks возвращает задачи выражения в порядке ID со стадиями выполнения
+func (c *Client) Tasks(ctx context.Context, id int) ([]Task, error) {
+	var resp struct {
+		Tasks []Task `json:"tasks"`
+	}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/expressions/%d/tasks", id), nil, nil, &resp)
+	return resp.Tasks, err
+}
+
+// Agents возвращает агентов оркестратора. Требует секрета администратора в WithToken
+func (c *Client) Agents(ctx context.Context) ([]Agent, error) {
+	var resp struct {
+		Agents []Agent `json:"agents"`
+	}
+	err := c.do(ctx, http.MethodGet, "/admin/v1/agents", nil, nil, &resp)
+	return resp.Agents, err
+}
+
+// Cancel отменяет незавершенное выражение и возвращает его итоговое состояние.
+// Для уже завершенного выражения возвращает ошибку ErrConflict
+func (c *Client) Cancel(ctx context.Context, id int) (Expression, error) {
+	var resp expressionResponse
+	err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/expressions/%d", id), nil, nil, &resp)
+	return resp.Expression, err
+}
+
+// Evaluate синхронно вычисляет выражение на сервере и возвращает результат
+func (c *Client) Evaluate(ctx context.Context, expression string) (string, error) {
+	var resp struct {
+		Result string `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/evaluate", nil, expressionRequest{Expression: expression}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Result, nil
+}
+
+// do выполняет запрос с повторными попытками после временных сбоев и декодирует
+// JSON-ответ в out
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in, out any) error {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("ошибка кодирования запроса: %w", err)
+		}
+		body = data
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, header, body, out)
+		if err == nil || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		delay, retry := c.retryDelay(err, attempt)
+		if !retry {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay сообщает, можно ли повторить запрос после ошибки, и задержку перед попыткой
+func (c *Client) retryDelay(err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if !apiErr.retryable() {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return min(apiErr.RetryAfter, maxRetryDelay), true
+		}
+	}
+	return min(c.retryBase<<attempt, maxRetryDelay), true
+}
+
+// send выполняет одну попытку запроса
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("некорректный ответ API: %w", err)
+		}
+	}
+	return nil
+}
+
+// newAPIError создает ошибку API из ответа с кодом 4xx или 5xx
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// newIdempotencyKey генерирует случайный ключ идемпотентности
+func newIdempotencyKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации ключа идемпотентности: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// query возвращает параметры запроса списка выражений
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if len(o.Statuses) > 0 {
+		statuses := make([]string, len(o.Statuses))
+		for i, status := range o.Statuses {
+			statuses[i] = string(status)
+		}
+		query.Set("status", strings.Join(statuses, ","))
+	}
+	if !o.CreatedAfter.IsZero() {
+		query.Set("created_after", o.CreatedAfter.Format(time.RFC3339))
+	}
+	if !o.CreatedBefore.IsZero() {
+		query.Set("created_before", o.CreatedBefore.Format(time.RFC3339))
+	}
+	if o.Search != "" {
+		query.Set("q", o.Search)
+	}
+	if o.SortBy != "" {
+		query.Set("sort", o.SortBy)
+	}
+	if o.Descending {
+		query.Set("order", "desc")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	return query
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/orchestrator"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testAPI — настоящий сервер оркестратора с аутентификацией и ключами идемпотентности
+type testAPI struct {
+	*httptest.Server
+	storage  *orchestrator.Storage
+	failures atomic.Int32 // Сколько следующих ответов заменить на 503
+	requests atomic.Int32 // Количество полученных запросов
+}
+
+// newTestAPI запускает сервер оркестратора. Пока failures больше нуля, запрос обрабатывается,
+// но его ответ теряется и клиент получает 503, как при сбое балансировщика
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	api := &testAPI{storage: orchestrator.NewStorage()}
+	server := orchestrator.NewServer(api.storage, orchestrator.NewParser(orchestrator.OperationTimes{}))
+	server.SetAuthenticator(auth.NewAuthenticator("jwt-secret", time.Hour, "agent-secret", "admin-secret"))
+	server.SetIdempotencyStore(middleware.NewIdempotencyStore(time.Minute))
+	routes := server.SetupRoutes()
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.requests.Add(1)
+		if api.failures.Add(-1) >= 0 {
+			routes.ServeHTTP(httptest.NewRecorder(), r)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "Сервис перегружен"}`))
+			return
+		}
+		api.failures.Store(0)
+		routes.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// login регистрирует пользователя и возвращает его JWT
+func login(t *testing.T, baseURL, name string) string {
+	t.Helper()
+	creds, _ := json.Marshal(map[string]string{"login": name, "password": "password123"})
+	if resp, err := http.Post(baseURL+"/api/v1/register", "application/json", bytes.NewReader(creds)); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+
+	resp, err := http.Post(baseURL+"/api/v1/login", "application/json", bytes.NewReader(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body auth.LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Token
+}
+
+func TestClient_SubmitWait(t *testing.T) {
+	ts := newTestAPI(t)
+	testutil.StartAgent(t, ts.storage)
+	c := New(ts.URL, WithToken(login(t, ts.URL, "alice")), WithPollInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := c.Submit(ctx, "(2+3)*4-6/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := c.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Status != StatusCompleted || expr.Result == nil || *expr.Result != "18" {
+		t.Errorf("expression = %+v, want COMPLETED with result 18", expr)
+	}
+
+	// Ошибка вычисления возвращается в выражении, а не как ошибка Wait
+	id, _ = c.Submit(ctx, "1/0")
+	if expr, err := c.Wait(ctx, id); err != nil || expr.Status != StatusError || expr.Error == "" {
+		t.Errorf("Wait(1/0) = %+v, %v; want ERROR", expr, err)
+	}
+
+	page, err := c.List(ctx, ListOptions{Statuses: []Status{StatusCompleted}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Expressions) != 1 || page.Expressions[0].Expression != "(2+3)*4-6/3" {
+		t.Errorf("List(COMPLETED) = %+v", page.Expressions)
+	}
+
+	tasks, err := c.Tasks(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 4 || tasks[3].State != TaskDone || len(tasks[3].Dependencies) != 2 {
+		t.Errorf("Tasks(1) = %+v, want 4 done tasks", tasks)
+	}
+
+	if result, err := c.Evaluate(ctx, "2+2*2"); err != nil || result != "6" {
+		t.Errorf("Evaluate(2+2*2) = %q, %v; want 6", result, err)
+	}
+}
+
+func TestClient_Cancel(t *testing.T) {
+	ts := newTestAPI(t)
+	ctx := context.Background()
+	alice := New(ts.URL, WithToken(login(t, ts.URL, "alice")))
+	bob := New(ts.URL, WithToken(login(t, ts.URL, "bob")))
+
+	id, err := alice.Submit(ctx, "1+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.Cancel(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancel of foreign expression error = %v, want %v", err, ErrNotFound)
+	}
+
+	expr, err := alice.Cancel(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Status != StatusCancelled {
+		t.Errorf("cancelled expression status = %s, want %s", expr.Status, StatusCancelled)
+	}
+	if _, err := alice.Cancel(ctx, id); !errors.Is(err, ErrConflict) {
+		t.Errorf("repeated cancel error = %v, want %v", err, ErrConflict)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	ts := newTestAPI(t)
+	ctx := context.Background()
+	c := New(ts.URL, WithToken(login(t, ts.URL, "alice")))
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"unauthorized", func() error { _, err := New(ts.URL).Submit(ctx, "1+1"); return err }, ErrUnauthorized},
+		{"invalid expression", func() error { _, err := c.Submit(ctx, "2+"); return err }, ErrInvalidExpression},
+		{"not found", func() error { _, err := c.Get(ctx, 999); return err }, ErrNotFound},
+		{"bad list query", func() error { _, err := c.List(ctx, ListOptions{SortBy: "size"}); return err }, ErrBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Message == "" {
+				t.Errorf("error %v should be *APIError with server message", err)
+			}
+		})
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	ts := newTestAPI(t)
+	ctx := context.Background()
+	token := login(t, ts.URL, "alice")
+
+	// Без повторов временный сбой возвращается сразу
+	ts.failures.Store(1)
+	if _, err := New(ts.URL, WithToken(token), WithRetries(0, 0)).Evaluate(ctx, "1+1"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("error = %v, want %v", err, ErrUnavailable)
+	}
+
+	// Ответы на две попытки потеряны, но выражение создано только один раз
+	c := New(ts.URL, WithToken(token), WithRetries(3, time.Millisecond))
+	ts.failures.Store(2)
+	ts.requests.Store(0)
+	id, err := c.Submit(ctx, "1+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	if stats := ts.storage.Stats(); stats.Expressions != 1 || id != 1 {
+		t.Errorf("expressions = %d, id = %d; want a single expression", stats.Expressions, id)
+	}
+
+	// Отмена контекста прерывает ожидание между попытками
+	slow := New(ts.URL, WithToken(token), WithRetries(3, time.Hour))
+	ts.failures.Store(1)
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := slow.Get(ctx, id); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("error = %v, want %v", err, ErrUnavailable)
+	}
+}
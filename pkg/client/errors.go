@@ -0,0 +1,62 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ошибки API, сопоставленные с кодами ответа. Проверяются через errors.Is:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	ErrBadRequest        = errors.New("некорректный запрос")        // 400
+	ErrUnauthorized      = errors.New("требуется аутентификация")   // 401
+	ErrForbidden         = errors.New("доступ запрещен")            // 403
+	ErrNotFound          = errors.New("не найдено")                 // 404
+	ErrConflict          = errors.New("конфликт состояния")         // 409
+	ErrGone              = errors.New("ресурс больше недоступен")   // 410
+	ErrInvalidExpression = errors.New("некорректное выражение")     // 422
+	ErrRateLimited       = errors.New("превышена частота запросов") // 429
+	ErrServer            = errors.New("внутренняя ошибка сервера")  // 500
+	ErrUnavailable       = errors.New("сервис временно недоступен") // 502, 503, 504
+)
+
+// statusErrors сопоставляет коды ответа с ошибками API
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusGone:                ErrGone,
+	http.StatusUnprocessableEntity: ErrInvalidExpression,
+	http.StatusTooManyRequests:     ErrRateLimited,
+	http.StatusInternalServerError: ErrServer,
+	http.StatusBadGateway:          ErrUnavailable,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+	http.StatusGatewayTimeout:      ErrUnavailable,
+}
+
+// APIError — ответ API с кодом ошибки и сообщением из тела {"error": "..."}
+type APIError struct {
+	StatusCode int           // HTTP-код ответа
+	Message    string        // Сообщение сервера
+	RetryAfter time.Duration // Значение заголовка Retry-After (0 — не задано)
+}
+
+// Error возвращает код и сообщение ошибки
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API вернул %d: %s", e.StatusCode, e.Message)
+}
+
+// Is сопоставляет ошибку с ошибками API по коду ответа
+func (e *APIError) Is(target error) bool {
+	return statusErrors[e.StatusCode] == target
+}
+
+// retryable сообщает, можно ли повторить запрос, получивший этот ответ
+func (e *APIError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || errors.Is(e, ErrUnavailable)
+}
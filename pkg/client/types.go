@@ -0,0 +1,101 @@
+package client
+
+import "time"
+
+// Status — статус вычисления выражения
+type Status string
+
+// Статусы выражения
+const (
+	StatusPending    Status = "PENDING"    // Ожидает выполнения
+	StatusProcessing Status = "PROCESSING" // В процессе выполнения
+	StatusCompleted  Status = "COMPLETED"  // Вычисление завершено
+	StatusError      Status = "ERROR"      // Ошибка при вычислении
+	StatusCancelled  Status = "CANCELLED"  // Выражение отменено
+)
+
+// IsTerminal сообщает, является ли статус окончательным
+func (s Status) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusError || s == StatusCancelled
+}
+
+// Expression — выражение и состояние его вычисления
+type Expression struct {
+	ID          int        `json:"id"`
+	Expression  string     `json:"expression,omitempty"`   // Исходное выражение
+	Status      Status     `json:"status"`                 // Текущий статус вычисления
+	Result      *string    `json:"result,omitempty"`       // Результат (nil, если не вычислено)
+	Error       string     `json:"error,omitempty"`        // Причина ошибки для статусов ERROR и CANCELLED
+	CreatedAt   time.Time  `json:"created_at"`             // Время добавления
+	StartedAt   *time.Time `json:"started_at,omitempty"`   // Время выдачи первой задачи агенту
+	CompletedAt *time.Time `json:"completed_at,omitempty"` // Время перехода в окончательный статус
+}
+
+// Поля сортировки списка выражений
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "created_at"
+	SortByDuration  = "duration"
+)
+
+// ListOptions задает фильтрацию, сортировку и пагинацию списка выражений.
+// Нулевые значения полей не ограничивают выборку
+type ListOptions struct {
+	Statuses      []Status  // Допустимые статусы
+	CreatedAfter  time.Time // Нижняя граница времени добавления (включительно)
+	CreatedBefore time.Time // Верхняя граница времени добавления (не включительно)
+	Search        string    // Подстрока исходного выражения
+	SortBy        string    // Поле сортировки: SortByID, SortByCreatedAt или SortByDuration
+	Descending    bool      // Сортировка по убыванию
+	Limit         int       // Максимальный размер страницы
+	Cursor        string    // Курсор, полученный с предыдущей страницей
+}
+
+// Page — страница списка выражений
+type Page struct {
+	Expressions []Expression `json:"expressions"`
+	NextCursor  string       `json:"next_cursor,omitempty"` // Пусто на последней странице
+}
+
+// TaskState — стадия выполнения задачи
+type TaskState string
+
+// Стадии выполнения задачи
+const (
+	TaskWaiting TaskState = "WAITING" // Ожидает результатов зависимостей
+	TaskReady   TaskState = "READY"   // Готова к выдаче агенту
+	TaskRunning TaskState = "RUNNING" // Выполняется агентом
+	TaskDone    TaskState = "DONE"    // Результат получен
+	TaskSkipped TaskState = "SKIPPED" // Не будет выполнена: выражение завершилось ошибкой или отменено
+)
+
+// Task — одна операция графа вычисления выражения. Аргументы вида res:N ссылаются
+// на результат задачи N
+type Task struct {
+	ID           int       `json:"id"`
+	Arg1         string    `json:"arg1"`
+	Arg2         string    `json:"arg2"`
+	Operation    string    `json:"operation"`        // ADD, SUBTRACT, MULTIPLY или DIVIDE
+	Dependencies []int     `json:"dependencies"`     // Задачи, результаты которых нужны для выполнения
+	State        TaskState `json:"state"`            // Стадия выполнения
+	Result       *float64  `json:"result,omitempty"` // Результат выполнения
+	Agents       []string  `json:"agents,omitempty"` // Агенты, которым выдана задача
+}
+
+// Agent — агент, обращавшийся к оркестратору или выполняющий задачи
+type Agent struct {
+	ID       string     `json:"id"`
+	LastSeen *time.Time `json:"last_seen,omitempty"` // Время последнего запроса (нет у встроенных агентов)
+	Active   bool       `json:"active"`              // Агент недавно обращался к оркестратору или выполняет задачи
+	Tasks    int        `json:"tasks"`               // Количество выполняемых задач
+}
+
+// expressionRequest — тело запроса на вычисление выражения
+type expressionRequest struct {
+	Expression string `json:"expression"`
+}
+
+// expressionResponse — ответ с одним выражением
+type expressionResponse struct {
+	Expression Expression `json:"expression"`
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mpkelevra23/arithmetic-web-service/errors"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/handler"
+	"go.uber.org/zap"
+)
+
+// TestBatchEvaluateHandler проверяет пакетное вычисление выражений.
+func TestBatchEvaluateHandler(t *testing.T) {
+	logger := zap.NewNop()
+	handlerFunc := handler.BatchEvaluateHandler(logger, 4, nil)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       []handler.BatchResultItem
+	}{
+		{
+			name:           "JSON Array",
+			body:           `[{"correlation_id":"a","expression":"1+2*3"},{"correlation_id":"b","expression":"10/0"},{"correlation_id":"c","expression":"1+x"}]`,
+			expectedStatus: http.StatusOK,
+			expected: []handler.BatchResultItem{
+				{CorrelationID: "a", Result: "7"},
+				{CorrelationID: "b", Error: errors.ErrDivisionByZero},
+				{CorrelationID: "c", Error: errors.ErrInvalidInput},
+			},
+		},
+		{
+			name:           "NDJSON Stream",
+			body:           "{\"correlation_id\":\"x\",\"expression\":\"2*2\"}\n{\"correlation_id\":\"y\",\"expression\":\"\"}\n",
+			expectedStatus: http.StatusOK,
+			expected: []handler.BatchResultItem{
+				{CorrelationID: "x", Result: "4"},
+				{CorrelationID: "y", Error: errors.ErrMissingField},
+			},
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `[{"correlation_id":"a",`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/evaluate/batch", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handlerFunc.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var resp handler.BatchCalculateResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Не удалось декодировать тело ответа: %v", err)
+			}
+			if len(resp.Results) != len(tt.expected) {
+				t.Fatalf("Ожидалось %d результатов, получено %d", len(tt.expected), len(resp.Results))
+			}
+			for i, want := range tt.expected {
+				if resp.Results[i] != want {
+					t.Errorf("Результат %d: ожидалось %+v, получено %+v", i, want, resp.Results[i])
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/mpkelevra23/arithmetic-web-service/internal/calculator"
@@ -131,3 +132,50 @@ func TestCalc(t *testing.T) {
 		})
 	}
 }
+
+// TestEval проверяет вычисление выражений с переменными и позиции ошибок разбора.
+func TestEval(t *testing.T) {
+	vars := map[string]float64{"x": 5, "ans": -2}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected float64
+		errMsg   string
+		offset   int
+	}{
+		{name: "Переменные", expr: "x * (ans + 1)", expected: -5},
+		{name: "Неизвестная переменная", expr: "x + y", errMsg: "unknown variable: y", offset: 4},
+		{name: "Лишний оператор", expr: "2 + * 3", errMsg: "invalid token: *", offset: 4},
+		{name: "Незакрытая скобка", expr: "(1 + 2", errMsg: "missing closing parenthesis", offset: 6},
+		{name: "Неполное выражение", expr: "1 +", errMsg: "insufficient tokens", offset: 3},
+		{name: "Лишняя скобка", expr: "1 + 2)", errMsg: "unexpected token: )", offset: 5},
+		{name: "Некорректное число", expr: "1 + 2..5", errMsg: "invalid number: 2..5", offset: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := calculator.Eval(tt.expr, vars)
+			if tt.errMsg == "" {
+				if err != nil || result != tt.expected {
+					t.Errorf("Eval(%q) = %v, %v; ожидается %v", tt.expr, result, err, tt.expected)
+				}
+				return
+			}
+
+			var syntaxErr *calculator.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Eval(%q) вернул ошибку %v, ожидается SyntaxError", tt.expr, err)
+			}
+			if syntaxErr.Msg != tt.errMsg || syntaxErr.Offset != tt.offset {
+				t.Errorf("Eval(%q): ошибка %q на позиции %d; ожидается %q на позиции %d",
+					tt.expr, syntaxErr.Msg, syntaxErr.Offset, tt.errMsg, tt.offset)
+			}
+		})
+	}
+
+	// Без переменных сообщение об ошибке не меняется
+	if _, err := calculator.Calc("x + 1"); err == nil || err.Error() != "invalid number: x" {
+		t.Errorf("Calc(\"x + 1\") вернул ошибку %v, ожидается invalid number: x", err)
+	}
+}
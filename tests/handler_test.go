@@ -12,8 +12,8 @@ import (
 	"net/http/httptest"
 )
 
-// TestCalculateHandler проверяет работу HTTP-обработчика CalculateHandler.
-func TestCalculateHandler(t *testing.T) {
+// TestEvaluateHandler проверяет работу HTTP-обработчика EvaluateHandler.
+func TestEvaluateHandler(t *testing.T) {
 	// Инициализация логгера
 	logger, err := zap.NewDevelopment()
 	if err != nil {
@@ -27,7 +27,7 @@ func TestCalculateHandler(t *testing.T) {
 	}(logger)
 
 	// Инициализация обработчика
-	handlerFunc := handler.CalculateHandler(logger)
+	handlerFunc := handler.EvaluateHandler(logger, nil)
 
 	// Определение тестовых случаев
 	tests := []struct {
@@ -131,7 +131,7 @@ func TestCalculateHandler(t *testing.T) {
 			}
 
 			// Создание нового HTTP запроса
-			req := httptest.NewRequest(tt.method, "/api/v1/calculate", bytes.NewBuffer(reqBody))
+			req := httptest.NewRequest(tt.method, "/api/v1/evaluate", bytes.NewBuffer(reqBody))
 			req.Header.Set("Content-Type", "application/json")
 			if tt.name == "Trigger Internal Server Error by Header" {
 				req.Header.Set("X-Trigger-500", "true")
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/auth"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+)
+
+// TestIdempotencyMiddleware проверяет повторное использование ответа по ключу идемпотентности.
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":` + strconv.Itoa(calls) + `}`))
+	})
+
+	store := middleware.NewIdempotencyStore(50 * time.Millisecond)
+	idempotentHandler := middleware.IdempotencyMiddleware(store)(testHandler)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		idempotentHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Первый запрос выполняется обработчиком
+	first := send("key-1", `{"expression":"2+2"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"id":1}` {
+		t.Fatalf("Первый ответ: %d %s", first.Code, first.Body.String())
+	}
+
+	// Повтор с тем же ключом и телом возвращает сохранённый ответ
+	replay := send("key-1", `{"expression":"2+2"}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != `{"id":1}` {
+		t.Errorf("Повторный ответ: %d %s", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Ожидался заголовок Idempotent-Replayed")
+	}
+	if calls != 1 {
+		t.Errorf("Обработчик вызван %d раз, ожидался 1", calls)
+	}
+
+	// Тот же ключ с другим телом отклоняется
+	if conflict := send("key-1", `{"expression":"3+3"}`); conflict.Code != http.StatusConflict {
+		t.Errorf("Ожидался статус 409, получен %d", conflict.Code)
+	}
+
+	// Запросы без ключа не кешируются
+	send("", `{"expression":"2+2"}`)
+	if calls != 2 {
+		t.Errorf("Обработчик вызван %d раз, ожидалось 2", calls)
+	}
+
+	// По истечении окна ключ можно использовать повторно
+	time.Sleep(60 * time.Millisecond)
+	if expired := send("key-1", `{"expression":"3+3"}`); expired.Code != http.StatusCreated {
+		t.Errorf("Ожидался статус 201 после истечения ключа, получен %d", expired.Code)
+	}
+	if calls != 3 {
+		t.Errorf("Обработчик вызван %d раз, ожидалось 3", calls)
+	}
+}
+
+// TestIdempotencyMiddleware_Scope проверяет, что ключи разных пользователей не пересекаются,
+// а ответы о превышении квоты и частоты запросов не сохраняются.
+func TestIdempotencyMiddleware_Scope(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		w.Write([]byte(strconv.Itoa(calls)))
+	})
+	idempotentHandler := middleware.IdempotencyMiddleware(middleware.NewIdempotencyStore(time.Minute))(testHandler)
+
+	send := func(userID int, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(`{"expression":"2+2"}`))
+		req = req.WithContext(auth.WithUser(req.Context(), auth.User{ID: userID}))
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		rr := httptest.NewRecorder()
+		idempotentHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Тот же ключ другого пользователя не возвращает чужой ответ
+	if first := send(1, "shared"); first.Body.String() != "1" {
+		t.Fatalf("Первый ответ: %s", first.Body.String())
+	}
+	if other := send(2, "shared"); other.Body.String() != "2" || other.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Ответ другому пользователю: %s, повтор %q", other.Body.String(), other.Header().Get("Idempotent-Replayed"))
+	}
+
+	// Отказы по квоте и частоте запросов можно повторить с тем же ключом
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusRequestEntityTooLarge, http.StatusUnauthorized} {
+		status = code
+		send(1, "retry-"+strconv.Itoa(code))
+		status = http.StatusCreated
+		if retry := send(1, "retry-"+strconv.Itoa(code)); retry.Code != http.StatusCreated {
+			t.Errorf("Повтор после %d: статус %d, ожидался 201", code, retry.Code)
+		}
+	}
+
+	// Ошибка разбора выражения окончательна и сохраняется
+	status = http.StatusUnprocessableEntity
+	send(1, "invalid")
+	status = http.StatusCreated
+	if replay := send(1, "invalid"); replay.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Повтор после 422: статус %d, ожидался сохраненный 422", replay.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/metrics"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+)
+
+// TestRouteLabel проверяет приведение путей к шаблонам маршрутов
+func TestRouteLabel(t *testing.T) {
+	tests := []struct {
+		path, pattern string
+		status        int
+		expected      string
+	}{
+		{"/api/v1/calculate", "/api/v1/calculate", http.StatusOK, "/api/v1/calculate"},
+		{"/api/v1/expressions/42", "/api/v1/expressions/", http.StatusOK, "/api/v1/expressions/{id}"},
+		{"/api/v1/expressions/42/deliveries", "/api/v1/expressions/", http.StatusOK, "/api/v1/expressions/{id}/deliveries"},
+		{"/api/v1/expressions/abc", "/api/v1/expressions/", http.StatusNotFound, "/api/v1/expressions/"},
+		{"/static/app.js", "/static/", http.StatusOK, "/static/"},
+		{"/wp-admin", "", http.StatusNotFound, "unmatched"},
+	}
+
+	for _, tt := range tests {
+		if got := middleware.RouteLabel(tt.path, tt.pattern, tt.status); got != tt.expected {
+			t.Errorf("RouteLabel(%q, %q, %d) = %q, ожидалось %q", tt.path, tt.pattern, tt.status, got, tt.expected)
+		}
+	}
+}
+
+// TestMetricsMiddleware проверяет гистограмму длительности запросов по маршруту и статусу
+func TestMetricsMiddleware(t *testing.T) {
+	registry := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/expressions/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	mux.Handle("/metrics", registry.Handler())
+	handler := middleware.MetricsMiddleware(registry, "test", mux)(mux)
+
+	for _, path := range []string{"/api/v1/expressions/1", "/api/v1/expressions/2", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := rr.Body.String()
+
+	for _, line := range []string{
+		"# TYPE test_http_request_duration_seconds histogram",
+		`test_http_request_duration_seconds_count{method="GET",route="/api/v1/expressions/{id}",status="202"} 2`,
+		`test_http_request_duration_seconds_bucket{method="GET",route="/api/v1/expressions/{id}",status="202",le="+Inf"} 2`,
+		`test_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Строка %q не найдена в выводе метрик:\n%s", line, output)
+		}
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mpkelevra23/arithmetic-web-service/internal/logging"
 	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // TestLoggingMiddleware проверяет, что LoggingMiddleware корректно обрабатывает запросы.
@@ -40,3 +42,54 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("Ожидался статус 200, получен %d", w.Result().StatusCode)
 	}
 }
+
+// TestRequestIDMiddleware проверяет передачу идентификатора запроса в ответ и в логи.
+func TestRequestIDMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	var fromContext string
+	handler := middleware.RequestIDMiddleware(middleware.LoggingMiddleware(logger)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromContext = logging.RequestIDFromContext(r.Context())
+		})))
+
+	// Идентификатор клиента сохраняется
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(logging.RequestIDHeader, "client-id-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get(logging.RequestIDHeader); got != "client-id-1" || fromContext != "client-id-1" {
+		t.Errorf("Ожидался идентификатор client-id-1, в ответе %q, в контексте %q", got, fromContext)
+	}
+	entries := logs.FilterField(logging.RequestID("client-id-1")).All()
+	if len(entries) != 1 || entries[0].Message != "Request" {
+		t.Errorf("Ожидалась одна запись лога с request_id, получено %d", len(entries))
+	}
+
+	// Некорректный идентификатор заменяется сгенерированным
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(logging.RequestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get(logging.RequestIDHeader); got == "" || got == "bad id\n" || got != fromContext {
+		t.Errorf("Ожидался сгенерированный идентификатор, в ответе %q, в контексте %q", got, fromContext)
+	}
+}
+
+// TestLoggingMiddleware_QuietPaths проверяет понижение уровня логов для частых запросов.
+func TestLoggingMiddleware_QuietPaths(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	handler := middleware.LoggingMiddleware(zap.New(core), "/internal/task")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/internal/task", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/expressions", nil))
+
+	entries := logs.All()
+	if len(entries) != 2 || entries[0].Level != zap.DebugLevel || entries[1].Level != zap.InfoLevel {
+		t.Errorf("Ожидались записи уровней debug и info, получено %+v", entries)
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mpkelevra23/arithmetic-web-service/internal/middleware"
+	"github.com/mpkelevra23/arithmetic-web-service/internal/ratelimit"
+)
+
+// TestRateLimitMiddleware проверяет ответ 429 и заголовки X-RateLimit-* при превышении лимита.
+func TestRateLimitMiddleware(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limitedHandler := middleware.RateLimitMiddleware(ratelimit.NewLimiter(1, 2), nil)(testHandler)
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		limitedHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := send("10.0.0.1:1234"); rr.Code != http.StatusOK {
+			t.Fatalf("Запрос %d: ожидался статус 200, получен %d", i+1, rr.Code)
+		}
+	}
+
+	rr := send("10.0.0.1:5678")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Ожидался статус 429, получен %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, ожидалось %q", rr.Header().Get("Retry-After"), "1")
+	}
+	if rr.Header().Get("X-RateLimit-Limit") != "2" || rr.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Неверные заголовки X-RateLimit-*: %v", rr.Header())
+	}
+
+	// Другой IP-адрес ограничивается независимо
+	if rr := send("10.0.0.2:1234"); rr.Code != http.StatusOK {
+		t.Errorf("Ожидался статус 200 для другого IP, получен %d", rr.Code)
+	}
+}
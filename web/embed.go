@@ -9,6 +9,11 @@ import (
 //go:embed static/*
 var staticFiles embed.FS
 
+// OpenAPI — описание API сервера в формате OpenAPI 3
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
 // GetFileSystem возвращает HTTP файловую систему для веб-ресурсов
 func GetFileSystem() (http.FileSystem, error) {
 	// Получить подфайловую систему из директории static
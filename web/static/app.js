@@ -19,7 +19,7 @@ createApp({
             isCalculating.value = true;
 
             try {
-                const response = await fetch('/api/v1/calculate', {
+                const response = await fetch('/api/v1/evaluate', {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/json',